@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/FotiadisM/service-template/internal/config"
+	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/internal/fixtures"
+	"github.com/FotiadisM/service-template/internal/server"
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+	"github.com/FotiadisM/service-template/pkg/ilog"
+	"github.com/FotiadisM/service-template/pkg/version"
+)
+
+var errNoDebugKey = errors.New("LOGGING_DEBUG_KEY is not set")
+
+func runVersion(_ context.Context, w io.Writer, args []string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(version.Get())
+}
+
+// runConfig prints the configuration. Fields tagged with json:"-" hold
+// secrets and are left out.
+func runConfig(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return fmt.Errorf("%w: expected print", errUsage)
+	}
+
+	config, err := config.NewConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(config)
+}
+
+// runDebugToken prints a token for the X-Debug-Log header, signed with
+// LOGGING_DEBUG_KEY, that elevates the logs of the requests carrying it to
+// DEBUG until it expires.
+func runDebugToken(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("debug-token", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	ttl := fs.Duration("ttl", time.Hour, "token lifetime")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if err := noArgs(fs.Args()); err != nil {
+		return err
+	}
+	if *ttl <= 0 || *ttl > ilog.MaxDebugTokenTTL {
+		return fmt.Errorf("%w: -ttl must be positive and at most %s", errUsage, ilog.MaxDebugTokenTTL)
+	}
+
+	config, err := config.NewConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config.Logging.DebugKey == "" {
+		return errNoDebugKey
+	}
+
+	token := ilog.SignDebugToken([]byte(config.Logging.DebugKey), time.Now().Add(*ttl))
+	fmt.Fprintln(w, token)
+
+	return nil
+}
+
+// runHealthcheck probes the grpchealth endpoint of the server listening on
+// SERVER_ADDR. It is meant to be used as the Docker HEALTHCHECK.
+func runHealthcheck(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	service := fs.String("service", "readiness", "health service to check")
+	timeout := fs.Duration("timeout", 3*time.Second, "probe timeout")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if err := noArgs(fs.Args()); err != nil {
+		return err
+	}
+
+	config, err := config.NewConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	host, port, err := net.SplitHostPort(config.Server.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid server address %q: %w", config.Server.Addr, err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+
+	client := connect.NewClient[healthv1.HealthCheckRequest, healthv1.HealthCheckResponse](
+		http.DefaultClient,
+		"http://"+net.JoinHostPort(host, port)+"/grpc.health.v1.Health/Check",
+		// the call is logged to stderr, which Docker keeps with the probe result
+		connect.WithInterceptors(server.ChainClientMiddleware(config, slog.Default())...),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	res, err := client.CallUnary(ctx, connect.NewRequest(&healthv1.HealthCheckRequest{Service: *service}))
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	status := res.Msg.GetStatus()
+	fmt.Fprintln(w, status)
+	if status != healthv1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("service %q is %s", *service, status)
+	}
+
+	return nil
+}
+
+// runSeed loads fixture files into the database in a single transaction, or
+// the default fixtures if no file is given. It is meant for local development.
+func runSeed(ctx context.Context, w io.Writer, args []string) error {
+	set := fixtures.Default()
+	if len(args) > 0 {
+		var err error
+		set, err = fixtures.ParseFiles(args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	config, err := config.NewConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.New(ctx, config.DB)
+	if err != nil {
+		return fmt.Errorf("failed to create db: %w", err)
+	}
+	defer db.Close()
+
+	// the transaction may be retried, so the summary is only printed once it
+	// commits
+	var loaded *fixtures.Loaded
+	err = db.InTx(ctx, func(q queries.Querier) error {
+		var err error
+		loaded, err = fixtures.Load(ctx, q, set)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "seeded %d authors, %d books and %d book reviews\n",
+		len(loaded.Authors), len(loaded.Books), len(loaded.BookReviews))
+
+	return nil
+}
@@ -2,48 +2,68 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"errors"
-	"fmt"
+	"sync/atomic"
 
-	"connectrpc.com/connect"
-	"connectrpc.com/grpchealth"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/internal/database/migrate"
+	"github.com/FotiadisM/service-template/pkg/health"
 )
 
-var errUnknownService = errors.New("unknown service")
+var (
+	errSchemaNotReady = errors.New("database schema is not at the latest migration")
+	errCollectorDown  = errors.New("otel collector connection is failing")
+)
 
-type healthChecker struct {
-	DB *sql.DB
+// databaseCheck fails if the primary database is unreachable. Unhealthy
+// replicas are ejected from read routing as a side effect.
+func databaseCheck(db *database.DB) health.CheckFunc {
+	return db.Ping
 }
 
-var _ grpchealth.Checker = &healthChecker{}
+// schemaCheck fails until the schema is at the latest migration. Once it
+// is, the result is cached, as the running binary never expects a newer one.
+func schemaCheck(db *database.DB, migrator *migrate.Migrator) health.CheckFunc {
+	var ready atomic.Bool
 
-func (c *healthChecker) Check(ctx context.Context, req *grpchealth.CheckRequest) (*grpchealth.CheckResponse, error) {
-	if req.Service == "startup" {
-		return &grpchealth.CheckResponse{Status: grpchealth.StatusServing}, nil
-	}
+	return func(ctx context.Context) error {
+		if ready.Load() {
+			return nil
+		}
 
-	if req.Service == "readiness" {
-		return c.readiness(ctx)
-	}
+		var latest bool
+		err := db.Pool.AcquireFunc(ctx, func(conn *pgxpool.Conn) (err error) {
+			latest, err = migrator.IsLatest(ctx, conn.Conn())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if !latest {
+			return errSchemaNotReady
+		}
+		ready.Store(true)
 
-	if req.Service == "liveness" {
-		return &grpchealth.CheckResponse{Status: grpchealth.StatusServing}, nil
+		return nil
 	}
-
-	err := connect.NewError(
-		connect.CodeNotFound,
-		fmt.Errorf("'%s': %w", req.Service, errUnknownService),
-	)
-
-	return nil, err
 }
 
-func (c *healthChecker) readiness(ctx context.Context) (*grpchealth.CheckResponse, error) {
-	err := c.DB.PingContext(ctx)
-	if err != nil {
-		return &grpchealth.CheckResponse{Status: grpchealth.StatusNotServing}, nil //nolint
-	}
+// otlpCheck fails while the connection to the otel collector is in transient
+// failure. Exporters buffer and retry, so it is meant to be non critical.
+func otlpCheck(conn *grpc.ClientConn) health.CheckFunc {
+	return func(_ context.Context) error {
+		state := conn.GetState()
+		if state == connectivity.Idle {
+			conn.Connect()
+		}
+		if state == connectivity.TransientFailure {
+			return errCollectorDown
+		}
 
-	return &grpchealth.CheckResponse{Status: grpchealth.StatusServing}, nil
+		return nil
+	}
 }
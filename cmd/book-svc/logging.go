@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+
+	"github.com/FotiadisM/service-template/internal/config"
+	"github.com/FotiadisM/service-template/pkg/ilog"
+	"github.com/FotiadisM/service-template/pkg/version"
+)
+
+// logSinks creates the sinks of LOGGING_SINKS. The returned sinks have to be
+// closed, even when an error is returned.
+func logSinks(config *config.Config) ([]ilog.Sink, error) {
+	var sinks []ilog.Sink
+	for _, name := range config.Logging.Sinks {
+		sink, ok, err := logSink(config, name)
+		if err != nil {
+			return sinks, fmt.Errorf("log sink %s: %w", name, err)
+		}
+		if ok {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	return sinks, nil
+}
+
+func logSink(config *config.Config, name string) (ilog.Sink, bool, error) {
+	switch name {
+	case "stdout":
+		format := config.Logging.Stdout.Format
+		if format == "" {
+			format = ilog.FormatPretty.String()
+			if config.Logging.JSON {
+				format = ilog.FormatJSON.String()
+			}
+		}
+		level, f, err := parseSink(config.Logging.Stdout.Level, format)
+		if err != nil {
+			return ilog.Sink{}, false, err
+		}
+		return ilog.WriterSink(os.Stdout, f, level), true, nil
+	case "file":
+		c := config.Logging.File
+		level, f, err := parseSink(c.Level, c.Format)
+		if err != nil {
+			return ilog.Sink{}, false, err
+		}
+		sink, err := ilog.FileSink(c.Path, ilog.Rotation{
+			MaxSize:    c.MaxSize << 20,
+			MaxAge:     c.MaxAge,
+			MaxBackups: c.MaxBackups,
+			Compress:   c.Compress,
+			OnError: func(err error) {
+				// resolved when called, once the logger is the default one
+				slog.Error("log file rotation failed", ilog.Err(err))
+			},
+		}, f, level)
+		return sink, err == nil, err
+	case "syslog":
+		c := config.Logging.Syslog
+		level, f, err := parseSink(c.Level, c.Format)
+		if err != nil {
+			return ilog.Sink{}, false, err
+		}
+		tag := c.Tag
+		if tag == "" {
+			tag = config.Inst.ServiceName
+		}
+		sink, err := ilog.SyslogSink(c.Socket, tag, f, level)
+		return sink, err == nil, err
+	case "otel":
+		if config.Inst.OtelSDKDisabled {
+			return ilog.Sink{}, false, nil
+		}
+		level, _, err := parseSink(config.Logging.OTel.Level, "")
+		if err != nil {
+			return ilog.Sink{}, false, err
+		}
+		return ilog.OTelSink(config.Inst.ServiceName, level, otelslog.WithVersion(version.Version)), true, nil
+	default:
+		return ilog.Sink{}, false, errors.New("unknown sink, expected stdout, file, syslog or otel")
+	}
+}
+
+// parseSink parses the level and format of a sink. An empty level is nil and
+// an empty format is pretty.
+func parseSink(level, format string) (slog.Leveler, ilog.Format, error) {
+	f := ilog.FormatPretty
+	if format != "" {
+		var err error
+		if f, err = ilog.ParseFormat(format); err != nil {
+			return nil, f, err
+		}
+	}
+	if level == "" {
+		return nil, f, nil
+	}
+
+	l, err := ilog.ParseLevel(level)
+	if err != nil {
+		return nil, f, err
+	}
+
+	return l, f, nil
+}
+
+// closeSinks closes sinks, after which the logger must not be used. The
+// records held back by the logger must be passed on first, see ilog.Close.
+func closeSinks(sinks []ilog.Sink) error {
+	var errs []error
+	for _, sink := range sinks {
+		errs = append(errs, sink.Close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -2,83 +2,94 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log/slog"
-	"net/http"
+	"io"
 	"os"
+	"strings"
 
-	"connectrpc.com/connect"
-	"connectrpc.com/grpchealth"
-	"go.opentelemetry.io/otel"
-
-	"github.com/FotiadisM/service-template/api/docs"
-	"github.com/FotiadisM/service-template/api/gen/go/book/v1/bookv1connect"
-	"github.com/FotiadisM/service-template/internal/config"
-	"github.com/FotiadisM/service-template/internal/database"
-	"github.com/FotiadisM/service-template/internal/server"
-	bookv1 "github.com/FotiadisM/service-template/internal/services/book/v1"
-	"github.com/FotiadisM/service-template/pkg/ilog"
 	"github.com/FotiadisM/service-template/pkg/version"
 )
 
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name  string
+	usage string
+	// run executes the command, args excludes the command name.
+	run func(ctx context.Context, w io.Writer, args []string) error
+}
+
+var commands = []command{
+	{name: "serve", usage: "start the server (default)", run: runServe},
+	{name: "migrate", usage: "up|down [n]|status|verify the database schema", run: runMigrate},
+	{name: "seed", usage: "load fixture files, or the default fixtures, into the database", run: runSeed},
+	{name: "config", usage: "print the configuration with secrets redacted", run: runConfig},
+	{name: "debug-token", usage: "sign a token for the X-Debug-Log header [-ttl 1h]", run: runDebugToken},
+	{name: "healthcheck", usage: "probe the readiness of a running server", run: runHealthcheck},
+	{name: "version", usage: "print version information as JSON", run: runVersion},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: book-svc [flags] <command> [args]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(w, "\nflags:\n")
+	flag.CommandLine.SetOutput(w)
+	flag.PrintDefaults()
+}
+
 func main() {
 	version.AddFlag(nil)
+	flag.Usage = func() { usage(os.Stderr) }
 	flag.Parse()
 
-	ctx := context.Background()
-	config := config.NewConfig(ctx)
+	os.Exit(run(context.Background(), os.Stdout, flag.Args()))
+}
 
-	shutdownFunc, err := initializeOTEL(ctx, config.Inst)
-	if err != nil {
-		fmt.Fprintf(os.Stdout, "failed to initialize otel SDK: %v\n", err)
-		os.Exit(1)
+func run(ctx context.Context, w io.Writer, args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
 
-	log := ilog.NewLogger(
-		ilog.WithLogLevel(slog.Level(config.Logging.Level)),
-		ilog.WithJSON(config.Logging.JSON),
-		ilog.WithAddSource(config.Logging.AddSource),
-	)
-	slog.SetDefault(log)
-	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
-		log.Warn("open-telemetry", ilog.Err(err))
-	}))
-	defer func() {
-		err = shutdownFunc(ctx)
-		if err != nil {
-			log.Warn("failed to shutdown otel", ilog.Err(err))
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
 		}
-	}()
 
-	db, err := database.New(config.DB)
-	if err != nil {
-		log.Error("failed to create db", ilog.Err(err))
-		os.Exit(1)
+		err := cmd.run(ctx, w, args)
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n\n", name, err)
+			usage(os.Stderr)
+			return exitUsage
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			return exitError
+		}
+		return exitOK
 	}
 
-	mux := http.NewServeMux()
-	healthChecker := &healthChecker{
-		DB: db.DB,
-	}
-	mux.Handle(grpchealth.NewHandler(healthChecker))
-	mux.Handle("/api/docs/", http.StripPrefix("/api/docs/", http.FileServerFS(docs.DocsFS)))
-
-	svc := bookv1.NewService(db)
-	interceptors := server.ChainMiddleware(config, log)
-	booksvcPath, booksvcHanlder := bookv1connect.NewBookServiceHandler(svc,
-		connect.WithInterceptors(interceptors...),
-	)
-
-	serverHandler := server.ChainHandlers(mux, config, log, map[string]http.Handler{
-		booksvcPath: booksvcHanlder,
-	})
-
-	server, err := server.NewServer(config, log, serverHandler)
-	if err != nil {
-		log.Error("failed to create server", ilog.Err(err))
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage(os.Stderr)
+
+	return exitUsage
+}
+
+// noArgs returns a usage error if the command was given any arguments.
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %s", errUsage, strings.Join(args, " "))
 	}
 
-	server.Start()
-	server.AwaitShutdown(ctx)
+	return nil
 }
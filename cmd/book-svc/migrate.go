@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/FotiadisM/service-template/internal/config"
+	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/internal/database/migrate"
+	"github.com/FotiadisM/service-template/pkg/ilog"
+	"github.com/FotiadisM/service-template/pkg/lifecycle"
+)
+
+var errMigrateUsage = fmt.Errorf("%w: expected up|down [n]|status|verify", errUsage)
+
+func runMigrate(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	m, err := migrate.New(database.Migrations())
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "verify" {
+		if err = m.Verify(); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "migration directory is valid")
+		return nil
+	}
+
+	config, err := config.NewConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, err := pgx.Connect(ctx, database.ConnString(config.DB))
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx, conn)
+		for _, mig := range applied {
+			fmt.Fprintf(w, "applied %s\n", mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(w, "no pending migrations")
+		}
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			n, err = strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("%w: invalid number of migrations %q", errUsage, args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, conn, n)
+		for _, mig := range reverted {
+			fmt.Fprintf(w, "reverted %s\n", mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+	case "status":
+		status, err := m.Status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "current: %s\nlatest: %s\npending: %d\n\n", status.Current, status.Latest, status.Pending())
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, mig := range status.Migrations {
+			appliedAt := "pending"
+			if mig.Applied {
+				appliedAt = mig.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", mig.Version, mig.Name, appliedAt)
+		}
+		return tw.Flush()
+
+	default:
+		return errMigrateUsage
+	}
+
+	return nil
+}
+
+// migrationsHook applies the migrations in the background once the database
+// is available. Stopping it cancels a migration in progress, which is rolled
+// back.
+func migrationsHook(log *slog.Logger, db *database.DB, m *migrate.Migrator) lifecycle.Hook {
+	var (
+		cancel context.CancelFunc
+		done   = make(chan struct{})
+	)
+
+	return lifecycle.Hook{
+		Name:      "migrations",
+		DependsOn: []string{"database"},
+		OnStart: func(ctx context.Context) error {
+			ctx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			go func() {
+				defer close(done)
+				migrateOnStart(ctx, log, db, m)
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if cancel == nil {
+				return nil
+			}
+			cancel()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// migrateOnStart applies pending migrations in the background. Replicas that
+// lose the race for the advisory lock wait for the winner and find nothing left
+// to apply.
+func migrateOnStart(ctx context.Context, log *slog.Logger, db *database.DB, m *migrate.Migrator) {
+	err := db.Pool.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
+		applied, err := m.Up(ctx, c.Conn())
+		for _, mig := range applied {
+			log.Info("applied migration", "name", mig.Name)
+		}
+		return err
+	})
+	if err != nil {
+		log.Error("failed to apply migrations", ilog.Err(err))
+		return
+	}
+
+	log.Info("database schema is up to date", "version", m.Latest())
+}
@@ -18,20 +18,24 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/FotiadisM/service-template/internal/config"
+	"github.com/FotiadisM/service-template/pkg/health"
 )
 
 type otelShutDownFunc func(ctx context.Context) error
 
-func initializeOTEL(ctx context.Context, config config.Instrumentation) (otelShutDownFunc, error) {
+// initializeOTEL sets up the global otel providers. The returned health check
+// reports the state of the collector connection, it is nil if the SDK is
+// disabled.
+func initializeOTEL(ctx context.Context, config config.Instrumentation) (otelShutDownFunc, health.CheckFunc, error) {
 	if config.OtelSDKDisabled {
-		return func(ctx context.Context) error { return nil }, nil
+		return func(ctx context.Context) error { return nil }, nil, nil
 	}
 
 	conn, err := grpc.NewClient(config.OtelExporterAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gRPC connection to collector: %w", err)
+		return nil, nil, fmt.Errorf("failed to create gRPC connection to collector: %w", err)
 	}
 
 	res, err := resource.New(ctx,
@@ -40,12 +44,12 @@ func initializeOTEL(ctx context.Context, config config.Instrumentation) (otelShu
 		resource.WithContainer(),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create otel resource %w", err)
+		return nil, nil, fmt.Errorf("failed to create otel resource %w", err)
 	}
 
 	traceExporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithGRPCConn(conn))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		return nil, nil, fmt.Errorf("failed to create trace exporter: %w", err)
 	}
 
 	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
@@ -58,7 +62,7 @@ func initializeOTEL(ctx context.Context, config config.Instrumentation) (otelShu
 
 	metricExporter, err := otlpmetricgrpc.New(ctx, otlpmetricgrpc.WithGRPCConn(conn))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create metrics exporter: %w", err)
+		return nil, nil, fmt.Errorf("failed to create metrics exporter: %w", err)
 	}
 
 	meterProvider := sdkmetric.NewMeterProvider(
@@ -69,7 +73,7 @@ func initializeOTEL(ctx context.Context, config config.Instrumentation) (otelShu
 
 	logExporter, err := otlploggrpc.New(ctx, otlploggrpc.WithGRPCConn(conn))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create log exporter: %w", err)
+		return nil, nil, fmt.Errorf("failed to create log exporter: %w", err)
 	}
 	loggerProvider := sdklog.NewLoggerProvider(
 		sdklog.WithResource(res),
@@ -77,10 +81,16 @@ func initializeOTEL(ctx context.Context, config config.Instrumentation) (otelShu
 	)
 	global.SetLoggerProvider(loggerProvider)
 
+	// the logger provider is shut down last, so that logs emitted while the
+	// other providers flush are exported
 	fn := func(ctx context.Context) error {
-		err := tracerProvider.Shutdown(ctx)
-		return errors.Join(err, meterProvider.Shutdown(ctx))
+		return errors.Join(
+			tracerProvider.Shutdown(ctx),
+			meterProvider.Shutdown(ctx),
+			loggerProvider.Shutdown(ctx),
+			conn.Close(),
+		)
 	}
 
-	return fn, nil
+	return fn, otlpCheck(conn), nil
 }
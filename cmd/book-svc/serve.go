@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel"
+
+	"github.com/FotiadisM/service-template/api/docs"
+	"github.com/FotiadisM/service-template/api/gen/go/book/v1/bookv1connect"
+	"github.com/FotiadisM/service-template/internal/admin"
+	"github.com/FotiadisM/service-template/internal/config"
+	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/internal/database/migrate"
+	"github.com/FotiadisM/service-template/internal/server"
+	bookv1 "github.com/FotiadisM/service-template/internal/services/book/v1"
+	"github.com/FotiadisM/service-template/pkg/connect/interceptors/recovery"
+	"github.com/FotiadisM/service-template/pkg/health"
+	"github.com/FotiadisM/service-template/pkg/ilog"
+	"github.com/FotiadisM/service-template/pkg/lifecycle"
+	"github.com/FotiadisM/service-template/pkg/version"
+)
+
+func runServe(ctx context.Context, _ io.Writer, args []string) error {
+	if err := noArgs(args); err != nil {
+		return err
+	}
+
+	config, err := config.NewConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	shutdownFunc, otlpHealthCheck, err := initializeOTEL(ctx, config.Inst)
+	if err != nil {
+		return fmt.Errorf("failed to initialize otel SDK: %w", err)
+	}
+
+	namedLevels, err := ilog.ParseLevels(config.Logging.Levels)
+	if err != nil {
+		return fmt.Errorf("invalid LOGGING_LEVELS: %w", err)
+	}
+	redactMode, err := ilog.ParseRedactMode(config.Logging.RedactMode)
+	if err != nil {
+		return fmt.Errorf("invalid LOGGING_REDACT_MODE: %w", err)
+	}
+	redactor, err := ilog.NewRedactor(redactMode,
+		append(slices.Clone(ilog.DefaultRedactKeys), config.Logging.RedactKeys...),
+		ilog.DefaultDetectors(),
+		[]byte(config.Logging.RedactHashKey),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid LOGGING_REDACT_HASH_KEY: %w", err)
+	}
+
+	sinks, err := logSinks(config)
+	if err != nil {
+		return errors.Join(err, closeSinks(sinks))
+	}
+
+	levels := ilog.NewLevels(slog.Level(config.Logging.Level))
+	levels.Configure(slog.Level(config.Logging.Level), namedLevels)
+
+	log := ilog.NewLogger(
+		ilog.WithLevels(levels),
+		ilog.WithSinks(sinks...),
+		ilog.WithRedactor(redactor),
+		ilog.WithSampling(config.Logging.SampleInterval, config.Logging.SampleInitial, config.Logging.SampleThereafter),
+		ilog.WithDedupWindow(config.Logging.DedupWindow),
+		ilog.WithAddSource(config.Logging.AddSource),
+	)
+	slog.SetDefault(log)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warn("open-telemetry", ilog.Err(err))
+	}))
+
+	app := lifecycle.New(log,
+		lifecycle.WithDrainPeriod(config.Server.DrainPeriod),
+		lifecycle.WithShutdownTimeout(config.Server.ShutdownTimeout),
+	)
+	// appended first to be stopped last, after every hook logged its shutdown
+	app.Append(lifecycle.Hook{
+		Name: "logging",
+		OnStop: func(context.Context) error {
+			ilog.Close(log)
+			return closeSinks(sinks)
+		},
+	})
+	app.Append(lifecycle.Hook{
+		Name:   "otel",
+		OnStop: shutdownFunc,
+	})
+
+	app.Append(reloadLevelsHook(log, levels))
+
+	if err = setupServe(ctx, config, log, app, otlpHealthCheck); err != nil {
+		return errors.Join(err, app.Abort(ctx))
+	}
+
+	return app.Run(ctx)
+}
+
+// setupServe creates the components of the server and appends them to app.
+// Components are appended as soon as they are created, so that app.Abort
+// releases them if a later step fails.
+func setupServe(
+	ctx context.Context,
+	config *config.Config,
+	log *slog.Logger,
+	app *lifecycle.Manager,
+	otlpHealthCheck health.CheckFunc,
+) error {
+	db, err := database.New(ctx, config.DB)
+	if err != nil {
+		return fmt.Errorf("failed to create db: %w", err)
+	}
+	app.Append(lifecycle.Hook{
+		Name:      "database",
+		DependsOn: []string{"otel"},
+		OnStop: func(context.Context) error {
+			db.Close()
+			return nil
+		},
+	})
+
+	healthRegistry := health.NewRegistry(
+		health.WithDefaultInterval(config.Health.Interval),
+		health.WithDefaultTimeout(config.Health.Timeout),
+	)
+	healthRegistry.Register("database", databaseCheck(db))
+	if otlpHealthCheck != nil {
+		healthRegistry.Register("otlp-exporter", otlpHealthCheck, health.NonCritical())
+	}
+
+	if config.Server.MigrateOnStart {
+		migrator, err := migrate.New(database.Migrations())
+		if err != nil {
+			return fmt.Errorf("failed to load migrations: %w", err)
+		}
+		healthRegistry.Register("migrations", schemaCheck(db, migrator))
+		app.Append(migrationsHook(ilog.Named(log, "db"), db, migrator))
+	}
+
+	app.Append(lifecycle.Hook{
+		Name:      "health",
+		DependsOn: []string{"database"},
+		OnStart: func(ctx context.Context) error {
+			healthRegistry.Start(ctx)
+			return nil
+		},
+		OnDrain: healthRegistry.Drain,
+		OnStop: func(context.Context) error {
+			healthRegistry.Stop()
+			return nil
+		},
+	})
+
+	if config.Admin.Addr != "" {
+		adminServer, err := admin.NewServer(config, log, healthRegistry)
+		if err != nil {
+			return fmt.Errorf("failed to create admin server: %w", err)
+		}
+		app.Append(lifecycle.Hook{
+			Name:      "admin",
+			DependsOn: []string{"health"},
+			OnStart: func(context.Context) error {
+				if err := adminServer.Start(); err != nil {
+					return err
+				}
+				go func() {
+					if err := <-adminServer.Err(); err != nil {
+						app.Fail(err)
+					}
+				}()
+				return nil
+			},
+			OnStop: adminServer.Shutdown,
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(health.NewHandler(healthRegistry))
+	health.RegisterProbes(mux, healthRegistry)
+	mux.Handle("/api/docs/", http.StripPrefix("/api/docs/", http.FileServerFS(docs.DocsFS)))
+
+	reporters := []recovery.PanicReporter{recovery.NewLogReporter(ilog.Named(log, "rpc"))}
+	if config.Inst.SentryDSN != "" {
+		sentry, err := recovery.NewSentryReporter(config.Inst.SentryDSN,
+			recovery.WithSentryEnvironment(config.Environment),
+			recovery.WithSentryRelease(version.Version),
+			recovery.WithSentryLogger(log),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create sentry reporter: %w", err)
+		}
+		// stopped after the http server, to send the panics of the last requests
+		app.Append(lifecycle.Hook{
+			Name:   "sentry",
+			OnStop: sentry.Close,
+		})
+		reporters = append(reporters, sentry)
+	}
+
+	svc := bookv1.NewService(db)
+	interceptors := server.ChainMiddleware(config, ilog.Named(log, "rpc"), reporters...)
+	booksvcPath, booksvcHanlder := bookv1connect.NewBookServiceHandler(svc,
+		connect.WithInterceptors(interceptors...),
+	)
+
+	serverHandler, err := server.ChainHandlers(mux, config, ilog.Named(log, "http"), map[string]http.Handler{
+		booksvcPath: booksvcHanlder,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create handlers: %w", err)
+	}
+
+	server, err := server.NewServer(config, log, serverHandler)
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	app.Append(lifecycle.Hook{
+		Name:      "http",
+		DependsOn: []string{"database", "health"},
+		OnStart: func(context.Context) error {
+			if err := server.Start(); err != nil {
+				return err
+			}
+			go func() {
+				if err := <-server.Err(); err != nil {
+					app.Fail(err)
+				}
+			}()
+			return nil
+		},
+		OnStop: server.Shutdown,
+	})
+
+	return nil
+}
+
+// reloadLevelsHook reloads the log levels from the environment on SIGHUP,
+// cancelling the levels changed at runtime.
+func reloadLevelsHook(log *slog.Logger, levels *ilog.Levels) lifecycle.Hook {
+	var (
+		hup  = make(chan os.Signal, 1)
+		done = make(chan struct{})
+	)
+
+	reload := func(ctx context.Context) error {
+		config, err := config.NewConfig(ctx)
+		if err != nil {
+			return err
+		}
+		named, err := ilog.ParseLevels(config.Logging.Levels)
+		if err != nil {
+			return fmt.Errorf("invalid LOGGING_LEVELS: %w", err)
+		}
+		levels.Configure(slog.Level(config.Logging.Level), named)
+
+		return nil
+	}
+
+	return lifecycle.Hook{
+		Name: "log-levels",
+		OnStart: func(ctx context.Context) error {
+			signal.Notify(hup, syscall.SIGHUP)
+			go func() {
+				for {
+					select {
+					case <-done:
+						return
+					case <-hup:
+					}
+
+					if err := reload(context.WithoutCancel(ctx)); err != nil {
+						log.Error("failed to reload log levels", ilog.Err(err))
+						continue
+					}
+					log.Info("reloaded log levels")
+				}
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			signal.Stop(hup)
+			close(done)
+			return nil
+		},
+	}
+}
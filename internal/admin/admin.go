@@ -0,0 +1,233 @@
+// Package admin implements the admin server, a listener separate from the API
+// server that exposes profiling, build and runtime information to operators.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/FotiadisM/service-template/internal/config"
+	"github.com/FotiadisM/service-template/pkg/health"
+	"github.com/FotiadisM/service-template/pkg/ilog"
+	"github.com/FotiadisM/service-template/pkg/version"
+)
+
+var ErrUnprotected = errors.New("admin server must either bind to localhost or require a token")
+
+type Server struct {
+	log *slog.Logger
+
+	server *http.Server
+	errc   chan error
+}
+
+// NewServer creates the admin server listening on config.Admin.Addr. Requests
+// must carry the admin token as a bearer token if one is configured, otherwise
+// the server must be bound to a loopback address.
+func NewServer(config *config.Config, log *slog.Logger, registry *health.Registry) (*Server, error) {
+	if config.Admin.Token == "" && !isLoopback(config.Admin.Addr) {
+		return nil, fmt.Errorf("%s: %w", config.Admin.Addr, ErrUnprotected)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.HandleFunc("GET /debug/goroutines", goroutinesHandler)
+	mux.HandleFunc("GET /buildinfo", buildInfoHandler)
+	mux.Handle("GET /config", jsonHandler(func() any { return config }))
+	mux.Handle("GET /health", jsonHandler(func() any {
+		return map[health.Probe]health.Report{
+			health.ProbeStartup:   registry.Report(health.ProbeStartup),
+			health.ProbeReadiness: registry.Report(health.ProbeReadiness),
+			health.ProbeLiveness:  registry.Report(health.ProbeLiveness),
+		}
+	}))
+
+	if levels, ok := ilog.LevelsOf(log); ok {
+		h := &levelsHandler{levels: levels, defaultTTL: config.Logging.LevelTTL, log: log}
+		mux.HandleFunc("GET /loglevel", h.list)
+		mux.HandleFunc("PUT /loglevel", h.set)
+		mux.HandleFunc("DELETE /loglevel", h.reset)
+	}
+
+	server := &Server{
+		log: log,
+		server: &http.Server{
+			Addr:    config.Admin.Addr,
+			Handler: protect(mux, config.Admin.Token),
+			// no write timeout, profiles and traces stream for as long as requested
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		errc: make(chan error, 1),
+	}
+
+	return server, nil
+}
+
+// Start listens on the configured address and serves in the background. Errors
+// of the server after Start returns are reported by Err.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+
+	s.log.Info("admin server is listening", "addr", ln.Addr().String())
+	go func() {
+		err := s.server.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.errc <- fmt.Errorf("admin server exited: %w", err)
+		}
+		close(s.errc)
+	}()
+
+	return nil
+}
+
+// Err returns a channel that receives the error the server exits with, if any,
+// and is closed once it stops serving.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
+// Shutdown stops the server. Long running profiles are cut short by ctx.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown admin server: %w", err)
+	}
+
+	return nil
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// protect rejects requests without the bearer token, or from remote addresses
+// if there is no token.
+func protect(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jsonHandler serves the value returned by fn. Fields tagged with json:"-"
+// hold secrets and are left out.
+func jsonHandler(fn func() any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(fn())
+	})
+}
+
+func buildInfoHandler(w http.ResponseWriter, r *http.Request) {
+	info := struct {
+		version.Info
+		GoVersion string `json:"go_version"`
+		GOOS      string `json:"goos"`
+		GOARCH    string `json:"goarch"`
+	}{
+		Info:      version.Get(),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+	}
+
+	jsonHandler(func() any { return info }).ServeHTTP(w, r)
+}
+
+// goroutinesHandler writes the stack of every goroutine, in the format of an
+// unrecovered panic.
+func goroutinesHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// levelsHandler changes the levels of loggers. The logger query parameter
+// names the logger, the root logger if empty.
+type levelsHandler struct {
+	levels     *ilog.Levels
+	defaultTTL time.Duration
+	log        *slog.Logger
+}
+
+func (h *levelsHandler) list(w http.ResponseWriter, r *http.Request) {
+	jsonHandler(func() any { return h.levels.List() }).ServeHTTP(w, r)
+}
+
+// set overrides the level of a logger with the level query parameter, for the
+// duration of the ttl query parameter or the configured LOGGING_LEVEL_TTL.
+func (h *levelsHandler) set(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("logger")
+
+	level, err := ilog.ParseLevel(query.Get("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ttl := h.defaultTTL
+	if s := query.Get("ttl"); s != "" {
+		ttl, err = time.ParseDuration(s)
+		if err != nil || ttl <= 0 {
+			http.Error(w, fmt.Sprintf("invalid ttl %q", s), http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.levels.Set(name, level, ttl)
+	h.log.Info("log level changed", "name", name, "level", level, "ttl", ttl)
+	h.list(w, r)
+}
+
+func (h *levelsHandler) reset(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("logger")
+
+	h.levels.Reset(name)
+	h.log.Info("log level reset", "name", name)
+	h.list(w, r)
+}
@@ -2,29 +2,120 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/sethvargo/go-envconfig"
 )
 
 type Logging struct {
-	Level     int  `env:"LEVEL, default=0"`
+	Level int `env:"LEVEL, default=0"`
+	// JSON sets the default format of the stdout sink to json instead of pretty.
 	JSON      bool `env:"JSON, default=false"`
 	AddSource bool `env:"ADD_SOURCE, default=false"`
+	// Levels sets the levels of named loggers, e.g. db:debug,rpc:warn. Loggers
+	// that are not set follow Level. Both are reloaded on SIGHUP.
+	Levels map[string]string `env:"LEVELS"`
+	// LevelTTL sets how long a level changed at runtime through the admin
+	// server lasts before reverting to the configured one (30m).
+	LevelTTL time.Duration `env:"LEVEL_TTL, default=30m"`
+	// DebugKey signs the tokens of the X-Debug-Log header, which elevate the
+	// logs of a single request to DEBUG. Tokens are created with the
+	// debug-token command. The header is ignored if no key is set.
+	DebugKey string `env:"DEBUG_KEY" json:"-"`
+	// DebugSampledTraces elevates the logs of requests with a sampled trace to DEBUG.
+	DebugSampledTraces bool `env:"DEBUG_SAMPLED_TRACES"`
+	// RedactMode is how sensitive data is redacted from logs: mask, hash or drop (mask).
+	RedactMode string `env:"REDACT_MODE, default=mask"`
+	// RedactKeys are attribute keys redacted in addition to ilog.DefaultRedactKeys.
+	RedactKeys []string `env:"REDACT_KEYS"`
+	// RedactHashKey keys the hashes of the hash redaction mode, which requires
+	// it. Unkeyed hashes of emails or card numbers are easily reversed.
+	RedactHashKey string `env:"REDACT_HASH_KEY" json:"-"`
+	// SampleInitial records of every message and level are logged per
+	// SampleInterval, then one in SampleThereafter. ERROR records are always
+	// logged. A SampleInitial of 0 disables sampling.
+	SampleInterval   time.Duration `env:"SAMPLE_INTERVAL, default=1s"`
+	SampleInitial    int           `env:"SAMPLE_INITIAL, default=100"`
+	SampleThereafter int           `env:"SAMPLE_THEREAFTER, default=100"`
+	// DedupWindow collapses identical records logged within the window into
+	// one with a repeated count. 0 disables deduplication.
+	DedupWindow time.Duration `env:"DEDUP_WINDOW, default=1s"`
+	// Payloads lists the procedures, e.g. /book.v1.BookService/CreateBook, or
+	// services, e.g. book.v1.BookService, whose messages are logged. * logs the
+	// messages of every procedure. Sensitive fields are redacted.
+	Payloads []string `env:"PAYLOADS"`
+	// PayloadLimit truncates logged messages to this many bytes (4096).
+	PayloadLimit int `env:"PAYLOAD_LIMIT, default=4096"`
+
+	// Sinks lists where logs are written: stdout, file, syslog and otel. The
+	// otel sink is skipped if the OTel SDK is disabled. The level of a sink
+	// is the minimum level of the records it writes, in addition to the level
+	// of the logger. Formats are pretty, text or json.
+	Sinks  []string   `env:"SINKS, default=stdout,otel"`
+	Stdout StdoutSink `env:", prefix=STDOUT_"`
+	File   FileSink   `env:", prefix=FILE_"`
+	Syslog SyslogSink `env:", prefix=SYSLOG_"`
+	OTel   OTelSink   `env:", prefix=OTEL_"`
+}
+
+type StdoutSink struct {
+	Level string `env:"LEVEL"`
+	// Format defaults to json if JSON is set, pretty otherwise.
+	Format string `env:"FORMAT"`
+}
+
+type FileSink struct {
+	Level  string `env:"LEVEL"`
+	Format string `env:"FORMAT, default=json"`
+	Path   string `env:"PATH, default=book-svc.log"`
+	// MaxSize is the size in megabytes after which the file is rotated (100).
+	MaxSize int64 `env:"MAX_SIZE, default=100"`
+	// MaxAge is how long a file is written before it is rotated (24h).
+	MaxAge time.Duration `env:"MAX_AGE, default=24h"`
+	// MaxBackups is the number of rotated files kept, 0 keeps all of them (7).
+	MaxBackups int `env:"MAX_BACKUPS, default=7"`
+	// Compress compresses rotated files with gzip.
+	Compress bool `env:"COMPRESS, default=true"`
+}
+
+type SyslogSink struct {
+	Level  string `env:"LEVEL"`
+	Format string `env:"FORMAT, default=text"`
+	// Socket is the path of the unix socket of the syslog daemon, the
+	// default socket of the system if empty.
+	Socket string `env:"SOCKET"`
+	// Tag is the tag of the messages, the service name if empty.
+	Tag string `env:"TAG"`
+}
+
+type OTelSink struct {
+	Level string `env:"LEVEL"`
 }
 
 type DB struct {
-	Host            string            `env:"HOST, required"`
-	Port            int               `env:"PORT, required"`
-	Username        string            `env:"USER, required"                  json:"-"`
-	Password        string            `env:"PASS, required"                  json:"-"`
-	Database        string            `env:"DBNAME, required"`
-	Params          map[string]string `env:"PARAMS, default=sslmode:disable"`
-	MaxOpenConns    int               `env:"OPEN_CONNS"`
-	MaxIdleConns    int               `env:"IDLE_CONNS"`
-	ConnMaxLifetime time.Duration     `env:"CONN_LIFETIME"`
+	Host     string            `env:"HOST, required"`
+	Port     int               `env:"PORT, required"`
+	Username string            `env:"USER, required"                  json:"-"`
+	Password string            `env:"PASS, required"                  json:"-"`
+	Database string            `env:"DBNAME, required"`
+	Params   map[string]string `env:"PARAMS, default=sslmode:disable" json:"-"`
+	// Replicas are connection URIs of read replicas. Read only queries are
+	// routed to the replica with the least acquired connections.
+	Replicas []string `env:"REPLICAS" json:"-"`
+
+	// MaxOpenConns is the maximum size of the connection pool (pgxpool default).
+	MaxOpenConns int32 `env:"OPEN_CONNS"`
+	// MinConns is the minimum number of connections kept open in the pool (pgxpool default).
+	MinConns int32 `env:"MIN_CONNS"`
+	// ConnMaxLifetime is the duration after which a connection is closed (pgxpool default).
+	ConnMaxLifetime time.Duration `env:"CONN_LIFETIME"`
+	// ConnMaxIdleTime is the duration after which an idle connection is closed (pgxpool default).
+	ConnMaxIdleTime time.Duration `env:"CONN_IDLE_TIME"`
+	// HealthCheckPeriod is how often idle connections are health checked (pgxpool default).
+	HealthCheckPeriod time.Duration `env:"HEALTH_CHECK_PERIOD"`
 }
 
 type Redis struct {
@@ -36,8 +127,13 @@ type Redis struct {
 }
 
 type Instrumentation struct {
+	// ServiceName names the service in telemetry and logs.
+	ServiceName      string `env:"OTEL_SERVICE_NAME, default=book-svc"`
 	OtelExporterAddr string `env:"OTEL_EXPORTER_ADDR"`
 	OtelSDKDisabled  bool   `env:"OTEL_SDK_DISABLED"`
+	// SentryDSN sends recovered panics to a Sentry compatible server. Panics
+	// are logged if it is not set.
+	SentryDSN string `env:"SENTRY_DSN" json:"-"`
 }
 
 type Cors struct {
@@ -50,6 +146,21 @@ type Cors struct {
 	AllowPrivateNetwork bool     `env:"ALLOW_PRIVATE_NETWORK, default=false"`
 }
 
+type Health struct {
+	// Interval sets how often health checks are evaluated in the background (10s).
+	Interval time.Duration `env:"INTERVAL, default=10s"`
+	// Timeout sets how long a single health check may take (2s).
+	Timeout time.Duration `env:"TIMEOUT, default=2s"`
+}
+
+type Admin struct {
+	// Addr enables the admin server, serving pprof, expvar, build information,
+	// the config and health details. It must be a loopback address unless Token is set.
+	Addr string `env:"ADDR"`
+	// Token is required as a bearer token by the admin server if set.
+	Token string `env:"TOKEN" json:"-"`
+}
+
 type Server struct {
 	Addr string `env:"ADDR, default=:8080"`
 
@@ -58,6 +169,9 @@ type Server struct {
 	// DisableRESTTranscoding disable HTTP JSON+REST to RPC transcoding.
 	// It support Google's HTTP transcoding options.
 	DisableRESTTranscoding bool `env:"DISABLE_REST_TRANSCODING"`
+	// MigrateOnStart applies the embedded migrations when the server starts.
+	// Readiness reports NOT_SERVING until the schema is up to date.
+	MigrateOnStart bool `env:"MIGRATE_ON_START"`
 
 	// ReadTimeout sets the maximum time a client has to fully stream a request (5s).
 	ReadTimeout time.Duration `env:"READ_TIMEOUT, default=5s"`
@@ -73,25 +187,52 @@ type Server struct {
 	// values, including the request line. It does not limit the
 	// size of the request body (1MB).
 	MaxHeaderBytes int `env:"MAX_HEADE_RBYTES, default=1048576"`
+	// DrainPeriod sets how long readiness reports NOT_SERVING before the server
+	// stops accepting requests on shutdown, so that load balancers stop routing to it (5s).
+	DrainPeriod time.Duration `env:"DRAIN_PERIOD, default=5s"`
 	// ShutdownTimeout defines how long Graceful shutdown will wait before forcibly shutting down.
 	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT, default=5s"`
 }
 
 type Config struct {
+	// Environment names the deployment, e.g. production or staging. In
+	// development the errors of panics carry their stack.
+	Environment string `env:"ENVIRONMENT, default=production"`
+
 	Inst    Instrumentation
 	Server  Server  `env:", prefix=SERVER_"`
 	DB      DB      `env:", prefix=PSQL_"`
 	Logging Logging `env:", prefix=LOGGING_"`
 	Cors    Cors    `env:", prefix=CORS_"`
 	Redis   Redis   `env:", prefix=REDIS_"`
+	Health  Health  `env:", prefix=HEALTH_"`
+	Admin   Admin   `env:", prefix=ADMIN_"`
 }
 
-func NewConfig(ctx context.Context) *Config {
+// Dev reports whether the service runs in development.
+func (c *Config) Dev() bool {
+	return c.Environment == "development"
+}
+
+var ErrNoRedactHashKey = errors.New("LOGGING_REDACT_HASH_KEY is required by LOGGING_REDACT_MODE=hash")
+
+func NewConfig(ctx context.Context) (*Config, error) {
 	config := &Config{}
 	if err := envconfig.Process(ctx, config); err != nil {
-		fmt.Fprintf(os.Stdout, "failed to parse config: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return config, nil
+}
+
+// validate checks the values that depend on each other.
+func (c *Config) validate() error {
+	if strings.EqualFold(c.Logging.RedactMode, "hash") && c.Logging.RedactHashKey == "" {
+		return ErrNoRedactHashKey
 	}
 
-	return config
+	return nil
 }
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		logging Logging
+		wantErr error
+	}{
+		{"Mask", Logging{RedactMode: "mask"}, nil},
+		{"Drop", Logging{RedactMode: "drop"}, nil},
+		{"HashWithKey", Logging{RedactMode: "hash", RedactHashKey: "key"}, nil},
+		{"HashWithoutKey", Logging{RedactMode: "hash"}, ErrNoRedactHashKey},
+		{"HashUpperCase", Logging{RedactMode: "HASH"}, ErrNoRedactHashKey},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := (&Config{Logging: tc.logging}).validate()
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
@@ -2,100 +2,132 @@ package database
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
 
-	"github.com/XSAM/otelsql"
-	// PostgreSQL databse driver.
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/FotiadisM/service-template/internal/config"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
-	"github.com/FotiadisM/service-template/pkg/ilog"
 )
 
 type DB struct {
-	DB *sql.DB
+	// Pool is the connection pool of the primary.
+	Pool *pgxpool.Pool
 	*queries.Queries
+
+	replicas []*replica
+	// tx is set when the DB is bound to a transaction, see WithTx.
+	tx pgx.Tx
+	// retry configures the retries of InTx.
+	retry []RetryOption
 }
 
-func New(config config.DB) (*DB, error) {
-	str := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s", config.Host, config.Port, config.Username, config.Password, config.Database)
+// ConnString builds a postgres:// connection URI from config, escaping
+// credentials and parameters.
+func ConnString(config config.DB) string {
+	params := url.Values{}
 	for k, v := range config.Params {
-		str += fmt.Sprintf(" %s=%s", k, v)
+		params.Set(k, v)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.Username, config.Password),
+		Host:     net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		Path:     "/" + config.Database,
+		RawQuery: params.Encode(),
 	}
 
-	conn, err := otelsql.Open("pgx", str, otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true}))
+	return u.String()
+}
+
+func newPool(ctx context.Context, connString string, config config.DB, role string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
 
 	if config.MaxOpenConns > 0 {
-		conn.SetMaxOpenConns(config.MaxOpenConns)
+		poolConfig.MaxConns = config.MaxOpenConns
 	}
-	if config.MaxIdleConns > 0 {
-		conn.SetMaxIdleConns(config.MaxIdleConns)
+	if config.MinConns > 0 {
+		poolConfig.MinConns = config.MinConns
 	}
 	if config.ConnMaxLifetime > 0 {
-		conn.SetConnMaxLifetime(config.ConnMaxLifetime)
+		poolConfig.MaxConnLifetime = config.ConnMaxLifetime
 	}
-
-	db := &DB{
-		DB:      conn,
-		Queries: queries.New(conn),
+	if config.ConnMaxIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.ConnMaxIdleTime
 	}
-
-	return db, nil
-}
-
-func NewFromDBConn(conn *sql.DB) (*DB, error) {
-	db := &DB{
-		DB:      conn,
-		Queries: queries.New(conn),
+	if config.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.HealthCheckPeriod
 	}
+	roleAttr := attribute.String("db.pool.role", role)
+	poolConfig.ConnConfig.Tracer = otelpgx.NewTracer(
+		otelpgx.WithTrimSQLInSpanName(),
+		otelpgx.WithAttributes(roleAttr),
+	)
 
-	return db, nil
-}
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
 
-type TxFn func(db *DB) (err error)
+	if err = otelpgx.RecordStats(pool, otelpgx.WithStatsAttributes(roleAttr)); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to record database stats: %w", err)
+	}
 
-func WithTx(ctx context.Context, db *DB, fn TxFn) error {
-	return WithConfiguredTx(ctx, db, nil, fn)
+	return pool, nil
 }
 
-func WithConfiguredTx(ctx context.Context, db *DB, options *sql.TxOptions, fn TxFn) error {
-	log := ilog.FromContext(ctx)
-
-	tx, err := db.DB.BeginTx(ctx, options)
+func New(ctx context.Context, config config.DB) (*DB, error) {
+	pool, err := newPool(ctx, ConnString(config), config, "primary")
 	if err != nil {
-		log.Error("failed to begin transaction", ilog.Err(err))
-		return err
+		return nil, err
 	}
 
-	defer func() {
-		if p := recover(); p != nil {
-			log.Error("recovered from panic, rolling back transaction and panicking again")
-			if err = tx.Rollback(); err != nil {
-				log.Error("failed to roll back transaction", ilog.Err(err))
+	replicas := make([]*pgxpool.Pool, 0, len(config.Replicas))
+	for i, dsn := range config.Replicas {
+		var replica *pgxpool.Pool
+		replica, err = newPool(ctx, dsn, config, "replica")
+		if err != nil {
+			pool.Close()
+			for _, r := range replicas {
+				r.Close()
 			}
-			panic(p)
+			return nil, fmt.Errorf("replica %d: %w", i, err)
 		}
-	}()
+		replicas = append(replicas, replica)
+	}
 
-	err = fn(&DB{
-		DB:      db.DB,
-		Queries: db.Queries.WithTx(tx),
-	})
-	if err != nil {
-		if txErr := tx.Rollback(); txErr != nil {
-			log.Error("failed to roll back transaction", ilog.Err(err))
-		}
-		return err
+	return NewFromPool(pool, replicas...), nil
+}
+
+// NewFromPool creates a DB using pool as the primary. Reads are routed to
+// replicas when any are given.
+func NewFromPool(pool *pgxpool.Pool, replicas ...*pgxpool.Pool) *DB {
+	r := &router{primary: pool}
+	for _, p := range replicas {
+		rep := &replica{pool: p}
+		rep.healthy.Store(true)
+		r.replicas = append(r.replicas, rep)
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	return &DB{
+		Pool:     pool,
+		Queries:  queries.New(r),
+		replicas: r.replicas,
 	}
+}
 
-	return nil
+func (db *DB) Close() {
+	closeReplicas(db.replicas)
+	db.Pool.Close()
 }
@@ -0,0 +1,430 @@
+// Package migrate applies atlas formatted migration directories without
+// requiring the atlas binary. Applied migrations are recorded in the revisions
+// table of atlas, so the database can be migrated by either of them.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	atlasmigrate "ariga.io/atlas/sql/migrate"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+const (
+	// lockID is the key of the advisory lock that serializes migrations
+	// between replicas starting at the same time.
+	lockID int64 = 0x626f6f6b2d737663 // "book-svc"
+
+	// revisionsTable is where atlas records revisions when the connection URL
+	// is not bound to a schema, as in atlas.hcl.
+	revisionsTable  = "atlas_schema_revisions.atlas_schema_revisions"
+	operatorVersion = "service-template/migrate"
+	downDir         = "down"
+)
+
+var (
+	ErrNoDownMigration = errors.New("no down migration")
+	ErrChecksum        = errors.New("applied migration checksum mismatch")
+	ErrPartial         = errors.New("migration partially applied by atlas")
+)
+
+type Migration struct {
+	// Version is the version prefix of the migration file name.
+	Version string
+	// Name is the migration file name.
+	Name string
+	// Checksum is the hash of the file in atlas.sum.
+	Checksum string
+
+	Up   []byte
+	Down []byte
+}
+
+type Migrator struct {
+	fsys       fs.FS
+	migrations []Migration
+}
+
+// New loads the migrations found in fsys. Up migrations are the *.sql files at
+// the root of fsys, down migrations are files with the same name in down/.
+func New(fsys fs.FS) (*Migrator, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	slices.Sort(names)
+
+	sumData, err := fs.ReadFile(fsys, atlasmigrate.HashFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", atlasmigrate.HashFileName, err)
+	}
+	var sum atlasmigrate.HashFile
+	if err = sum.UnmarshalText(sumData); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", atlasmigrate.HashFileName, err)
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		up, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		down, err := fs.ReadFile(fsys, path.Join(downDir, name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read down migration %s: %w", name, err)
+		}
+
+		checksum, _ := sum.SumByName(name)
+		migrations = append(migrations, Migration{
+			Version:  atlasmigrate.NewLocalFile(name, up).Version(),
+			Name:     name,
+			Checksum: checksum,
+			Up:       up,
+			Down:     down,
+		})
+	}
+
+	return &Migrator{fsys: fsys, migrations: migrations}, nil
+}
+
+// Verify checks the integrity of the migration files against atlas.sum.
+func (m *Migrator) Verify() error {
+	files := make([]atlasmigrate.File, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		files = append(files, atlasmigrate.NewLocalFile(mig.Name, mig.Up))
+	}
+
+	expected, err := atlasmigrate.NewHashFile(files)
+	if err != nil {
+		return fmt.Errorf("failed to hash migrations: %w", err)
+	}
+
+	sumData, err := fs.ReadFile(m.fsys, atlasmigrate.HashFileName)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", atlasmigrate.HashFileName, err)
+	}
+	var actual atlasmigrate.HashFile
+	if err = actual.UnmarshalText(sumData); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", atlasmigrate.HashFileName, err)
+	}
+
+	if expected.Sum() != actual.Sum() {
+		for i, h := range actual {
+			if i >= len(expected) || expected[i] != h {
+				return fmt.Errorf("%s: %w", h.N, atlasmigrate.ErrChecksumMismatch)
+			}
+		}
+		return fmt.Errorf("%s: %w", atlasmigrate.HashFileName, atlasmigrate.ErrChecksumMismatch)
+	}
+
+	return nil
+}
+
+// Migrations returns all known migrations ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return slices.Clone(m.migrations)
+}
+
+// Latest returns the version of the newest migration.
+func (m *Migrator) Latest() string {
+	if len(m.migrations) == 0 {
+		return ""
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+type Revision struct {
+	Version   string
+	Checksum  string
+	AppliedAt time.Time
+	// Baseline marks the revision written by atlas for a baseline version,
+	// every migration up to it counts as applied.
+	Baseline bool
+	// Partial marks a migration that atlas failed to apply completely.
+	Partial bool
+}
+
+type MigrationStatus struct {
+	Migration
+
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Status struct {
+	// Current is the version of the last applied migration.
+	Current string
+	// Latest is the version of the newest known migration.
+	Latest     string
+	Migrations []MigrationStatus
+}
+
+// Pending returns the number of migrations that are not applied.
+func (s *Status) Pending() int {
+	n := 0
+	for _, m := range s.Migrations {
+		if !m.Applied {
+			n++
+		}
+	}
+
+	return n
+}
+
+func withLock(ctx context.Context, conn *pgx.Conn, fn func() error) error {
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID)
+		if err != nil {
+			ilog.FromContext(ctx).Error("failed to release migration lock", ilog.Err(err))
+		}
+	}()
+
+	return fn()
+}
+
+// ensureRevisionsTable creates the revisions table the way atlas does, if the
+// atlas CLI never ran against the database.
+func ensureRevisionsTable(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS atlas_schema_revisions;
+	CREATE TABLE IF NOT EXISTS `+revisionsTable+` (
+		version CHARACTER VARYING NOT NULL,
+		description CHARACTER VARYING NOT NULL,
+		type BIGINT NOT NULL DEFAULT 2,
+		applied BIGINT NOT NULL DEFAULT 0,
+		total BIGINT NOT NULL DEFAULT 0,
+		executed_at TIMESTAMPTZ NOT NULL,
+		execution_time BIGINT NOT NULL,
+		error TEXT NULL,
+		error_stmt TEXT NULL,
+		hash CHARACTER VARYING NOT NULL,
+		partial_hashes JSONB NULL,
+		operator_version CHARACTER VARYING NOT NULL,
+
+		PRIMARY KEY (version)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create revisions table: %w", err)
+	}
+
+	return nil
+}
+
+// history is the set of revisions recorded in the database.
+type history struct {
+	revisions map[string]Revision
+	// baseline is the version of the baseline revision, if any.
+	baseline string
+}
+
+// applied returns the revision of mig, if it is applied. Migrations up to the
+// baseline are applied even though atlas records no revision for them.
+func (h *history) applied(mig Migration) (Revision, bool) {
+	if rev, ok := h.revisions[mig.Version]; ok {
+		return rev, !rev.Partial
+	}
+	if h.baseline != "" && mig.Version <= h.baseline {
+		return Revision{Version: mig.Version, Baseline: true}, true
+	}
+
+	return Revision{}, false
+}
+
+func revisions(ctx context.Context, conn *pgx.Conn) (*history, error) {
+	h := &history{revisions: map[string]Revision{}}
+
+	var exists bool
+	err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", revisionsTable).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up revisions table: %w", err)
+	}
+	if !exists {
+		return h, nil
+	}
+
+	// versions starting with a dot are atlas metadata, not migrations
+	rows, err := conn.Query(ctx, `SELECT version, hash, executed_at, type, applied, total, COALESCE(error, '')
+		FROM `+revisionsTable+` WHERE version NOT LIKE '.%'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %w", err)
+	}
+	revs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Revision, error) {
+		var (
+			r              Revision
+			typ            int64
+			applied, total int
+			errMsg         string
+		)
+		err := row.Scan(&r.Version, &r.Checksum, &r.AppliedAt, &typ, &applied, &total, &errMsg)
+		r.Baseline = atlasmigrate.RevisionType(typ) == atlasmigrate.RevisionTypeBaseline
+		r.Partial = !atlasmigrate.RevisionType(typ).Has(atlasmigrate.RevisionTypeResolved) && (applied < total || errMsg != "")
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan revisions: %w", err)
+	}
+
+	for _, r := range revs {
+		h.revisions[r.Version] = r
+		if r.Baseline {
+			h.baseline = r.Version
+		}
+	}
+
+	return h, nil
+}
+
+func writeRevision(ctx context.Context, tx pgx.Tx, mig Migration, executedAt time.Time) error {
+	f := atlasmigrate.NewLocalFile(mig.Name, mig.Up)
+	stmts, err := f.Stmts()
+	if err != nil {
+		return fmt.Errorf("failed to parse statements: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO `+revisionsTable+`
+		(version, description, type, applied, total, executed_at, execution_time, hash, operator_version)
+		VALUES ($1, $2, $3, $4, $4, $5, $6, $7, $8)`,
+		mig.Version, f.Desc(), int64(atlasmigrate.RevisionTypeExecute), len(stmts),
+		executedAt, time.Since(executedAt).Nanoseconds(), mig.Checksum, operatorVersion,
+	)
+
+	return err
+}
+
+// Status reports which migrations are applied to the database.
+func (m *Migrator) Status(ctx context.Context, conn *pgx.Conn) (*Status, error) {
+	h, err := revisions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Latest: m.Latest()}
+	for _, mig := range m.migrations {
+		rev, applied := h.applied(mig)
+		if applied {
+			status.Current = mig.Version
+		}
+		status.Migrations = append(status.Migrations, MigrationStatus{
+			Migration: mig,
+			Applied:   applied,
+			AppliedAt: rev.AppliedAt,
+		})
+	}
+
+	return status, nil
+}
+
+// IsLatest reports whether every known migration is applied to the database.
+func (m *Migrator) IsLatest(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	status, err := m.Status(ctx, conn)
+	if err != nil {
+		return false, err
+	}
+
+	return status.Pending() == 0, nil
+}
+
+// Up verifies the migration files and applies every pending migration, each
+// one in its own transaction. Concurrent callers are serialized with an
+// advisory lock, so only one of them applies the migrations.
+func (m *Migrator) Up(ctx context.Context, conn *pgx.Conn) ([]Migration, error) {
+	if err := m.Verify(); err != nil {
+		return nil, err
+	}
+
+	applied := []Migration{}
+	err := withLock(ctx, conn, func() error {
+		if err := ensureRevisionsTable(ctx, conn); err != nil {
+			return err
+		}
+
+		h, err := revisions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if rev, ok := h.revisions[mig.Version]; ok && rev.Partial {
+				return fmt.Errorf("%s: %w", mig.Name, ErrPartial)
+			}
+			if rev, ok := h.applied(mig); ok {
+				// atlas records no checksum for baselines
+				if rev.Checksum != "" && rev.Checksum != mig.Checksum {
+					return fmt.Errorf("%s: %w", mig.Name, ErrChecksum)
+				}
+				continue
+			}
+
+			err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				executedAt := time.Now()
+				if _, err := tx.Exec(ctx, string(mig.Up)); err != nil {
+					return err
+				}
+				return writeRevision(ctx, tx, mig, executedAt)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last n applied migrations, newest first. Migrations up to
+// an atlas baseline are never reverted.
+func (m *Migrator) Down(ctx context.Context, conn *pgx.Conn, n int) ([]Migration, error) {
+	reverted := []Migration{}
+	err := withLock(ctx, conn, func() error {
+		h, err := revisions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			mig := m.migrations[i]
+			rev, ok := h.applied(mig)
+			if !ok {
+				continue
+			}
+			if rev.Baseline {
+				break
+			}
+			if len(strings.TrimSpace(string(mig.Down))) == 0 {
+				return fmt.Errorf("%s: %w", mig.Name, ErrNoDownMigration)
+			}
+
+			err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, string(mig.Down)); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, "DELETE FROM "+revisionsTable+" WHERE version = $1", mig.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
@@ -0,0 +1,452 @@
+package migrate_test
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	atlasmigrate "ariga.io/atlas/sql/migrate"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/internal/database/migrate"
+	"github.com/FotiadisM/service-template/pkg/testdb"
+)
+
+// atlasDDL creates the revisions table the way the atlas CLI does.
+const atlasDDL = `CREATE SCHEMA atlas_schema_revisions;
+CREATE TABLE atlas_schema_revisions.atlas_schema_revisions (
+	"version" character varying NOT NULL,
+	"description" character varying NOT NULL,
+	"type" bigint NOT NULL DEFAULT 2,
+	"applied" bigint NOT NULL DEFAULT 0,
+	"total" bigint NOT NULL DEFAULT 0,
+	"executed_at" timestamptz NOT NULL,
+	"execution_time" bigint NOT NULL,
+	"error" text NULL,
+	"error_stmt" text NULL,
+	"hash" character varying NOT NULL,
+	"partial_hashes" jsonb NULL,
+	"operator_version" character varying NOT NULL,
+	PRIMARY KEY ("version")
+)`
+
+var (
+	serverOnce sync.Once
+	server     *testdb.Server
+	serverErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if server != nil {
+		_ = server.Close(context.Background())
+	}
+	os.Exit(code)
+}
+
+// newConns returns n connections to a new, empty database.
+func newConns(t *testing.T, n int) []*pgx.Conn {
+	t.Helper()
+	ctx := context.Background()
+
+	serverOnce.Do(func() {
+		server, serverErr = testdb.New(ctx)
+	})
+	testdb.SkipUnavailable(t, serverErr)
+	require.NoError(t, serverErr, "failed to create template database")
+
+	pool := server.NewDatabase(t)
+	conns := make([]*pgx.Conn, 0, n)
+	for range n {
+		c, err := pool.Acquire(ctx)
+		require.NoError(t, err)
+		t.Cleanup(c.Release)
+		conns = append(conns, c.Conn())
+	}
+
+	return conns
+}
+
+func newMigrator(t *testing.T) *migrate.Migrator {
+	t.Helper()
+
+	m, err := migrate.New(database.Migrations())
+	require.NoError(t, err)
+
+	return m
+}
+
+func tableExists(t *testing.T, conn *pgx.Conn, name string) bool {
+	t.Helper()
+
+	var exists bool
+	err := conn.QueryRow(context.Background(), "SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists)
+	require.NoError(t, err)
+
+	return exists
+}
+
+// migrationsFS returns a migration directory of files with a valid atlas.sum.
+func migrationsFS(t *testing.T, files map[string]string) fstest.MapFS {
+	t.Helper()
+
+	fsys := fstest.MapFS{}
+	var local []atlasmigrate.File
+	for name, data := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(data)}
+		local = append(local, atlasmigrate.NewLocalFile(name, []byte(data)))
+	}
+	// atlas.sum lists files by name
+	slices.SortFunc(local, func(a, b atlasmigrate.File) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	sum, err := atlasmigrate.NewHashFile(local)
+	require.NoError(t, err)
+	sumData, err := sum.MarshalText()
+	require.NoError(t, err)
+	fsys[atlasmigrate.HashFileName] = &fstest.MapFile{Data: sumData}
+
+	return fsys
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	m := newMigrator(t)
+	migrations := m.Migrations()
+	require.Len(t, migrations, 3)
+
+	for i, mig := range migrations {
+		assert.NotEmpty(t, mig.Checksum, mig.Name)
+		assert.NotEmpty(t, mig.Down, mig.Name)
+		if i > 0 {
+			assert.Less(t, migrations[i-1].Version, mig.Version)
+		}
+	}
+	assert.Equal(t, "20250217112710", m.Latest())
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Embedded", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, newMigrator(t).Verify())
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+		fsys := migrationsFS(t, map[string]string{
+			"1_a.sql": "CREATE TABLE a (id int);",
+			"2_b.sql": "CREATE TABLE b (id int);",
+		})
+
+		m, err := migrate.New(fsys)
+		require.NoError(t, err)
+		require.NoError(t, m.Verify())
+	})
+
+	t.Run("ModifiedFile", func(t *testing.T) {
+		t.Parallel()
+		fsys := migrationsFS(t, map[string]string{
+			"1_a.sql": "CREATE TABLE a (id int);",
+			"2_b.sql": "CREATE TABLE b (id int);",
+		})
+		fsys["1_a.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE a (id bigint);")}
+
+		m, err := migrate.New(fsys)
+		require.NoError(t, err)
+		err = m.Verify()
+		require.ErrorIs(t, err, atlasmigrate.ErrChecksumMismatch)
+		assert.ErrorContains(t, err, "1_a.sql")
+	})
+
+	t.Run("AddedFile", func(t *testing.T) {
+		t.Parallel()
+		fsys := migrationsFS(t, map[string]string{
+			"1_a.sql": "CREATE TABLE a (id int);",
+		})
+		fsys["2_b.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id int);")}
+
+		m, err := migrate.New(fsys)
+		require.NoError(t, err)
+		require.ErrorIs(t, m.Verify(), atlasmigrate.ErrChecksumMismatch)
+	})
+
+	t.Run("MissingSum", func(t *testing.T) {
+		t.Parallel()
+		_, err := migrate.New(fstest.MapFS{
+			"1_a.sql": &fstest.MapFile{Data: []byte("CREATE TABLE a (id int);")},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestUp(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := newConns(t, 1)[0]
+	m := newMigrator(t)
+
+	applied, err := m.Up(ctx, conn)
+	require.NoError(t, err)
+	require.Equal(t, m.Migrations(), applied)
+	assert.True(t, tableExists(t, conn, "authors"))
+	assert.True(t, tableExists(t, conn, "books"))
+	assert.True(t, tableExists(t, conn, "book_reviews"))
+
+	applied, err = m.Up(ctx, conn)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	status, err := m.Status(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, m.Latest(), status.Current)
+	assert.Zero(t, status.Pending())
+
+	// revisions are recorded the way atlas records them
+	rows, err := conn.Query(ctx, `SELECT version, type, applied, total, hash
+		FROM atlas_schema_revisions.atlas_schema_revisions ORDER BY version`)
+	require.NoError(t, err)
+	type revision struct {
+		Version        string
+		Type           int64
+		Applied, Total int
+		Hash           string
+	}
+	revs, err := pgx.CollectRows(rows, pgx.RowToStructByPos[revision])
+	require.NoError(t, err)
+	require.Len(t, revs, len(m.Migrations()))
+	for i, mig := range m.Migrations() {
+		assert.Equal(t, mig.Version, revs[i].Version)
+		assert.Equal(t, int64(atlasmigrate.RevisionTypeExecute), revs[i].Type)
+		assert.Positive(t, revs[i].Total)
+		assert.Equal(t, revs[i].Total, revs[i].Applied)
+		assert.Equal(t, mig.Checksum, revs[i].Hash)
+	}
+}
+
+func TestDown(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := newConns(t, 1)[0]
+	m := newMigrator(t)
+	migrations := m.Migrations()
+
+	_, err := m.Up(ctx, conn)
+	require.NoError(t, err)
+
+	reverted, err := m.Down(ctx, conn, 1)
+	require.NoError(t, err)
+	require.Equal(t, migrations[2:], reverted)
+	assert.False(t, tableExists(t, conn, "book_reviews"))
+	assert.True(t, tableExists(t, conn, "books"))
+
+	status, err := m.Status(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, migrations[1].Version, status.Current)
+	assert.Equal(t, 1, status.Pending())
+
+	reverted, err = m.Down(ctx, conn, 10)
+	require.NoError(t, err)
+	require.Equal(t, []migrate.Migration{migrations[1], migrations[0]}, reverted)
+	assert.False(t, tableExists(t, conn, "authors"))
+
+	status, err = m.Status(ctx, conn)
+	require.NoError(t, err)
+	assert.Empty(t, status.Current)
+	assert.Equal(t, len(migrations), status.Pending())
+
+	applied, err := m.Up(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, migrations, applied)
+}
+
+func TestDownWithoutDownMigration(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := newConns(t, 1)[0]
+
+	m, err := migrate.New(migrationsFS(t, map[string]string{
+		"1_a.sql": "CREATE TABLE a (id int);",
+	}))
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx, conn)
+	require.NoError(t, err)
+
+	_, err = m.Down(ctx, conn, 1)
+	require.ErrorIs(t, err, migrate.ErrNoDownMigration)
+	assert.True(t, tableExists(t, conn, "a"))
+}
+
+func TestUpChecksumMismatch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := newConns(t, 1)[0]
+	m := newMigrator(t)
+
+	_, err := m.Up(ctx, conn)
+	require.NoError(t, err)
+
+	_, err = conn.Exec(ctx, `UPDATE atlas_schema_revisions.atlas_schema_revisions
+		SET hash = 'h1:changed' WHERE version = $1`, m.Migrations()[0].Version)
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx, conn)
+	require.ErrorIs(t, err, migrate.ErrChecksum)
+	assert.ErrorContains(t, err, m.Migrations()[0].Name)
+}
+
+func TestUpFailedMigration(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conn := newConns(t, 1)[0]
+
+	m, err := migrate.New(migrationsFS(t, map[string]string{
+		"1_a.sql": "CREATE TABLE a (id int);",
+		"2_b.sql": "CREATE TABLE b (id int); SELECT missing FROM nowhere;",
+	}))
+	require.NoError(t, err)
+
+	applied, err := m.Up(ctx, conn)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "2_b.sql")
+	require.Len(t, applied, 1)
+
+	// the failed migration is rolled back as a whole
+	assert.False(t, tableExists(t, conn, "b"))
+	status, err := m.Status(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Pending())
+}
+
+func TestUpAfterAtlas(t *testing.T) {
+	t.Parallel()
+	m := newMigrator(t)
+	migrations := m.Migrations()
+
+	// atlasApply applies migrations like the atlas CLI does.
+	atlasApply := func(t *testing.T, conn *pgx.Conn, migrations []migrate.Migration) {
+		t.Helper()
+		ctx := context.Background()
+
+		for _, mig := range migrations {
+			_, err := conn.Exec(ctx, string(mig.Up))
+			require.NoError(t, err)
+			_, err = conn.Exec(ctx, `INSERT INTO atlas_schema_revisions.atlas_schema_revisions
+				(version, description, type, applied, total, executed_at, execution_time, hash, operator_version)
+				VALUES ($1, '', 2, 1, 1, now(), 0, $2, 'Atlas CLI v0.31.0')`,
+				mig.Version, mig.Checksum,
+			)
+			require.NoError(t, err)
+		}
+	}
+
+	t.Run("Applied", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		conn := newConns(t, 1)[0]
+
+		_, err := conn.Exec(ctx, atlasDDL)
+		require.NoError(t, err)
+		// atlas keeps metadata in the revisions table too
+		_, err = conn.Exec(ctx, `INSERT INTO atlas_schema_revisions.atlas_schema_revisions
+			(version, description, type, executed_at, execution_time, hash, operator_version)
+			VALUES ('.atlas_cloud_identifiers', 'id', 2, now(), 0, '', 'Atlas CLI v0.31.0')`)
+		require.NoError(t, err)
+		atlasApply(t, conn, migrations[:2])
+
+		applied, err := m.Up(ctx, conn)
+		require.NoError(t, err)
+		assert.Equal(t, migrations[2:], applied)
+
+		status, err := m.Status(ctx, conn)
+		require.NoError(t, err)
+		assert.Len(t, status.Migrations, len(migrations))
+		assert.Zero(t, status.Pending())
+	})
+
+	t.Run("Baseline", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		conn := newConns(t, 1)[0]
+
+		_, err := conn.Exec(ctx, atlasDDL)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, string(migrations[0].Up))
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO atlas_schema_revisions.atlas_schema_revisions
+			(version, description, type, executed_at, execution_time, hash, operator_version)
+			VALUES ($1, '', 1, now(), 0, '', 'Atlas CLI v0.31.0')`, migrations[0].Version)
+		require.NoError(t, err)
+
+		applied, err := m.Up(ctx, conn)
+		require.NoError(t, err)
+		assert.Equal(t, migrations[1:], applied)
+
+		// the baseline itself is not reverted
+		reverted, err := m.Down(ctx, conn, 10)
+		require.NoError(t, err)
+		assert.Len(t, reverted, 2)
+		assert.True(t, tableExists(t, conn, "authors"))
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		conn := newConns(t, 1)[0]
+
+		_, err := conn.Exec(ctx, atlasDDL)
+		require.NoError(t, err)
+		_, err = conn.Exec(ctx, `INSERT INTO atlas_schema_revisions.atlas_schema_revisions
+			(version, description, type, applied, total, executed_at, execution_time, error, hash, operator_version)
+			VALUES ($1, '', 2, 0, 1, now(), 0, 'syntax error', $2, 'Atlas CLI v0.31.0')`,
+			migrations[0].Version, migrations[0].Checksum,
+		)
+		require.NoError(t, err)
+
+		_, err = m.Up(ctx, conn)
+		require.ErrorIs(t, err, migrate.ErrPartial)
+	})
+}
+
+func TestUpConcurrent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conns := newConns(t, 4)
+	m := newMigrator(t)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		applied []migrate.Migration
+		errs    []error
+	)
+	for _, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			migs, err := m.Up(ctx, conn)
+
+			mu.Lock()
+			defer mu.Unlock()
+			applied = append(applied, migs...)
+			errs = append(errs, err)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	// the advisory lock lets a single caller apply each migration
+	assert.ElementsMatch(t, m.Migrations(), applied)
+}
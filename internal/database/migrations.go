@@ -0,0 +1,20 @@
+package database
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql migrations/atlas.sum migrations/down/*.sql
+var migrationsFS embed.FS
+
+// Migrations returns the embedded atlas migration directory. Down migrations,
+// which atlas does not know about, live in its down/ sub-directory.
+func Migrations() fs.FS {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
@@ -0,0 +1,2 @@
+-- Drop "authors" table
+DROP TABLE "public"."authors";
@@ -0,0 +1,2 @@
+-- Drop "books" table
+DROP TABLE "public"."books";
@@ -0,0 +1,2 @@
+-- Drop "book_reviews" table
+DROP TABLE "public"."book_reviews";
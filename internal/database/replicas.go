@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+// readQueryPrefixes are the Querier method name prefixes of queries that
+// only read data and can therefore be served by a replica.
+var readQueryPrefixes = []string{"Get", "List", "Count", "Search"}
+
+// sqlc prefixes every generated query with a "-- name: <Method> :<cmd>" comment.
+var sqlcNameRe = regexp.MustCompile(`^--\s*name:\s*(\w+)\s+:(\w+)`)
+
+type primaryCtxKey struct{}
+
+// ContextWithPrimary forces all queries made with ctx to go to the primary.
+// Use it for requests that need to read their own writes.
+func ContextWithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryCtxKey{}).(bool)
+	return v
+}
+
+// IsReadQuery reports whether the sqlc query can be served by a replica,
+// based on the name of its Querier method.
+func IsReadQuery(sql string) bool {
+	m := sqlcNameRe.FindStringSubmatch(sql)
+	if m == nil || m[2] == "exec" || m[2] == "execrows" || m[2] == "execresult" {
+		return false
+	}
+
+	for _, prefix := range readQueryPrefixes {
+		if strings.HasPrefix(m[1], prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// router is a queries.DBTX that sends reads to the least loaded healthy
+// replica and everything else to the primary.
+type router struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+}
+
+var _ queries.DBTX = (*router)(nil)
+
+func (r *router) pick(ctx context.Context, sql string) queries.DBTX {
+	if len(r.replicas) == 0 || usePrimary(ctx) || !IsReadQuery(sql) {
+		return r.primary
+	}
+
+	var (
+		best     *replica
+		bestConn int32
+	)
+	for _, rep := range r.replicas {
+		if !rep.healthy.Load() {
+			continue
+		}
+		acquired := rep.pool.Stat().AcquiredConns()
+		if best == nil || acquired < bestConn {
+			best, bestConn = rep, acquired
+		}
+	}
+	if best == nil {
+		return r.primary
+	}
+
+	return best.pool
+}
+
+func (r *router) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return r.primary.Exec(ctx, sql, args...)
+}
+
+func (r *router) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return r.pick(ctx, sql).Query(ctx, sql, args...)
+}
+
+func (r *router) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return r.pick(ctx, sql).QueryRow(ctx, sql, args...)
+}
+
+// Ping checks the primary and every replica. Replicas that fail are ejected
+// from read routing until a later Ping succeeds. Only a failing primary
+// results in an error, as reads fall back to it.
+func (db *DB) Ping(ctx context.Context) error {
+	log := ilog.FromContext(ctx)
+
+	for i, rep := range db.replicas {
+		err := rep.pool.Ping(ctx)
+		healthy := err == nil
+		if rep.healthy.Swap(healthy) != healthy {
+			if healthy {
+				log.Info("database replica is healthy again, adding it back", "replica", i)
+			} else {
+				log.Warn("database replica is unhealthy, ejecting it", "replica", i, ilog.Err(err))
+			}
+		}
+	}
+
+	if err := db.Pool.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping primary: %w", err)
+	}
+
+	return nil
+}
+
+func closeReplicas(replicas []*replica) {
+	for _, rep := range replicas {
+		rep.pool.Close()
+	}
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+)
+
+// Store is a queries.Querier that can also run a unit of work atomically.
+type Store interface {
+	queries.Querier
+
+	// InTx runs fn inside a transaction. The transaction is committed if fn
+	// returns nil and rolled back otherwise. It may be retried, so fn can run
+	// more than once.
+	InTx(ctx context.Context, fn func(q queries.Querier) error) error
+}
+
+var _ Store = (*DB)(nil)
+
+// InTx retries the transaction on serialization failures and deadlocks, see
+// WithRetryingTx and WithTxRetry.
+func (db *DB) InTx(ctx context.Context, fn func(q queries.Querier) error) error {
+	return WithRetryingTx(ctx, db, pgx.TxOptions{}, func(tx *DB) error {
+		return fn(tx)
+	}, db.retry...)
+}
+
+// WithTxRetry returns a copy of db whose InTx retries transactions with opts.
+func (db *DB) WithTxRetry(opts ...RetryOption) *DB {
+	c := *db
+	c.retry = opts
+
+	return &c
+}
+
+// QuerierStore turns any queries.Querier, like mocks.MockQuerier, into a Store.
+// InTx simply calls fn with the wrapped Querier, there is no transaction.
+type QuerierStore struct {
+	queries.Querier
+}
+
+var _ Store = (*QuerierStore)(nil)
+
+func NewQuerierStore(q queries.Querier) *QuerierStore {
+	return &QuerierStore{Querier: q}
+}
+
+func (s *QuerierStore) InTx(_ context.Context, fn func(q queries.Querier) error) error {
+	return fn(s.Querier)
+}
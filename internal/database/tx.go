@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+type TxFn func(db *DB) (err error)
+
+// WithTx runs fn inside a transaction. If db is already bound to a
+// transaction, fn runs inside a savepoint of that transaction instead, so
+// helpers using WithTx can be composed freely.
+func WithTx(ctx context.Context, db *DB, fn TxFn) error {
+	return WithConfiguredTx(ctx, db, pgx.TxOptions{}, fn)
+}
+
+// WithConfiguredTx is like WithTx but allows setting the transaction options.
+// The options are ignored for nested transactions.
+func WithConfiguredTx(ctx context.Context, db *DB, options pgx.TxOptions, fn TxFn) error {
+	log := ilog.FromContext(ctx)
+
+	var (
+		tx  pgx.Tx
+		err error
+	)
+	if db.tx != nil {
+		// pgx implements nested transactions with savepoints
+		tx, err = db.tx.Begin(ctx)
+	} else {
+		tx, err = db.Pool.BeginTx(ctx, options)
+	}
+	if err != nil {
+		log.Error("failed to begin transaction", ilog.Err(err))
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error("recovered from panic, rolling back transaction and panicking again")
+			if err = tx.Rollback(ctx); err != nil {
+				log.Error("failed to roll back transaction", ilog.Err(err))
+			}
+			panic(p)
+		}
+	}()
+
+	err = fn(&DB{
+		Pool:     db.Pool,
+		Queries:  db.Queries.WithTx(tx),
+		replicas: db.replicas,
+		tx:       tx,
+		retry:    db.retry,
+	})
+	if err != nil {
+		if txErr := tx.Rollback(ctx); txErr != nil {
+			log.Error("failed to roll back transaction", ilog.Err(txErr))
+		}
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// IsRetryableTxError reports whether err is a serialization failure or a
+// deadlock, after which the whole transaction can safely be retried.
+func IsRetryableTxError(err error) bool {
+	if pgErr := new(pgconn.PgError); errors.As(err, &pgErr) {
+		return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+	}
+
+	return false
+}
+
+type retryOptions struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func defaultRetryOptions() *retryOptions {
+	return &retryOptions{
+		maxAttempts: 5,
+		baseDelay:   10 * time.Millisecond,
+		maxDelay:    time.Second,
+	}
+}
+
+type RetryOption func(*retryOptions)
+
+// WithMaxAttempts sets how many times the transaction is attempted in total (5).
+func WithMaxAttempts(n int) RetryOption {
+	return func(o *retryOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithBaseDelay sets the backoff before the first retry (10ms). It doubles on every retry.
+func WithBaseDelay(d time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		o.baseDelay = d
+	}
+}
+
+// WithMaxDelay caps the backoff between two attempts (1s).
+func WithMaxDelay(d time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		o.maxDelay = d
+	}
+}
+
+// backoff returns a full jitter exponential backoff for the given retry.
+func (o *retryOptions) backoff(retry int) time.Duration {
+	d := o.baseDelay << retry
+	if d <= 0 || d > o.maxDelay {
+		d = o.maxDelay
+	}
+
+	return rand.N(d) + 1 //nolint:gosec
+}
+
+// WithRetryingTx runs fn inside a transaction like WithConfiguredTx, retrying the
+// whole transaction with exponential backoff and jitter when it fails with a
+// serialization failure or a deadlock. Retries stop once the attempt budget is
+// exhausted or the next backoff would exceed the context deadline.
+// If db is already bound to a transaction fn runs inside a savepoint and is not
+// retried, as only the outermost transaction can be.
+func WithRetryingTx(ctx context.Context, db *DB, options pgx.TxOptions, fn TxFn, opts ...RetryOption) error {
+	if db.tx != nil {
+		return WithConfiguredTx(ctx, db, options, fn)
+	}
+
+	o := defaultRetryOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	log := ilog.FromContext(ctx)
+	span := trace.SpanFromContext(ctx)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		span.AddEvent("db.transaction.attempt", trace.WithAttributes(
+			attribute.Int("db.transaction.attempt", attempt),
+		))
+
+		err = WithConfiguredTx(ctx, db, options, fn)
+		if err == nil || !IsRetryableTxError(err) {
+			return err
+		}
+		if attempt >= o.maxAttempts {
+			break
+		}
+
+		delay := o.backoff(attempt - 1)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			break
+		}
+
+		span.AddEvent("db.transaction.retry", trace.WithAttributes(
+			attribute.Int("db.transaction.attempt", attempt),
+			attribute.String("db.transaction.retry_delay", delay.String()),
+			attribute.String("error", err.Error()),
+		))
+		log.Warn("retrying transaction", "attempt", attempt, "delay", delay, ilog.Err(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	span.AddEvent("db.transaction.retries_exhausted")
+	return fmt.Errorf("transaction failed after retries: %w", err)
+}
@@ -0,0 +1,306 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+	"github.com/FotiadisM/service-template/pkg/testdb"
+)
+
+var (
+	serverOnce sync.Once
+	server     *testdb.Server
+	serverErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if server != nil {
+		_ = server.Close(context.Background())
+	}
+	os.Exit(code)
+}
+
+// newTestDB returns a DB connected to a new, migrated database.
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	serverOnce.Do(func() {
+		server, serverErr = testdb.New(context.Background(), testdb.WithMigrations(Migrations()))
+	})
+	testdb.SkipUnavailable(t, serverErr)
+	require.NoError(t, serverErr, "failed to create template database")
+
+	return NewFromPool(server.NewDatabase(t))
+}
+
+func createAuthor(ctx context.Context, q queries.Querier, id uuid.UUID) error {
+	_, err := q.CreateAuthor(ctx, queries.CreateAuthorParams{ID: id, Name: "Ursula K. Le Guin"})
+	return err
+}
+
+func authorExists(t *testing.T, db *DB, id uuid.UUID) bool {
+	t.Helper()
+
+	_, err := db.GetAuthor(context.Background(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false
+	}
+	require.NoError(t, err)
+
+	return true
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"SerializationFailure", &pgconn.PgError{Code: "40001"}, true},
+		{"DeadlockDetected", &pgconn.PgError{Code: "40P01"}, true},
+		{"Wrapped", fmt.Errorf("failed to create book: %w", &pgconn.PgError{Code: "40001"}), true},
+		{"Joined", errors.Join(errors.New("rollback"), &pgconn.PgError{Code: "40P01"}), true},
+		{"UniqueViolation", &pgconn.PgError{Code: "23505"}, false},
+		{"LockNotAvailable", &pgconn.PgError{Code: "55P03"}, false},
+		{"NotPgError", errors.New("40001"), false},
+		{"Nil", nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, IsRetryableTxError(tc.err))
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	t.Parallel()
+
+	o := defaultRetryOptions()
+	WithBaseDelay(10 * time.Millisecond)(o)
+	WithMaxDelay(100 * time.Millisecond)(o)
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{0, 10 * time.Millisecond},
+		{1, 20 * time.Millisecond},
+		{2, 40 * time.Millisecond},
+		{3, 80 * time.Millisecond},
+		{4, 100 * time.Millisecond},
+		// the shifted delay overflows
+		{70, 100 * time.Millisecond},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprint(tc.retry), func(t *testing.T) {
+			t.Parallel()
+
+			// full jitter picks any delay up to the exponential one
+			for range 1000 {
+				d := o.backoff(tc.retry)
+				assert.Positive(t, d)
+				assert.LessOrEqual(t, d, tc.max)
+			}
+		})
+	}
+}
+
+func TestWithRetryingTx(t *testing.T) {
+	t.Parallel()
+
+	retryable := &pgconn.PgError{Code: pgSerializationFailure, Message: "could not serialize access"}
+	fast := []RetryOption{WithBaseDelay(time.Millisecond), WithMaxDelay(time.Millisecond)}
+
+	t.Run("RetriesUntilSuccess", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		db := newTestDB(t)
+		id := uuid.New()
+
+		attempts := 0
+		err := WithRetryingTx(ctx, db, pgx.TxOptions{}, func(tx *DB) error {
+			attempts++
+			// failed attempts are rolled back, or the author would be duplicated
+			if err := createAuthor(ctx, tx, id); err != nil {
+				return err
+			}
+			if attempts < 3 {
+				return fmt.Errorf("failed to create book: %w", retryable)
+			}
+			return nil
+		}, fast...)
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.True(t, authorExists(t, db, id))
+	})
+
+	t.Run("OtherErrors", func(t *testing.T) {
+		t.Parallel()
+		db := newTestDB(t)
+		want := errors.New("author not found")
+
+		attempts := 0
+		err := WithRetryingTx(context.Background(), db, pgx.TxOptions{}, func(*DB) error {
+			attempts++
+			return want
+		}, fast...)
+		require.ErrorIs(t, err, want)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("MaxAttempts", func(t *testing.T) {
+		t.Parallel()
+		db := newTestDB(t)
+
+		attempts := 0
+		err := WithRetryingTx(context.Background(), db, pgx.TxOptions{}, func(*DB) error {
+			attempts++
+			return retryable
+		}, append(fast, WithMaxAttempts(4))...)
+		require.ErrorIs(t, err, retryable)
+		assert.ErrorContains(t, err, "transaction failed after retries")
+		assert.Equal(t, 4, attempts)
+	})
+
+	t.Run("ContextDeadline", func(t *testing.T) {
+		t.Parallel()
+		db := newTestDB(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// the backoff would outlive the deadline, so there is no retry
+		start := time.Now()
+		attempts := 0
+		err := WithRetryingTx(ctx, db, pgx.TxOptions{}, func(*DB) error {
+			attempts++
+			return retryable
+		}, WithBaseDelay(time.Hour), WithMaxDelay(time.Hour))
+		require.ErrorIs(t, err, retryable)
+		assert.Equal(t, 1, attempts)
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		t.Parallel()
+		db := newTestDB(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+		err := WithRetryingTx(ctx, db, pgx.TxOptions{}, func(*DB) error {
+			attempts++
+			cancel()
+			return retryable
+		}, WithBaseDelay(time.Minute), WithMaxDelay(time.Minute))
+		require.ErrorIs(t, err, retryable)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		db := newTestDB(t)
+
+		// only the outermost transaction can be retried
+		attempts := 0
+		err := WithTx(ctx, db, func(tx *DB) error {
+			return WithRetryingTx(ctx, tx, pgx.TxOptions{}, func(*DB) error {
+				attempts++
+				return retryable
+			}, fast...)
+		})
+		require.ErrorIs(t, err, retryable)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestWithTxNested(t *testing.T) {
+	t.Parallel()
+
+	t.Run("RollbackToSavepoint", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		db := newTestDB(t)
+		outer, inner := uuid.New(), uuid.New()
+		want := errors.New("inner failed")
+
+		err := WithTx(ctx, db, func(tx *DB) error {
+			if err := createAuthor(ctx, tx, outer); err != nil {
+				return err
+			}
+
+			err := WithTx(ctx, tx, func(tx *DB) error {
+				if err := createAuthor(ctx, tx, inner); err != nil {
+					return err
+				}
+				return want
+			})
+			require.ErrorIs(t, err, want)
+
+			// the outer transaction is still usable after the savepoint is rolled back
+			_, err = tx.GetAuthor(ctx, outer)
+			return err
+		})
+		require.NoError(t, err)
+		assert.True(t, authorExists(t, db, outer))
+		assert.False(t, authorExists(t, db, inner))
+	})
+
+	t.Run("OuterRollback", func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		db := newTestDB(t)
+		inner := uuid.New()
+		want := errors.New("outer failed")
+
+		err := WithTx(ctx, db, func(tx *DB) error {
+			err := WithTx(ctx, tx, func(tx *DB) error {
+				return createAuthor(ctx, tx, inner)
+			})
+			require.NoError(t, err)
+			return want
+		})
+		require.ErrorIs(t, err, want)
+		// releasing the savepoint does not commit it
+		assert.False(t, authorExists(t, db, inner))
+	})
+}
+
+func TestInTx(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := newTestDB(t).WithTxRetry(WithBaseDelay(time.Millisecond), WithMaxDelay(time.Millisecond))
+	id := uuid.New()
+
+	attempts := 0
+	err := db.InTx(ctx, func(q queries.Querier) error {
+		attempts++
+		if err := createAuthor(ctx, q, id); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return &pgconn.PgError{Code: pgDeadlockDetected}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.True(t, authorExists(t, db, id))
+}
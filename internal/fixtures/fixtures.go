@@ -0,0 +1,397 @@
+// Package fixtures loads declarative YAML or JSON fixture files into the
+// database through the generated Querier.
+//
+// Records are grouped by table and keyed by a symbolic name. A record can
+// refer to another one with "$name" instead of its id, or to a row that
+// already exists with a literal UUID:
+//
+//	authors:
+//	  author1:
+//	    name: Author1
+//	books:
+//	  book1:
+//	    title: Book1
+//	    author: $author1
+//
+// Records without an id get a new UUIDv7. Insert order is resolved from the
+// references, so files can list tables and records in any order.
+package fixtures
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+)
+
+//go:embed data/*.yaml
+var dataFS embed.FS
+
+var (
+	ErrUnknownRef     = errors.New("unknown fixture reference")
+	ErrCyclicRef      = errors.New("cyclic fixture reference")
+	ErrDuplicateName  = errors.New("duplicate fixture name")
+	ErrInvalidRefType = errors.New("fixture reference points to the wrong table")
+)
+
+type Author struct {
+	ID   uuid.UUID `yaml:"id"`
+	Name string    `yaml:"name"`
+	Bio  string    `yaml:"bio"`
+}
+
+type Book struct {
+	ID uuid.UUID `yaml:"id"`
+	// Author is a "$name" reference or the UUID of an existing author.
+	Author      string `yaml:"author"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+}
+
+type BookReview struct {
+	ID uuid.UUID `yaml:"id"`
+	// Book is a "$name" reference or the UUID of an existing book.
+	Book   string `yaml:"book"`
+	Rating int32  `yaml:"rating"`
+	Text   string `yaml:"text"`
+}
+
+// Set is a collection of fixture records keyed by name. Names are unique
+// across tables.
+type Set struct {
+	Authors     map[string]*Author     `yaml:"authors"`
+	Books       map[string]*Book       `yaml:"books"`
+	BookReviews map[string]*BookReview `yaml:"book_reviews"`
+}
+
+// Parse parses a YAML or JSON fixture document.
+func Parse(data []byte) (*Set, error) {
+	set := &Set{}
+	if err := yaml.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+
+	return set, nil
+}
+
+// ParseFS parses and merges the files of fsys matching the patterns, in
+// lexical order.
+func ParseFS(fsys fs.FS, patterns ...string) (*Set, error) {
+	names := []string{}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, matches...)
+	}
+	slices.Sort(names)
+
+	set := &Set{}
+	for _, name := range slices.Compact(names) {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		s, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		set.Merge(s)
+	}
+
+	return set, nil
+}
+
+// ParseFiles parses and merges the given files, in order.
+func ParseFiles(paths ...string) (*Set, error) {
+	set := &Set{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set.Merge(s)
+	}
+
+	return set, nil
+}
+
+// Default returns the fixtures shipped with the service.
+func Default() *Set {
+	set, err := ParseFS(dataFS, "data/*.yaml")
+	if err != nil {
+		panic(err)
+	}
+
+	return set
+}
+
+// Clone returns a deep copy of s, so that it can be modified per test.
+func (s *Set) Clone() *Set {
+	c := &Set{}
+	c.Merge(s)
+
+	return c
+}
+
+// Merge adds the records of other to s. Records with the same name replace
+// the ones already in s.
+func (s *Set) Merge(other *Set) {
+	s.Authors = mergeRecords(s.Authors, other.Authors)
+	s.Books = mergeRecords(s.Books, other.Books)
+	s.BookReviews = mergeRecords(s.BookReviews, other.BookReviews)
+}
+
+func mergeRecords[T any](dst, src map[string]*T) map[string]*T {
+	if dst == nil && len(src) > 0 {
+		dst = make(map[string]*T, len(src))
+	}
+	for name, r := range src {
+		v := *r
+		dst[name] = &v
+	}
+
+	return dst
+}
+
+// Loaded holds the inserted rows keyed by fixture name.
+type Loaded struct {
+	Authors     map[string]queries.Author
+	Books       map[string]queries.Book
+	BookReviews map[string]queries.BookReview
+}
+
+type record struct {
+	table  string
+	name   string
+	refs   []string
+	insert func(ctx context.Context, q queries.Querier, ids map[string]uuid.UUID) (uuid.UUID, error)
+}
+
+// Load inserts the records of the sets through q, merging them in order.
+// Callers that need atomicity should pass a Querier bound to a transaction.
+func Load(ctx context.Context, q queries.Querier, sets ...*Set) (*Loaded, error) {
+	set := &Set{}
+	for _, s := range sets {
+		set.Merge(s)
+	}
+
+	loaded := &Loaded{
+		Authors:     map[string]queries.Author{},
+		Books:       map[string]queries.Book{},
+		BookReviews: map[string]queries.BookReview{},
+	}
+
+	records, err := set.records(loaded)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := sortRecords(records)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uuid.UUID, len(order))
+	for _, r := range order {
+		id, err := r.insert(ctx, q, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert %s %q: %w", r.table, r.name, err)
+		}
+		ids[r.name] = id
+	}
+
+	return loaded, nil
+}
+
+func (s *Set) records(loaded *Loaded) (map[string]*record, error) {
+	records := map[string]*record{}
+	add := func(r *record) error {
+		if prev, ok := records[r.name]; ok {
+			return fmt.Errorf("%q in %s and %s: %w", r.name, prev.table, r.table, ErrDuplicateName)
+		}
+		records[r.name] = r
+		return nil
+	}
+
+	for name, a := range s.Authors {
+		err := add(&record{
+			table: "authors",
+			name:  name,
+			insert: func(ctx context.Context, q queries.Querier, _ map[string]uuid.UUID) (uuid.UUID, error) {
+				id, err := idOrNew(a.ID)
+				if err != nil {
+					return uuid.Nil, err
+				}
+				author, err := q.CreateAuthor(ctx, queries.CreateAuthorParams{ID: id, Name: a.Name, Bio: a.Bio})
+				loaded.Authors[name] = author
+				return id, err
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for name, b := range s.Books {
+		err := add(&record{
+			table: "books",
+			name:  name,
+			refs:  refs(b.Author),
+			insert: func(ctx context.Context, q queries.Querier, ids map[string]uuid.UUID) (uuid.UUID, error) {
+				id, err := idOrNew(b.ID)
+				if err != nil {
+					return uuid.Nil, err
+				}
+				authorID, err := resolve(b.Author, ids)
+				if err != nil {
+					return uuid.Nil, fmt.Errorf("author: %w", err)
+				}
+				book, err := q.CreateBook(ctx, queries.CreateBookParams{
+					ID:          id,
+					Title:       b.Title,
+					AuthorID:    authorID,
+					Description: b.Description,
+				})
+				loaded.Books[name] = book
+				return id, err
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for name, r := range s.BookReviews {
+		err := add(&record{
+			table: "book_reviews",
+			name:  name,
+			refs:  refs(r.Book),
+			insert: func(ctx context.Context, q queries.Querier, ids map[string]uuid.UUID) (uuid.UUID, error) {
+				id, err := idOrNew(r.ID)
+				if err != nil {
+					return uuid.Nil, err
+				}
+				bookID, err := resolve(r.Book, ids)
+				if err != nil {
+					return uuid.Nil, fmt.Errorf("book: %w", err)
+				}
+				review, err := q.CreateBookReview(ctx, queries.CreateBookReviewParams{
+					ID:     id,
+					BookID: bookID,
+					Rating: r.Rating,
+					Text:   r.Text,
+				})
+				loaded.BookReviews[name] = review
+				return id, err
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// references must point to a record of the table the foreign key targets
+	targets := map[string]string{"books": "authors", "book_reviews": "books"}
+	for _, r := range records {
+		for _, ref := range r.refs {
+			target, ok := records[ref]
+			if !ok {
+				return nil, fmt.Errorf("%s %q: $%s: %w", r.table, r.name, ref, ErrUnknownRef)
+			}
+			if target.table != targets[r.table] {
+				return nil, fmt.Errorf("%s %q: $%s is in %s: %w", r.table, r.name, ref, target.table, ErrInvalidRefType)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// sortRecords orders records so that every record comes after the ones it
+// references. Names are visited in lexical order to keep the result stable.
+func sortRecords(records map[string]*record) ([]*record, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int, len(records))
+	order := make([]*record, 0, len(records))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("$%s: %w", name, ErrCyclicRef)
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		r := records[name]
+		for _, ref := range r.refs {
+			if err := visit(ref); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, r)
+
+		return nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(records)) {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+func refs(values ...string) []string {
+	names := []string{}
+	for _, v := range values {
+		if name, ok := strings.CutPrefix(v, "$"); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func resolve(value string, ids map[string]uuid.UUID) (uuid.UUID, error) {
+	if name, ok := strings.CutPrefix(value, "$"); ok {
+		id, ok := ids[name]
+		if !ok {
+			return uuid.Nil, fmt.Errorf("$%s: %w", name, ErrUnknownRef)
+		}
+		return id, nil
+	}
+
+	return uuid.Parse(value)
+}
+
+func idOrNew(id uuid.UUID) (uuid.UUID, error) {
+	if id != uuid.Nil {
+		return id, nil
+	}
+
+	return uuid.NewV7()
+}
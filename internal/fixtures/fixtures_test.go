@@ -0,0 +1,350 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries/fake"
+)
+
+var (
+	authorID = uuid.MustParse("01950b20-756a-7056-bd6a-272db29cb3d1")
+	bookID   = uuid.MustParse("01950b20-756a-730a-8816-a7d8a675fc3e")
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	want := &Set{
+		Authors: map[string]*Author{
+			"author1": {ID: authorID, Name: "Author1", Bio: "Bio"},
+		},
+		Books: map[string]*Book{
+			"book1": {Author: "$author1", Title: "Book1"},
+		},
+		BookReviews: map[string]*BookReview{
+			"review1": {Book: bookID.String(), Rating: 5, Text: "Great"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    *Set
+		wantErr string
+	}{
+		{
+			name: "YAML",
+			data: `
+authors:
+  author1:
+    id: 01950b20-756a-7056-bd6a-272db29cb3d1
+    name: Author1
+    bio: Bio
+books:
+  book1:
+    title: Book1
+    author: $author1
+book_reviews:
+  review1:
+    book: 01950b20-756a-730a-8816-a7d8a675fc3e
+    rating: 5
+    text: Great
+`,
+			want: want,
+		},
+		{
+			name: "JSON",
+			data: `{
+  "authors": {"author1": {"id": "01950b20-756a-7056-bd6a-272db29cb3d1", "name": "Author1", "bio": "Bio"}},
+  "books": {"book1": {"title": "Book1", "author": "$author1"}},
+  "book_reviews": {"review1": {"book": "01950b20-756a-730a-8816-a7d8a675fc3e", "rating": 5, "text": "Great"}}
+}`,
+			want: want,
+		},
+		{
+			name: "Empty",
+			data: "",
+			want: &Set{},
+		},
+		{
+			name:    "InvalidID",
+			data:    "authors:\n  author1:\n    id: not-a-uuid\n",
+			wantErr: "failed to parse fixtures",
+		},
+		{
+			name:    "InvalidRating",
+			data:    `{"book_reviews": {"review1": {"rating": "five"}}}`,
+			wantErr: "failed to parse fixtures",
+		},
+		{
+			name:    "InvalidDocument",
+			data:    "authors: [",
+			wantErr: "failed to parse fixtures",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse([]byte(tc.data))
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"b.yaml":  {Data: []byte("authors:\n  author1:\n    name: Replaced\n")},
+		"a.yaml":  {Data: []byte("authors:\n  author1:\n    name: Author1\n  author2:\n    name: Author2\n")},
+		"c.json":  {Data: []byte(`{"books": {"book1": {"title": "Book1", "author": "$author1"}}}`)},
+		"bad.txt": {Data: []byte("[")},
+	}
+
+	// files are merged in lexical order, so b.yaml replaces author1
+	set, err := ParseFS(fsys, "*.yaml", "*.json", "a.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "Replaced", set.Authors["author1"].Name)
+	assert.Equal(t, "Author2", set.Authors["author2"].Name)
+	assert.Equal(t, "$author1", set.Books["book1"].Author)
+
+	_, err = ParseFS(fsys, "*.txt")
+	require.ErrorContains(t, err, "bad.txt: failed to parse fixtures")
+}
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+
+	set := Default()
+	clone := set.Clone()
+	require.Equal(t, set, clone)
+
+	clone.Authors["author1"].Name = "Changed"
+	assert.NotEqual(t, "Changed", set.Authors["author1"].Name)
+}
+
+func TestSortRecords(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		refs    map[string][]string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "NoReferences",
+			refs: map[string][]string{"c": nil, "a": nil, "b": nil},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "Chain",
+			refs: map[string][]string{"a": {"b"}, "b": {"c"}, "c": nil},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "Shared",
+			refs: map[string][]string{"review": {"book"}, "book": {"author"}, "author": nil, "other": {"author"}},
+			want: []string{"author", "book", "other", "review"},
+		},
+		{
+			name: "Diamond",
+			refs: map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}, "d": nil},
+			want: []string{"d", "b", "c", "a"},
+		},
+		{
+			name:    "Self",
+			refs:    map[string][]string{"a": {"a"}},
+			wantErr: ErrCyclicRef,
+		},
+		{
+			name:    "Cycle",
+			refs:    map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			wantErr: ErrCyclicRef,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			records := map[string]*record{}
+			for name, refs := range tc.refs {
+				records[name] = &record{name: name, refs: refs}
+			}
+
+			order, err := sortRecords(records)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			names := []string{}
+			for _, r := range order {
+				names = append(names, r.name)
+			}
+			assert.Equal(t, tc.want, names)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	q := fake.NewQuerier()
+	_, err := q.CreateAuthor(ctx, queries.CreateAuthorParams{ID: authorID, Name: "Existing"})
+	require.NoError(t, err)
+
+	// records are listed before the ones they reference
+	set, err := Parse([]byte(`
+book_reviews:
+  review1:
+    book: $book1
+    rating: 4
+books:
+  book1:
+    title: Book1
+    author: $author1
+  book2:
+    title: Book2
+    author: 01950b20-756a-7056-bd6a-272db29cb3d1
+authors:
+  author1:
+    name: Author1
+`))
+	require.NoError(t, err)
+
+	loaded, err := Load(ctx, q, set)
+	require.NoError(t, err)
+
+	author := loaded.Authors["author1"]
+	assert.Equal(t, "Author1", author.Name)
+	assert.Equal(t, uuid.Version(7), author.ID.Version(), "records without an id get a UUIDv7")
+	assert.Equal(t, author.ID, loaded.Books["book1"].AuthorID)
+	assert.Equal(t, authorID, loaded.Books["book2"].AuthorID)
+	assert.Equal(t, loaded.Books["book1"].ID, loaded.BookReviews["review1"].BookID)
+
+	got, err := q.GetBook(ctx, loaded.Books["book1"].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Book1", got.Title)
+}
+
+func TestLoadMergesSets(t *testing.T) {
+	t.Parallel()
+
+	base := &Set{Authors: map[string]*Author{"author1": {Name: "Author1"}}}
+	override := &Set{
+		Authors: map[string]*Author{"author1": {Name: "Replaced"}},
+		Books:   map[string]*Book{"book1": {Title: "Book1", Author: "$author1"}},
+	}
+
+	loaded, err := Load(context.Background(), fake.NewQuerier(), base, override)
+	require.NoError(t, err)
+	assert.Equal(t, "Replaced", loaded.Authors["author1"].Name)
+	assert.Equal(t, loaded.Authors["author1"].ID, loaded.Books["book1"].AuthorID)
+	// the sets are not modified
+	assert.Equal(t, "Author1", base.Authors["author1"].Name)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:    "UnknownReference",
+			data:    "books:\n  book1:\n    author: $missing\n",
+			wantErr: ErrUnknownRef,
+			wantMsg: `books "book1": $missing`,
+		},
+		{
+			name:    "UnknownReviewReference",
+			data:    "book_reviews:\n  review1:\n    book: $book1\n",
+			wantErr: ErrUnknownRef,
+			wantMsg: `book_reviews "review1": $book1`,
+		},
+		{
+			name:    "WrongTable",
+			data:    "authors:\n  author1: {}\nbook_reviews:\n  review1:\n    book: $author1\n",
+			wantErr: ErrInvalidRefType,
+			wantMsg: `book_reviews "review1": $author1 is in authors`,
+		},
+		{
+			name:    "DuplicateName",
+			data:    "authors:\n  shared: {}\nbooks:\n  shared:\n    author: $shared\n",
+			wantErr: ErrDuplicateName,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			set, err := Parse([]byte(tc.data))
+			require.NoError(t, err)
+
+			q := fake.NewQuerier()
+			_, err = Load(context.Background(), q, set)
+			require.ErrorIs(t, err, tc.wantErr)
+			assert.ErrorContains(t, err, tc.wantMsg)
+
+			// nothing is inserted when the references are invalid
+			authors, err := q.ListAuthors(context.Background())
+			require.NoError(t, err)
+			assert.Empty(t, authors)
+		})
+	}
+
+	t.Run("MissingRow", func(t *testing.T) {
+		t.Parallel()
+
+		// a UUID is not checked before the insert, it fails on the foreign key
+		set := &Set{Books: map[string]*Book{"book1": {Author: authorID.String()}}}
+		_, err := Load(context.Background(), fake.NewQuerier(), set)
+		require.ErrorContains(t, err, `failed to insert books "book1"`)
+		pgErr := new(pgconn.PgError)
+		require.True(t, errors.As(err, &pgErr))
+		assert.Equal(t, "books_author_id_fkey", pgErr.ConstraintName)
+	})
+
+	t.Run("InvalidUUID", func(t *testing.T) {
+		t.Parallel()
+
+		set := &Set{Books: map[string]*Book{"book1": {Author: "author1"}}}
+		_, err := Load(context.Background(), fake.NewQuerier(), set)
+		require.ErrorContains(t, err, `failed to insert books "book1": author: invalid UUID`)
+	})
+}
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	set := Default()
+	require.NotEmpty(t, set.Authors)
+	require.NotEmpty(t, set.Books)
+
+	loaded, err := Load(context.Background(), fake.NewQuerier(), set)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Authors, len(set.Authors))
+	assert.Len(t, loaded.Books, len(set.Books))
+	assert.Len(t, loaded.BookReviews, len(set.BookReviews))
+}
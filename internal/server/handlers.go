@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"os"
 
 	"connectrpc.com/grpcreflect"
 	"connectrpc.com/vanguard"
@@ -12,7 +11,7 @@ import (
 
 	"github.com/FotiadisM/service-template/api/gen/go/book/v1/bookv1connect"
 	"github.com/FotiadisM/service-template/internal/config"
-	"github.com/FotiadisM/service-template/pkg/ilog"
+	"github.com/FotiadisM/service-template/pkg/http/middleware/requestid"
 )
 
 func CorsHandlers(next http.Handler, config config.Cors) http.Handler {
@@ -35,12 +34,14 @@ func ReflectionHandler(mux *http.ServeMux, services ...string) {
 	mux.Handle(grpcreflect.NewHandlerV1Alpha(reflector))
 }
 
-func HTTPTranscoderHandler(mux *http.ServeMux, services map[string]http.Handler) error {
+func HTTPTranscoderHandler(mux *http.ServeMux, log *slog.Logger, services map[string]http.Handler) error {
 	vanguardServices := []*vanguard.Service{}
+	rpcPaths := []string{}
 	for path, handler := range services {
 		vanguardServices = append(vanguardServices,
 			vanguard.NewService(path, handler),
 		)
+		rpcPaths = append(rpcPaths, path)
 	}
 
 	var transcoder *vanguard.Transcoder
@@ -48,7 +49,7 @@ func HTTPTranscoderHandler(mux *http.ServeMux, services map[string]http.Handler)
 	if err != nil {
 		return fmt.Errorf("failed to create vanguard transcoder: %w", err)
 	}
-	mux.Handle("/", transcoder)
+	mux.Handle("/", HTTPTranscoderErrorWrapper(log, transcoder, rpcPaths...))
 
 	return nil
 }
@@ -58,16 +59,15 @@ func ChainHandlers(
 	config *config.Config,
 	log *slog.Logger,
 	services map[string]http.Handler,
-) http.Handler {
+) (http.Handler, error) {
 	if config.Server.DisableRESTTranscoding {
 		for path, handler := range services {
 			mux.Handle(path, handler)
 		}
 	} else {
-		err := HTTPTranscoderHandler(mux, services)
+		err := HTTPTranscoderHandler(mux, log, services)
 		if err != nil {
-			log.Error("failed to http transcoder", ilog.Err(err))
-			os.Exit(1)
+			return nil, err
 		}
 		log.Info("enabled http rest transcoding")
 	}
@@ -77,5 +77,5 @@ func ChainHandlers(
 		log.Info("enabled server reflection")
 	}
 
-	return CorsHandlers(mux, config.Cors)
+	return requestid.Handler(CorsHandlers(mux, config.Cors)), nil
 }
@@ -2,112 +2,254 @@ package server
 
 import (
 	"bytes"
-	"fmt"
+	"encoding/json"
 	"log/slog"
+	"maps"
+	"math"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
 	"connectrpc.com/connect"
+	"github.com/bufbuild/protovalidate-go"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
 )
 
-type WrappedResponseWriter struct {
-	Body       bytes.Buffer
-	StatusCode int
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Extensions are marshaled
+// as additional top-level members next to the standard ones.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(p.Extensions)+5)
+	maps.Copy(m, p.Extensions)
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+
+	return json.Marshal(m)
+}
+
+// problemResponseWriter passes successful responses through untouched and
+// only buffers error responses, so that streaming bodies are never held back.
+type problemResponseWriter struct {
+	http.ResponseWriter
+
+	statusCode  int
+	wroteHeader bool
+	intercept   bool
+	body        bytes.Buffer
+}
 
-	rw http.ResponseWriter
+func (w *problemResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = statusCode
+
+	if statusCode >= http.StatusBadRequest && isJSONContentType(w.Header().Get("Content-Type")) {
+		w.intercept = true
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
 }
 
-func (w *WrappedResponseWriter) Header() http.Header {
-	return w.rw.Header()
+func (w *problemResponseWriter) Write(buf []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.intercept {
+		return w.body.Write(buf)
+	}
+
+	return w.ResponseWriter.Write(buf)
 }
 
-func (w *WrappedResponseWriter) Write(buf []byte) (int, error) {
-	return w.Body.Write(buf)
+func (w *problemResponseWriter) Flush() {
+	if w.intercept {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
-func (w *WrappedResponseWriter) WriteHeader(statusCode int) {
-	w.StatusCode = statusCode
-	w.rw.WriteHeader(statusCode)
+func (w *problemResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
-func (w *WrappedResponseWriter) Flush() {}
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
 
-func HTTPTranscoderErrorWrapper(log *slog.Logger, next http.Handler) http.Handler {
-	errWriter := connect.NewErrorWriter()
+	return mediaType == "application/json"
+}
 
+// HTTPTranscoderErrorWrapper converts the google.rpc.Status error bodies written
+// by the REST transcoder into application/problem+json responses.
+// Requests whose path starts with one of rpcPaths are Connect, gRPC or gRPC-Web
+// calls and are passed through so that those clients keep their native error format.
+func HTTPTranscoderErrorWrapper(log *slog.Logger, next http.Handler, rpcPaths ...string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if errWriter.IsSupported(r) {
-			next.ServeHTTP(w, r)
+		for _, path := range rpcPaths {
+			if strings.HasPrefix(r.URL.Path, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
 		}
 
-		wrappedRW := &WrappedResponseWriter{rw: w}
+		// the transcoder rewrites r.URL to the RPC path, so keep the original URI
+		instance := r.URL.RequestURI()
+
+		wrappedRW := &problemResponseWriter{ResponseWriter: w}
 		next.ServeHTTP(wrappedRW, r)
 
-		if wrappedRW.StatusCode == http.StatusOK {
-			_, err := w.Write(wrappedRW.Body.Bytes())
-			if err != nil {
-				log.Error("failed to write to ResponseWriter", "error", err)
-			}
+		if !wrappedRW.intercept {
 			return
 		}
 
 		st := &status.Status{}
-		err := protojson.Unmarshal(wrappedRW.Body.Bytes(), st)
+		err := protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(wrappedRW.body.Bytes(), st)
 		if err != nil {
-			panic(err)
+			log.Warn("failed to unmarshal error response as google.rpc.Status", "error", err)
+			w.WriteHeader(wrappedRW.statusCode)
+			if _, err = w.Write(wrappedRW.body.Bytes()); err != nil {
+				log.Error("failed to write to ResponseWriter", "error", err)
+			}
+			return
 		}
 
-		if len(st.Details) == 0 {
+		problem := statusToProblem(log, st, wrappedRW.statusCode)
+		problem.Instance = instance
+
+		by, err := json.Marshal(problem)
+		if err != nil {
+			log.Error("failed to marshal problem details", "error", err)
+			w.WriteHeader(wrappedRW.statusCode)
 			return
 		}
 
-		m, err := st.Details[0].UnmarshalNew()
+		if retryAfter, ok := problem.Extensions["retry_after"].(int64); ok {
+			w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+		}
+		w.Header().Set("Content-Type", problemContentType)
+		w.Header().Del("Content-Length")
+		w.WriteHeader(wrappedRW.statusCode)
+		if _, err = w.Write(by); err != nil {
+			log.Error("failed to write to ResponseWriter", "error", err)
+		}
+	})
+}
+
+func statusToProblem(log *slog.Logger, st *status.Status, statusCode int) *Problem {
+	problem := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: st.Message,
+		Extensions: map[string]any{
+			"code": connect.Code(st.Code).String(), //nolint:gosec
+		},
+	}
+
+	for _, detail := range st.Details {
+		msg, err := detail.UnmarshalNew()
 		if err != nil {
-			log.Error("failed to UnmarshalNew *anypb.Any", "error", err)
-			return
+			log.Warn("failed to UnmarshalNew error detail", "type_url", detail.TypeUrl, "error", err)
+			continue
 		}
 
-		t, ok := m.(*bookv1.Error)
-		if ok {
-			res := &bookv1.ErrorResponse{
-				Error: t,
+		switch t := msg.(type) {
+		case *bookv1.Error:
+			if t.Code != "" {
+				problem.Type = "urn:book.v1:error:" + t.Code
+			}
+			if t.Name != "" {
+				problem.Title = t.Name
+			}
+			if t.Description != "" {
+				problem.Detail = t.Description
+			}
+			problem.Extensions["error"] = map[string]string{
+				"code":        t.Code,
+				"name":        t.Name,
+				"description": t.Description,
 			}
 
-			var by []byte
-			by, err = protojson.Marshal(res)
-			if err != nil {
-				log.Error("failed to Marshal ErrorResponse", "error", err)
+		case *errdetails.BadRequest:
+			violations := []map[string]string{}
+			for _, fv := range t.FieldViolations {
+				violations = append(violations, map[string]string{
+					"field":       fv.Field,
+					"description": fv.Description,
+				})
 			}
-			_, err = w.Write(by)
-			if err != nil {
-				log.Error("failed to write to ResponseWriter", "error", err)
+			problem.Extensions["violations"] = violations
+
+		case *validate.Violations:
+			violations := []map[string]string{}
+			for _, v := range t.Violations {
+				violations = append(violations, map[string]string{
+					"field":       protovalidate.FieldPathString(v.GetField()),
+					"description": v.GetMessage(),
+				})
 			}
+			problem.Extensions["violations"] = violations
 
-			return
-		}
+		case *errdetails.ErrorInfo:
+			if problem.Type == "about:blank" && t.Domain != "" && t.Reason != "" {
+				problem.Type = "urn:" + t.Domain + ":" + t.Reason
+			}
+			problem.Extensions["reason"] = t.Reason
+			problem.Extensions["domain"] = t.Domain
+			if len(t.Metadata) > 0 {
+				problem.Extensions["metadata"] = t.Metadata
+			}
 
-		log.Warn(fmt.Sprintf("unknown type '%s' passed as error details", st.Details[0].TypeUrl))
-		res := &bookv1.ErrorResponse{
-			Error: &bookv1.Error{
-				Code:        "unknown code",
-				Name:        "",
-				Description: "",
-			},
-		}
-		by, err := protojson.Marshal(res)
-		if err != nil {
-			log.Error("failed to Marshal ErrorResponse", "error", err)
-		}
-		_, err = w.Write(by)
-		if err != nil {
-			log.Error("failed to write to ResponseWriter", "error", err)
-		}
+		case *errdetails.RetryInfo:
+			if t.RetryDelay != nil {
+				problem.Extensions["retry_after"] = int64(math.Ceil(t.RetryDelay.AsDuration().Seconds()))
+			}
+
+		case *errdetails.PreconditionFailure:
+			violations := []map[string]string{}
+			for _, v := range t.Violations {
+				violations = append(violations, map[string]string{
+					"type":        v.Type,
+					"subject":     v.Subject,
+					"description": v.Description,
+				})
+			}
+			problem.Extensions["precondition_violations"] = violations
 
-		if len(st.Details) > 1 {
-			log.Warn("multiple error details are not supported")
+		default:
+			log.Debug("unsupported error detail type", "type_url", detail.TypeUrl)
 		}
-	})
+	}
+
+	return problem
 }
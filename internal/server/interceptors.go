@@ -10,7 +10,9 @@ import (
 	"connectrpc.com/connect"
 	"connectrpc.com/grpchealth"
 	"connectrpc.com/otelconnect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 
+	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
 	"github.com/FotiadisM/service-template/internal/config"
 	svcErrors "github.com/FotiadisM/service-template/internal/services/errors"
 	"github.com/FotiadisM/service-template/pkg/connect/interceptors/errsanitizer"
@@ -44,8 +46,24 @@ func OtelMiddleware() (connect.Interceptor, error) {
 	return m, nil
 }
 
-func LoggingMiddleware(log *slog.Logger) connect.Interceptor {
-	return logging.NewInterceptor(log)
+func LoggingMiddleware(config config.Logging, log *slog.Logger) connect.Interceptor {
+	payloads := func(_ context.Context, spec connect.Spec) bool {
+		service, _, _ := strings.Cut(strings.TrimLeft(spec.Procedure, "/"), "/")
+		for _, p := range config.Payloads {
+			if p == "*" || p == spec.Procedure || p == service {
+				return true
+			}
+		}
+		return false
+	}
+
+	return logging.NewInterceptor(log,
+		logging.WithDebugKey([]byte(config.DebugKey)),
+		logging.WithDebugSampledTraces(config.DebugSampledTraces),
+		logging.WithPayloads(payloads),
+		logging.WithPayloadLimit(config.PayloadLimit),
+		logging.WithSensitiveExtensions(bookv1.E_Sensitive),
+	)
 }
 
 func ValidationMiddleware() (connect.Interceptor, error) {
@@ -56,16 +74,31 @@ func ValidationMiddleware() (connect.Interceptor, error) {
 	return m, nil
 }
 
-func RecoveryMiddleware() connect.Interceptor {
-	return recovery.NewInterceptor()
+func RecoveryMiddleware(reporters ...recovery.PanicReporter) connect.Interceptor {
+	return recovery.NewInterceptor(recovery.WithPanicReporters(reporters...))
 }
 
-func ErrSanitizerMiddleware() connect.Interceptor {
+// ErrSanitizerMiddleware hides unexpected errors from clients. In dev the
+// errors of panics carry a DebugInfo detail with the stack.
+func ErrSanitizerMiddleware(dev bool) connect.Interceptor {
 	errSanitizerFunc := func(err error) error {
 		if tErr := new(connect.Error); errors.As(err, &tErr) {
 			return err
 		}
 
+		if pErr := new(recovery.PanicError); dev && errors.As(err, &pErr) {
+			cErr := connect.NewError(connect.CodeInternal, errUnexpected)
+			details, dErr := connect.NewErrorDetail(&errdetails.DebugInfo{
+				StackEntries: strings.Split(strings.TrimSpace(string(pErr.Stack)), "\n"),
+				Detail:       fmt.Sprintf("panic: %v", pErr.Panic),
+			})
+			if dErr == nil {
+				cErr.AddDetail(details)
+			}
+
+			return cErr
+		}
+
 		if tErr := new(svcErrors.ServiceError); errors.As(err, &tErr) {
 			cErr := connect.NewError(tErr.ConnectRPCCode, tErr)
 			details, _ := connect.NewErrorDetail(tErr.Err)
@@ -80,7 +113,7 @@ func ErrSanitizerMiddleware() connect.Interceptor {
 	return errsanitizer.NewInterceptor(errsanitizer.WithRecoveryFunc(errSanitizerFunc))
 }
 
-func ChainMiddleware(_ *config.Config, log *slog.Logger) []connect.Interceptor {
+func ChainMiddleware(config *config.Config, log *slog.Logger, reporters ...recovery.PanicReporter) []connect.Interceptor {
 	otelInterceptor, err := OtelMiddleware()
 	if err != nil {
 		panic(err)
@@ -92,12 +125,26 @@ func ChainMiddleware(_ *config.Config, log *slog.Logger) []connect.Interceptor {
 	}
 
 	interceptors := []connect.Interceptor{
-		ErrSanitizerMiddleware(),
+		ErrSanitizerMiddleware(config.Dev()),
 		otelInterceptor,
-		LoggingMiddleware(log),
-		RecoveryMiddleware(),
+		LoggingMiddleware(config.Logging, log),
+		RecoveryMiddleware(reporters...),
 		validationInterceptor,
 	}
 
 	return interceptors
 }
+
+// ChainClientMiddleware returns the interceptors of the outbound Connect
+// clients of the service, which trace and log every call.
+func ChainClientMiddleware(config *config.Config, log *slog.Logger) []connect.Interceptor {
+	otelInterceptor, err := OtelMiddleware()
+	if err != nil {
+		panic(err)
+	}
+
+	return []connect.Interceptor{
+		otelInterceptor,
+		LoggingMiddleware(config.Logging, log),
+	}
+}
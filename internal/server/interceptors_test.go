@@ -0,0 +1,131 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
+	"github.com/FotiadisM/service-template/internal/config"
+	"github.com/FotiadisM/service-template/internal/server"
+)
+
+const testProcedure = "/test.v1.TestService/Panic"
+
+func callPanic(t *testing.T, dev bool) *connect.Error {
+	t.Helper()
+
+	handler := connect.NewUnaryHandler(testProcedure,
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			panic("boom")
+		},
+		connect.WithInterceptors(server.ErrSanitizerMiddleware(dev), server.RecoveryMiddleware()),
+	)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](srv.Client(), srv.URL+testProcedure)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+
+	cErr := new(connect.Error)
+	require.ErrorAs(t, err, &cErr)
+
+	return cErr
+}
+
+func TestErrSanitizerMiddlewarePanic(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Production", func(t *testing.T) {
+		t.Parallel()
+
+		err := callPanic(t, false)
+		assert.Equal(t, connect.CodeInternal, err.Code())
+		assert.Equal(t, "unexpected error", err.Message())
+		assert.Empty(t, err.Details())
+	})
+
+	t.Run("Development", func(t *testing.T) {
+		t.Parallel()
+
+		err := callPanic(t, true)
+		assert.Equal(t, connect.CodeInternal, err.Code())
+		assert.Equal(t, "unexpected error", err.Message())
+		require.Len(t, err.Details(), 1)
+
+		detail, dErr := err.Details()[0].Value()
+		require.NoError(t, dErr)
+		info, ok := detail.(*errdetails.DebugInfo)
+		require.True(t, ok, "detail is a %T", detail)
+		assert.Equal(t, "panic: boom", info.GetDetail())
+		assert.NotEmpty(t, info.GetStackEntries())
+		assert.Contains(t, info.GetStackEntries()[0], "goroutine")
+	})
+}
+
+func TestErrSanitizerMiddlewareUnexpected(t *testing.T) {
+	t.Parallel()
+
+	handler := connect.NewUnaryHandler(testProcedure,
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			return nil, errors.New("connection refused")
+		},
+		connect.WithInterceptors(server.ErrSanitizerMiddleware(true)),
+	)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](srv.Client(), srv.URL+testProcedure)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+
+	// only panics carry debug information, other errors are hidden
+	cErr := new(connect.Error)
+	require.ErrorAs(t, err, &cErr)
+	assert.Equal(t, connect.CodeInternal, cErr.Code())
+	assert.Equal(t, "unexpected error", cErr.Message())
+	assert.Empty(t, cErr.Details())
+	assert.NotContains(t, cErr.Error(), "connection refused")
+}
+
+func TestLoggingMiddlewareRedactsSensitiveFields(t *testing.T) {
+	t.Parallel()
+
+	const procedure = "/book.v1.BookService/CreateAuthor"
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := connect.NewUnaryHandler(procedure,
+		func(context.Context, *connect.Request[bookv1.CreateAuthorRequest]) (*connect.Response[emptypb.Empty], error) {
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		},
+		connect.WithInterceptors(server.LoggingMiddleware(config.Logging{Payloads: []string{"*"}}, log)),
+	)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client := connect.NewClient[bookv1.CreateAuthorRequest, emptypb.Empty](srv.Client(), srv.URL+procedure)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&bookv1.CreateAuthorRequest{
+		Name: "Ursula K. Le Guin",
+		Bio:  "born in Berkeley",
+	}))
+	require.NoError(t, err)
+
+	var entry struct {
+		Payload string `json:"rpc.request.payload"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal([]byte(entry.Payload), &payload))
+	assert.Equal(t, map[string]any{"name": "Ursula K. Le Guin", "bio": "[REDACTED]"}, payload)
+}
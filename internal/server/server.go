@@ -3,14 +3,12 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/FotiadisM/service-template/internal/config"
-	"github.com/FotiadisM/service-template/pkg/ilog"
 )
 
 type Server struct {
@@ -18,6 +16,7 @@ type Server struct {
 
 	config *config.Config
 	server *http.Server
+	errc   chan error
 }
 
 func NewServer(config *config.Config, log *slog.Logger, mux http.Handler) (*Server, error) {
@@ -40,32 +39,45 @@ func NewServer(config *config.Config, log *slog.Logger, mux http.Handler) (*Serv
 		log:    log,
 		config: config,
 		server: httpServer,
+		errc:   make(chan error, 1),
 	}
 
 	return server, nil
 }
 
-func (s *Server) Start() {
-	s.log.Info("http server is listening", "addr", s.config.Server.Addr)
+// Start listens on the configured address and serves in the background. Errors
+// of the server after Start returns are reported by Err.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+
+	s.log.Info("http server is listening", "addr", ln.Addr().String())
 	go func() {
-		err := s.server.ListenAndServe()
+		err := s.server.Serve(ln)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			s.log.Error("http server exited", ilog.Err(err))
-			os.Exit(1)
+			s.errc <- fmt.Errorf("http server exited: %w", err)
 		}
+		close(s.errc)
 	}()
+
+	return nil
 }
 
-func (s *Server) AwaitShutdown(ctx context.Context) {
-	interruptSignal := make(chan os.Signal, 1)
-	signal.Notify(interruptSignal, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+// Err returns a channel that receives the error the server exits with, if any,
+// and is closed once it stops serving.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
 
-	<-interruptSignal
-	s.log.Info("shuting down")
-	timer, cancel := context.WithTimeout(ctx, s.config.Server.ShutdownTimeout)
-	defer cancel()
-	err := s.server.Shutdown(timer)
-	if err != nil {
-		s.log.Error("failed to shutdown http server", ilog.Err(err))
+// Shutdown stops accepting connections and waits for in flight requests to
+// finish, or for ctx to be done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.log.Info("shutting down http server")
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown http server: %w", err)
 	}
+
+	return nil
 }
@@ -0,0 +1,166 @@
+package bookv1
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/api/docs"
+	"github.com/FotiadisM/service-template/api/gen/go/book/v1/bookv1connect"
+	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/internal/fixtures"
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries/fake"
+	"github.com/FotiadisM/service-template/internal/test"
+	"github.com/FotiadisM/service-template/internal/test/contract"
+	"github.com/FotiadisM/service-template/pkg/idgen"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the contract tests")
+
+const (
+	contractAuthorID  = "01950b20-756a-7056-bd6a-272db29cb3d1" // author1 of the default fixtures
+	contractBookID    = "01950b20-756a-730a-8816-a7d8a675fc3e" // book1 of the default fixtures
+	contractMissingID = "01950b20-0000-7000-8000-000000000000"
+)
+
+type contractCase struct {
+	name   string
+	params map[string]string
+	body   string
+}
+
+// contractCases are the requests made for every operation of the swagger
+// document, keyed by operation ID. Each case runs against the default
+// fixtures.
+var contractCases = map[string][]contractCase{
+	"BookService_ListAuthors": {
+		{name: "ok"},
+	},
+	"BookService_CreateAuthor": {
+		{name: "ok", body: `{"name": "author", "bio": "bio"}`},
+		{name: "invalid", body: `{"name": ""}`},
+	},
+	"BookService_GetAuthor": {
+		{name: "ok", params: map[string]string{"id": contractAuthorID}},
+		{name: "not_found", params: map[string]string{"id": contractMissingID}},
+	},
+	"BookService_DeleteAuthor": {
+		{name: "ok", params: map[string]string{"id": contractAuthorID}},
+		{name: "not_found", params: map[string]string{"id": contractMissingID}},
+	},
+	"BookService_UpdateAuthor": {
+		{name: "ok", params: map[string]string{"id": contractAuthorID}, body: `{"bio": "new bio"}`},
+		{name: "not_found", params: map[string]string{"id": contractMissingID}, body: `{"bio": "new bio"}`},
+	},
+	"BookService_ListBooks": {
+		{name: "ok"},
+	},
+	"BookService_CreateBook": {
+		{name: "ok", body: `{"title": "book", "authorId": "` + contractAuthorID + `", "description": "description"}`},
+		{name: "author_not_found", body: `{"title": "book", "authorId": "` + contractMissingID + `"}`},
+	},
+	"BookService_CreateBookReview": {
+		{name: "ok", params: map[string]string{"bookId": contractBookID}, body: `{"rating": 4, "text": "review"}`},
+		{name: "invalid", params: map[string]string{"bookId": contractBookID}, body: `{"rating": 6, "text": "review"}`},
+	},
+	"BookService_GetBook": {
+		{name: "ok", params: map[string]string{"id": contractBookID}},
+		{name: "not_found", params: map[string]string{"id": contractMissingID}},
+	},
+	"BookService_DeleteBook": {
+		{name: "ok", params: map[string]string{"id": contractBookID}},
+	},
+	"BookService_UpdateBook": {
+		{name: "ok", params: map[string]string{"id": contractBookID}, body: `{"title": "new title"}`},
+	},
+}
+
+func TestRESTContract(t *testing.T) {
+	t.Parallel()
+
+	spec, err := contract.Load(docs.DocsFS, "book/v1/book.swagger.json")
+	require.NoError(t, err)
+
+	for _, op := range spec.Operations() {
+		cases, ok := contractCases[op.OperationID]
+		if !ok {
+			t.Errorf("%s %s (%s) has no contract cases", op.Method, op.Path, op.OperationID)
+			continue
+		}
+
+		for _, tc := range cases {
+			t.Run(op.OperationID+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				got := runContractCase(t, spec, op, tc)
+				golden := filepath.Join("testdata", "contract", op.OperationID+"_"+tc.name+".golden")
+				test.AssertGolden(t, golden, got, *update)
+			})
+		}
+	}
+}
+
+// runContractCase makes the request of tc against a service backed by the
+// in-memory Querier, validates the request and response bodies against the
+// spec, and returns the response in its golden file format.
+func runContractCase(t *testing.T, spec *contract.Spec, op *contract.Operation, tc contractCase) []byte {
+	t.Helper()
+	ctx := t.Context()
+
+	querier := fake.NewQuerier(fake.WithNow(func() time.Time { return testNow }))
+	_, err := fixtures.Load(ctx, querier, fixtures.Default())
+	require.NoError(t, err)
+
+	svc := newTestService(database.NewQuerierStore(querier))
+	svc.ids = idgen.NewSeeded(testIDSeed, svc.clock)
+
+	config := test.NewConfig()
+	svcPath, svcHandler := bookv1connect.NewBookServiceHandler(svc,
+		connect.WithInterceptors(test.ChainMiddleware(t, config)...),
+	)
+	server := test.NewServer(t, config, map[string]http.Handler{svcPath: svcHandler})
+	t.Cleanup(server.CleanUp)
+
+	url, err := op.URL(tc.params)
+	require.NoError(t, err)
+
+	var body io.Reader
+	if schema := op.BodySchema(); schema != nil {
+		require.NoError(t, spec.Validate(schema, []byte(tc.body)), "request body does not match the spec")
+		body = strings.NewReader(tc.body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, op.Method, server.URL+url, body)
+	require.NoError(t, err)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := server.Client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	contentType := res.Header.Get("Content-Type")
+	schema, err := op.ResponseSchema(res.StatusCode)
+	require.NoError(t, err)
+	require.NoError(t, spec.Validate(schema, resBody), "response body does not match the spec")
+
+	// protojson randomizes whitespace, so normalize it
+	indented := &bytes.Buffer{}
+	require.NoError(t, json.Indent(indented, resBody, "", "  "))
+
+	return fmt.Appendf(nil, "%s %s\nHTTP %d\nContent-Type: %s\n\n%s\n",
+		op.Method, url, res.StatusCode, contentType, indented.String())
+}
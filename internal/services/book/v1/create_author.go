@@ -6,15 +6,13 @@ import (
 
 	"connectrpc.com/connect"
 
-	"github.com/google/uuid"
-
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/encoder"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
 )
 
 func (s *Service) CreateAuthor(ctx context.Context, req *connect.Request[bookv1.CreateAuthorRequest]) (*connect.Response[bookv1.CreateAuthorResponse], error) {
-	id, err := uuid.NewV7()
+	id, err := s.ids.NewID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create uuid %w", err)
 	}
@@ -2,18 +2,18 @@ package bookv1
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
-	"time"
 
 	"connectrpc.com/connect"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
@@ -30,7 +30,7 @@ func (s *EndpointTestingSuite) TestCreateAuthor(t *testing.T) {
 	require.NoError(t, err)
 
 	require.NotEmpty(t, res.Msg.Author)
-	assert.NotEmpty(t, res.Msg.Author.Id)
+	assert.Equal(t, wantIDs(t, 1)[0].String(), res.Msg.Author.Id)
 	assert.Equal(t, authorReq.Name, res.Msg.Author.Name)
 	assert.Equal(t, authorReq.Bio, res.Msg.Author.Bio)
 	assert.NotEmpty(t, res.Msg.Author.CreatedAt)
@@ -49,17 +49,16 @@ func (s *EndpointTestingSuite) TestCreateAuthor(t *testing.T) {
 func (s *UnitTestingSuite) TestCreateAuthorHTTP(t *testing.T) {
 	ctx := t.Context()
 
-	s.DB.EXPECT().CreateAuthor(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, in queries.CreateAuthorParams) (queries.Author, error) {
-		now := time.Now()
-		author := queries.Author{
-			ID:        in.ID,
-			Name:      in.Name,
-			Bio:       in.Bio,
-			CreatedAt: now,
-			UpdatedAt: now,
-		}
-		return author, nil
-	}).Once()
+	id := wantIDs(t, 1)[0]
+	s.DB.EXPECT().CreateAuthor(mock.Anything, queries.CreateAuthorParams{
+		ID:   id,
+		Name: "author_name",
+	}).Return(queries.Author{
+		ID:        id,
+		Name:      "author_name",
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+	}, nil).Once()
 
 	reqBody := &bytes.Buffer{}
 	authorReq := &bookv1.CreateAuthorRequest{
@@ -80,9 +79,16 @@ func (s *UnitTestingSuite) TestCreateAuthorHTTP(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, res.StatusCode)
 	res_body := &bookv1.CreateAuthorResponse{}
-	err = json.NewDecoder(res.Body).Decode(res_body)
-	require.NoError(t, err)
-	require.NotEmpty(t, res_body.Author)
+	decodeProtoJSON(t, res, res_body)
+	want := &bookv1.CreateAuthorResponse{
+		Author: &bookv1.Author{
+			Id:        id.String(),
+			Name:      "author_name",
+			CreatedAt: timestamppb.New(testNow),
+			UpdatedAt: timestamppb.New(testNow),
+		},
+	}
+	assert.Truef(t, proto.Equal(want, res_body), "want %v, got %v", want, res_body)
 
 	s.DB.AssertExpectations(t)
 }
@@ -102,3 +108,44 @@ func (s *UnitTestingSuite) TestCreateAuthorValidation(t *testing.T) {
 
 	s.DB.AssertExpectations(t)
 }
+
+func (s *UnitTestingSuite) TestCreateAuthorValidationHTTP(t *testing.T) {
+	ctx := t.Context()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		fmt.Sprintf("%s/v1/authors", s.ServerURL),
+		bytes.NewBufferString(`{"name":""}`),
+	)
+	require.NoError(t, err)
+
+	res, err := s.HTTPClint.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+
+	problem := struct {
+		Type       string `json:"type"`
+		Status     int    `json:"status"`
+		Detail     string `json:"detail"`
+		Instance   string `json:"instance"`
+		Code       string `json:"code"`
+		Violations []struct {
+			Field       string `json:"field"`
+			Description string `json:"description"`
+		} `json:"violations"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(&problem)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "/v1/authors", problem.Instance)
+	assert.Equal(t, "invalid_argument", problem.Code)
+	assert.NotEmpty(t, problem.Detail)
+	require.Len(t, problem.Violations, 1)
+	assert.Equal(t, "name", problem.Violations[0].Field)
+
+	s.DB.AssertExpectations(t)
+}
@@ -2,6 +2,8 @@ package bookv1
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"connectrpc.com/connect"
@@ -13,7 +15,7 @@ import (
 )
 
 func (s *Service) CreateBook(ctx context.Context, req *connect.Request[bookv1.CreateBookRequest]) (*connect.Response[bookv1.CreateBookResponse], error) {
-	id, err := uuid.NewV7()
+	id, err := s.ids.NewID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create uuid %w", err)
 	}
@@ -28,9 +30,26 @@ func (s *Service) CreateBook(ctx context.Context, req *connect.Request[bookv1.Cr
 		Description: req.Msg.Description,
 		AuthorID:    authorID,
 	}
-	book, err := s.db.CreateBook(ctx, createParams)
+
+	var book queries.Book
+	err = s.db.InTx(ctx, func(q queries.Querier) error {
+		_, err = q.GetAuthor(ctx, authorID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("author not found"))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get author: %w", err)
+		}
+
+		book, err = q.CreateBook(ctx, createParams)
+		if err != nil {
+			return fmt.Errorf("failed to create book: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create author %w", err)
+		return nil, err
 	}
 
 	res := connect.NewResponse(&bookv1.CreateBookResponse{
@@ -2,10 +2,11 @@ package bookv1
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"connectrpc.com/connect"
-
 	"github.com/google/uuid"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
@@ -14,7 +15,7 @@ import (
 )
 
 func (s *Service) CreateBookReview(ctx context.Context, req *connect.Request[bookv1.CreateBookReviewRequest]) (*connect.Response[bookv1.CreateBookReviewResponse], error) {
-	id, err := uuid.NewV7()
+	id, err := s.ids.NewID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to created uuid: %w", err)
 	}
@@ -29,9 +30,26 @@ func (s *Service) CreateBookReview(ctx context.Context, req *connect.Request[boo
 		Rating: req.Msg.Rating,
 		Text:   req.Msg.Text,
 	}
-	review, err := s.db.CreateBookReview(ctx, createParams)
+
+	var review queries.BookReview
+	err = s.db.InTx(ctx, func(q queries.Querier) error {
+		_, err = q.GetBook(ctx, bookID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("book not found"))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get book: %w", err)
+		}
+
+		review, err = q.CreateBookReview(ctx, createParams)
+		if err != nil {
+			return fmt.Errorf("failed to create book review: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create book review: %w", err)
+		return nil, err
 	}
 
 	res := connect.NewResponse(&bookv1.CreateBookReviewResponse{
@@ -3,18 +3,20 @@ package bookv1
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"testing"
-	"time"
 
 	"connectrpc.com/connect"
-
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
@@ -24,7 +26,7 @@ func (s *EndpointTestingSuite) TestCreateBookReview(t *testing.T) {
 	ctx := t.Context()
 
 	req := &bookv1.CreateBookReviewRequest{
-		BookId: s.Fixtures.Book1.ID.String(),
+		BookId: s.Fixtures.Books["book1"].ID.String(),
 		Rating: 2,
 		Text:   "this is review",
 	}
@@ -32,8 +34,8 @@ func (s *EndpointTestingSuite) TestCreateBookReview(t *testing.T) {
 	require.NoError(t, err)
 
 	require.NotNil(t, res.Msg.Review)
-	assert.NotEmpty(t, res.Msg.Review.Id)
-	assert.Equal(t, s.Fixtures.Book1.ID.String(), res.Msg.Review.BookId)
+	assert.Equal(t, wantIDs(t, 1)[0].String(), res.Msg.Review.Id)
+	assert.Equal(t, s.Fixtures.Books["book1"].ID.String(), res.Msg.Review.BookId)
 	assert.Equal(t, req.Rating, res.Msg.Review.Rating)
 	assert.Equal(t, req.Text, res.Msg.Review.Text)
 }
@@ -41,22 +43,28 @@ func (s *EndpointTestingSuite) TestCreateBookReview(t *testing.T) {
 func (s *UnitTestingSuite) TestCreateBookReviewHTTP(t *testing.T) {
 	ctx := t.Context()
 
-	s.DB.EXPECT().CreateBookReview(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, in queries.CreateBookReviewParams) (queries.BookReview, error) {
-		now := time.Now()
-		book := queries.BookReview{
-			ID:        in.ID,
-			BookID:    in.BookID,
-			Rating:    in.Rating,
-			Text:      in.Text,
-			CreatedAt: now,
-			UpdatedAt: now,
-		}
-		return book, nil
-	})
+	s.DB.EXPECT().GetBook(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, id uuid.UUID) (queries.Book, error) {
+		return queries.Book{ID: id}, nil
+	}).Once()
+	id := wantIDs(t, 1)[0]
+	bookID := uuid.MustParse("0194fee7-3d16-7703-b28a-5b5c6ff6ecf4")
+	s.DB.EXPECT().CreateBookReview(mock.Anything, queries.CreateBookReviewParams{
+		ID:     id,
+		BookID: bookID,
+		Rating: 2,
+		Text:   "this is review",
+	}).Return(queries.BookReview{
+		ID:        id,
+		BookID:    bookID,
+		Rating:    2,
+		Text:      "this is review",
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+	}, nil).Once()
 
 	req_buf := &bytes.Buffer{}
 	req_body := &bookv1.CreateBookReviewRequest{
-		BookId: "0194fee7-3d16-7703-b28a-5b5c6ff6ecf4",
+		BookId: bookID.String(),
 		Rating: 2,
 		Text:   "this is review",
 	}
@@ -75,9 +83,39 @@ func (s *UnitTestingSuite) TestCreateBookReviewHTTP(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, res.StatusCode)
 	res_body := &bookv1.CreateBookReviewResponse{}
-	err = json.NewDecoder(res.Body).Decode(res_body)
-	require.NoError(t, err)
-	require.NotEmpty(t, res_body.Review)
+	decodeProtoJSON(t, res, res_body)
+	want := &bookv1.CreateBookReviewResponse{
+		Review: &bookv1.BookReview{
+			Id:        id.String(),
+			BookId:    bookID.String(),
+			Rating:    2,
+			Text:      "this is review",
+			CreatedAt: timestamppb.New(testNow),
+			UpdatedAt: timestamppb.New(testNow),
+		},
+	}
+	assert.Truef(t, proto.Equal(want, res_body), "want %v, got %v", want, res_body)
+
+	s.DB.AssertExpectations(t)
+}
+
+func (s *UnitTestingSuite) TestCreateBookReviewBookNotFound(t *testing.T) {
+	ctx := t.Context()
+
+	bookID := uuid.MustParse("0194fee7-3d16-7703-b28a-5b5c6ff6ecf4")
+	s.DB.EXPECT().GetBook(mock.Anything, bookID).Return(queries.Book{}, sql.ErrNoRows).Once()
+
+	req := connect.NewRequest(&bookv1.CreateBookReviewRequest{
+		BookId: bookID.String(),
+		Rating: 2,
+		Text:   "this is review",
+	})
+	res, err := s.Client.CreateBookReview(ctx, req)
+	cErr := &connect.Error{}
+	require.ErrorAs(t, err, &cErr)
+	require.Nil(t, res)
+
+	assert.Equal(t, connect.CodeNotFound, cErr.Code())
 
 	s.DB.AssertExpectations(t)
 }
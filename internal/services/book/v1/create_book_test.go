@@ -14,6 +14,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
@@ -24,14 +26,14 @@ func (s *EndpointTestingSuite) TestCreateBook(t *testing.T) {
 
 	bookReq := &bookv1.CreateBookRequest{
 		Title:       "book_title",
-		AuthorId:    s.Fixtures.Author1.ID.String(),
+		AuthorId:    s.Fixtures.Authors["author1"].ID.String(),
 		Description: "book_description",
 	}
 	res, err := s.Client.CreateBook(ctx, connect.NewRequest(bookReq))
 	require.NoError(t, err)
 
 	require.NotEmpty(t, res.Msg.Book)
-	assert.NotEmpty(t, res.Msg.Book.Id)
+	assert.Equal(t, wantIDs(t, 1)[0].String(), res.Msg.Book.Id)
 	assert.Equal(t, bookReq.Title, res.Msg.Book.Title)
 	assert.Equal(t, bookReq.AuthorId, res.Msg.Book.AuthorId)
 	assert.Equal(t, bookReq.Description, res.Msg.Book.Description)
@@ -52,20 +54,27 @@ func (s *EndpointTestingSuite) TestCreateBook(t *testing.T) {
 func (s *UnitTestingSuite) TestCreateBookHTTP(t *testing.T) {
 	ctx := t.Context()
 
-	s.DB.EXPECT().CreateBook(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, in queries.CreateBookParams) (queries.Book, error) {
-		book := queries.Book{
-			ID:          in.ID,
-			Title:       in.Title,
-			AuthorID:    in.AuthorID,
-			Description: in.Description,
-		}
-		return book, nil
-	})
+	s.DB.EXPECT().GetAuthor(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, id uuid.UUID) (queries.Author, error) {
+		return queries.Author{ID: id}, nil
+	}).Once()
+	id := wantIDs(t, 1)[0]
+	authorID := uuid.MustParse("0194fee7-3d16-7703-b28a-5b5c6ff6ecf4")
+	s.DB.EXPECT().CreateBook(mock.Anything, queries.CreateBookParams{
+		ID:       id,
+		Title:    "book_title",
+		AuthorID: authorID,
+	}).Return(queries.Book{
+		ID:        id,
+		Title:     "book_title",
+		AuthorID:  authorID,
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+	}, nil).Once()
 
 	req_buf := &bytes.Buffer{}
 	req_body := &bookv1.CreateBookRequest{
 		Title:    "book_title",
-		AuthorId: "0194fee7-3d16-7703-b28a-5b5c6ff6ecf4",
+		AuthorId: authorID.String(),
 	}
 	err := json.NewEncoder(req_buf).Encode(req_body)
 	require.NoError(t, err)
@@ -82,9 +91,17 @@ func (s *UnitTestingSuite) TestCreateBookHTTP(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, res.StatusCode)
 	res_body := &bookv1.CreateBookResponse{}
-	err = json.NewDecoder(res.Body).Decode(res_body)
-	require.NoError(t, err)
-	require.NotEmpty(t, res_body.Book)
+	decodeProtoJSON(t, res, res_body)
+	want := &bookv1.CreateBookResponse{
+		Book: &bookv1.Book{
+			Id:        id.String(),
+			Title:     "book_title",
+			AuthorId:  authorID.String(),
+			CreatedAt: timestamppb.New(testNow),
+			UpdatedAt: timestamppb.New(testNow),
+		},
+	}
+	assert.Truef(t, proto.Equal(want, res_body), "want %v, got %v", want, res_body)
 
 	s.DB.AssertExpectations(t)
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
 )
 
 func (s *Service) DeleteAuthor(ctx context.Context, req *connect.Request[bookv1.DeleteAuthorRequest]) (*connect.Response[bookv1.DeleteAuthorResponse], error) {
@@ -19,12 +20,23 @@ func (s *Service) DeleteAuthor(ctx context.Context, req *connect.Request[bookv1.
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("failed to parse author id: %w", err))
 	}
 
-	err = s.db.DeleteAuthor(ctx, id)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, connect.NewError(connect.CodeNotFound, nil)
-	}
+	err = s.db.InTx(ctx, func(q queries.Querier) error {
+		_, err = q.GetAuthor(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("author not found"))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get author: %w", err)
+		}
+
+		if err = q.DeleteAuthor(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete author: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete author: %w", err)
+		return nil, err
 	}
 
 	res := connect.NewResponse(&bookv1.DeleteAuthorResponse{})
@@ -15,11 +15,11 @@ func (s *EndpointTestingSuite) TestDeleteAuthor(t *testing.T) {
 	ctx := t.Context()
 
 	req := &bookv1.DeleteAuthorRequest{
-		Id: s.Fixtures.Author1.ID.String(),
+		Id: s.Fixtures.Authors["author1"].ID.String(),
 	}
 	_, err := s.Client.DeleteAuthor(ctx, connect.NewRequest(req))
 	require.NoError(t, err)
 
-	_, err = s.Service.db.GetAuthor(ctx, s.Fixtures.Author1.ID)
+	_, err = s.Service.db.GetAuthor(ctx, s.Fixtures.Authors["author1"].ID)
 	assert.ErrorIs(t, err, sql.ErrNoRows)
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
 )
 
 func (s *Service) DeleteBook(ctx context.Context, req *connect.Request[bookv1.DeleteBookRequest]) (*connect.Response[bookv1.DeleteBookResponse], error) {
@@ -18,12 +19,23 @@ func (s *Service) DeleteBook(ctx context.Context, req *connect.Request[bookv1.De
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("failed to parse book id: %w", err))
 	}
 
-	err = s.db.DeleteBook(ctx, id)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, connect.NewError(connect.CodeNotFound, nil)
-	}
+	err = s.db.InTx(ctx, func(q queries.Querier) error {
+		_, err = q.GetBook(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("book not found"))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get book: %w", err)
+		}
+
+		if err = q.DeleteBook(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete book: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete book: %w", err)
+		return nil, err
 	}
 
 	res := connect.NewResponse(&bookv1.DeleteBookResponse{})
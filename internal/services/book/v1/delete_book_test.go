@@ -16,11 +16,11 @@ func (s *EndpointTestingSuite) TestDeleteBook(t *testing.T) {
 	ctx := t.Context()
 
 	req := &bookv1.DeleteBookRequest{
-		Id: s.Fixtures.Book1.ID.String(),
+		Id: s.Fixtures.Books["book1"].ID.String(),
 	}
 	_, err := s.Client.DeleteBook(ctx, connect.NewRequest(req))
 	require.NoError(t, err)
 
-	_, err = s.Service.db.GetBook(ctx, s.Fixtures.Book1.ID)
+	_, err = s.Service.db.GetBook(ctx, s.Fixtures.Books["book1"].ID)
 	assert.ErrorIs(t, err, sql.ErrNoRows)
 }
@@ -0,0 +1,49 @@
+package bookv1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+)
+
+func (s *UnitTestingSuite) TestGetBookNotFoundHTTP(t *testing.T) {
+	ctx := t.Context()
+
+	id := uuid.MustParse("0194fee7-3d16-7703-b28a-5b5c6ff6ecf4")
+	s.DB.EXPECT().GetBook(mock.Anything, id).Return(queries.Book{}, sql.ErrNoRows).Once()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/v1/books/%s", s.ServerURL, id),
+		nil,
+	)
+	require.NoError(t, err)
+
+	res, err := s.HTTPClint.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+
+	problem := map[string]any{}
+	err = json.NewDecoder(res.Body).Decode(&problem)
+	require.NoError(t, err)
+	assert.Equal(t, "about:blank", problem["type"])
+	assert.Equal(t, http.StatusText(http.StatusNotFound), problem["title"])
+	assert.InDelta(t, http.StatusNotFound, problem["status"], 0)
+	assert.Equal(t, "/v1/books/"+id.String(), problem["instance"])
+	assert.Equal(t, "not_found", problem["code"])
+
+	s.DB.AssertExpectations(t)
+}
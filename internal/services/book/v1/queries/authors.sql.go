@@ -7,10 +7,10 @@ package queries
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createAuthor = `-- name: CreateAuthor :one
@@ -29,7 +29,7 @@ type CreateAuthorParams struct {
 }
 
 func (q *Queries) CreateAuthor(ctx context.Context, arg CreateAuthorParams) (Author, error) {
-	row := q.db.QueryRowContext(ctx, createAuthor, arg.ID, arg.Name, arg.Bio)
+	row := q.db.QueryRow(ctx, createAuthor, arg.ID, arg.Name, arg.Bio)
 	var i Author
 	err := row.Scan(
 		&i.ID,
@@ -47,7 +47,7 @@ WHERE id = $1
 `
 
 func (q *Queries) DeleteAuthor(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteAuthor, id)
+	_, err := q.db.Exec(ctx, deleteAuthor, id)
 	return err
 }
 
@@ -57,7 +57,7 @@ WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetAuthor(ctx context.Context, id uuid.UUID) (Author, error) {
-	row := q.db.QueryRowContext(ctx, getAuthor, id)
+	row := q.db.QueryRow(ctx, getAuthor, id)
 	var i Author
 	err := row.Scan(
 		&i.ID,
@@ -74,7 +74,7 @@ SELECT id, name, bio, created_at, updated_at FROM authors
 `
 
 func (q *Queries) ListAuthors(ctx context.Context) ([]Author, error) {
-	rows, err := q.db.QueryContext(ctx, listAuthors)
+	rows, err := q.db.Query(ctx, listAuthors)
 	if err != nil {
 		return nil, err
 	}
@@ -93,9 +93,6 @@ func (q *Queries) ListAuthors(ctx context.Context) ([]Author, error) {
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -114,13 +111,13 @@ RETURNING id, name, bio, created_at, updated_at
 
 type UpdateAuthorParams struct {
 	ID        uuid.UUID
-	Name      sql.NullString
-	Bio       sql.NullString
+	Name      pgtype.Text
+	Bio       pgtype.Text
 	UpdatedAt time.Time
 }
 
 func (q *Queries) UpdateAuthor(ctx context.Context, arg UpdateAuthorParams) (Author, error) {
-	row := q.db.QueryRowContext(ctx, updateAuthor,
+	row := q.db.QueryRow(ctx, updateAuthor,
 		arg.ID,
 		arg.Name,
 		arg.Bio,
@@ -28,7 +28,7 @@ type CreateBookReviewParams struct {
 }
 
 func (q *Queries) CreateBookReview(ctx context.Context, arg CreateBookReviewParams) (BookReview, error) {
-	row := q.db.QueryRowContext(ctx, createBookReview,
+	row := q.db.QueryRow(ctx, createBookReview,
 		arg.ID,
 		arg.BookID,
 		arg.Rating,
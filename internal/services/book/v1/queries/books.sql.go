@@ -7,10 +7,10 @@ package queries
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createBook = `-- name: CreateBook :one
@@ -30,7 +30,7 @@ type CreateBookParams struct {
 }
 
 func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (Book, error) {
-	row := q.db.QueryRowContext(ctx, createBook,
+	row := q.db.QueryRow(ctx, createBook,
 		arg.ID,
 		arg.Title,
 		arg.AuthorID,
@@ -54,7 +54,7 @@ WHERE id = $1
 `
 
 func (q *Queries) DeleteBook(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteBook, id)
+	_, err := q.db.Exec(ctx, deleteBook, id)
 	return err
 }
 
@@ -64,7 +64,7 @@ WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetBook(ctx context.Context, id uuid.UUID) (Book, error) {
-	row := q.db.QueryRowContext(ctx, getBook, id)
+	row := q.db.QueryRow(ctx, getBook, id)
 	var i Book
 	err := row.Scan(
 		&i.ID,
@@ -82,7 +82,7 @@ SELECT id, title, author_id, description, created_at, updated_at FROM books
 `
 
 func (q *Queries) ListBooks(ctx context.Context) ([]Book, error) {
-	rows, err := q.db.QueryContext(ctx, listBooks)
+	rows, err := q.db.Query(ctx, listBooks)
 	if err != nil {
 		return nil, err
 	}
@@ -102,9 +102,6 @@ func (q *Queries) ListBooks(ctx context.Context) ([]Book, error) {
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -123,13 +120,13 @@ RETURNING id, title, author_id, description, created_at, updated_at
 
 type UpdateBookParams struct {
 	ID          uuid.UUID
-	Title       sql.NullString
-	Description sql.NullString
+	Title       pgtype.Text
+	Description pgtype.Text
 	UpdatedAt   time.Time
 }
 
 func (q *Queries) UpdateBook(ctx context.Context, arg UpdateBookParams) (Book, error) {
-	row := q.db.QueryRowContext(ctx, updateBook,
+	row := q.db.QueryRow(ctx, updateBook,
 		arg.ID,
 		arg.Title,
 		arg.Description,
@@ -0,0 +1,253 @@
+package fake_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries/fake"
+	"github.com/FotiadisM/service-template/pkg/testdb"
+)
+
+// conformanceCases run against both the fake and PostgreSQL, each case with
+// an empty database.
+var conformanceCases = []struct {
+	name string
+	run  func(t *testing.T, q queries.Querier)
+}{
+	{"CreateAndGet", testCreateAndGet},
+	{"NotFound", testNotFound},
+	{"DuplicateID", testDuplicateID},
+	{"ForeignKey", testForeignKey},
+	{"UpdateCoalesce", testUpdateCoalesce},
+	{"DeleteCascade", testDeleteCascade},
+	{"ConcurrentCreate", testConcurrentCreate},
+}
+
+func TestFakeConformance(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range conformanceCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.run(t, fake.NewQuerier())
+		})
+	}
+}
+
+func TestPostgresConformance(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	server, err := testdb.New(ctx, testdb.WithMigrations(database.Migrations()))
+	testdb.SkipUnavailable(t, err)
+	require.NoError(t, err, "failed to create template database")
+	t.Cleanup(func() {
+		if err := server.Close(ctx); err != nil {
+			t.Logf("failed to close test database server: %v", err)
+		}
+	})
+
+	for _, tc := range conformanceCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.run(t, queries.New(server.NewDatabase(t)))
+		})
+	}
+}
+
+func newID(t *testing.T) uuid.UUID {
+	t.Helper()
+	id, err := uuid.NewV7()
+	require.NoError(t, err)
+	return id
+}
+
+func createAuthor(t *testing.T, q queries.Querier) queries.Author {
+	t.Helper()
+	author, err := q.CreateAuthor(context.Background(), queries.CreateAuthorParams{
+		ID:   newID(t),
+		Name: "name",
+		Bio:  "bio",
+	})
+	require.NoError(t, err)
+	return author
+}
+
+func createBook(t *testing.T, q queries.Querier, authorID uuid.UUID) queries.Book {
+	t.Helper()
+	book, err := q.CreateBook(context.Background(), queries.CreateBookParams{
+		ID:          newID(t),
+		Title:       "title",
+		AuthorID:    authorID,
+		Description: "description",
+	})
+	require.NoError(t, err)
+	return book
+}
+
+func assertPgError(t *testing.T, err error, code, constraint string) {
+	t.Helper()
+	pgErr := &pgconn.PgError{}
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, code, pgErr.Code)
+	assert.Equal(t, constraint, pgErr.ConstraintName)
+}
+
+func testCreateAndGet(t *testing.T, q queries.Querier) {
+	ctx := context.Background()
+
+	author := createAuthor(t, q)
+	assert.Equal(t, "name", author.Name)
+	assert.False(t, author.CreatedAt.IsZero())
+	assert.True(t, author.CreatedAt.Equal(author.UpdatedAt))
+
+	got, err := q.GetAuthor(ctx, author.ID)
+	require.NoError(t, err)
+	assert.Equal(t, author.ID, got.ID)
+	assert.True(t, author.CreatedAt.Equal(got.CreatedAt))
+
+	book := createBook(t, q, author.ID)
+	review, err := q.CreateBookReview(ctx, queries.CreateBookReviewParams{
+		ID:     newID(t),
+		BookID: book.ID,
+		Rating: 5,
+		Text:   "text",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, book.ID, review.BookID)
+	assert.Equal(t, int32(5), review.Rating)
+
+	books, err := q.ListBooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, books, 1)
+	assert.Equal(t, book.ID, books[0].ID)
+}
+
+func testNotFound(t *testing.T, q queries.Querier) {
+	ctx := context.Background()
+	id := newID(t)
+
+	_, err := q.GetAuthor(ctx, id)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = q.GetBook(ctx, id)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = q.UpdateAuthor(ctx, queries.UpdateAuthorParams{ID: id, UpdatedAt: time.Now()})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = q.UpdateBook(ctx, queries.UpdateBookParams{ID: id, UpdatedAt: time.Now()})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.NoError(t, q.DeleteAuthor(ctx, id))
+	assert.NoError(t, q.DeleteBook(ctx, id))
+
+	authors, err := q.ListAuthors(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, authors)
+	assert.Empty(t, authors)
+}
+
+func testDuplicateID(t *testing.T, q queries.Querier) {
+	author := createAuthor(t, q)
+
+	_, err := q.CreateAuthor(context.Background(), queries.CreateAuthorParams{ID: author.ID, Name: "other"})
+	assertPgError(t, err, "23505", "authors_pkey")
+}
+
+func testForeignKey(t *testing.T, q queries.Querier) {
+	ctx := context.Background()
+
+	_, err := q.CreateBook(ctx, queries.CreateBookParams{ID: newID(t), Title: "title", AuthorID: newID(t)})
+	assertPgError(t, err, "23503", "books_author_id_fkey")
+
+	_, err = q.CreateBookReview(ctx, queries.CreateBookReviewParams{ID: newID(t), BookID: newID(t), Rating: 1})
+	assertPgError(t, err, "23503", "book_reviews_book_id_fkey")
+}
+
+func testUpdateCoalesce(t *testing.T, q queries.Querier) {
+	ctx := context.Background()
+	author := createAuthor(t, q)
+	updatedAt := author.UpdatedAt.Add(time.Hour + time.Nanosecond)
+
+	updated, err := q.UpdateAuthor(ctx, queries.UpdateAuthorParams{
+		ID:        author.ID,
+		Name:      pgtype.Text{String: "new name", Valid: true},
+		UpdatedAt: updatedAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "new name", updated.Name)
+	assert.Equal(t, author.Bio, updated.Bio)
+	assert.True(t, author.CreatedAt.Equal(updated.CreatedAt))
+	assert.True(t, updatedAt.Truncate(time.Microsecond).Equal(updated.UpdatedAt))
+
+	book := createBook(t, q, author.ID)
+	updatedBook, err := q.UpdateBook(ctx, queries.UpdateBookParams{
+		ID:          book.ID,
+		Description: pgtype.Text{String: "", Valid: true},
+		UpdatedAt:   updatedAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, book.Title, updatedBook.Title)
+	assert.Empty(t, updatedBook.Description)
+}
+
+func testDeleteCascade(t *testing.T, q queries.Querier) {
+	ctx := context.Background()
+	author := createAuthor(t, q)
+	other := createAuthor(t, q)
+	book := createBook(t, q, author.ID)
+	otherBook := createBook(t, q, other.ID)
+	for _, b := range []queries.Book{book, otherBook} {
+		_, err := q.CreateBookReview(ctx, queries.CreateBookReviewParams{ID: newID(t), BookID: b.ID, Rating: 1})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, q.DeleteAuthor(ctx, author.ID))
+
+	_, err := q.GetAuthor(ctx, author.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = q.GetBook(ctx, book.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, q.DeleteBook(ctx, otherBook.ID))
+
+	books, err := q.ListBooks(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, books)
+
+	authors, err := q.ListAuthors(ctx)
+	require.NoError(t, err)
+	require.Len(t, authors, 1)
+	assert.Equal(t, other.ID, authors[0].ID)
+}
+
+func testConcurrentCreate(t *testing.T, q queries.Querier) {
+	const n = 20
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := q.CreateAuthor(context.Background(), queries.CreateAuthorParams{ID: uuid.Must(uuid.NewV7()), Name: "name"})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	authors, err := q.ListAuthors(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, authors, n)
+}
@@ -0,0 +1,311 @@
+// Package fake provides an in-memory queries.Querier for unit tests.
+//
+// It follows the semantics of the schema: primary keys are unique, foreign
+// keys are checked and deletes cascade, updates keep the current value of
+// NULL parameters, and missing rows are reported with pgx.ErrNoRows, which
+// matches sql.ErrNoRows. Constraint violations are returned as
+// *pgconn.PgError with the same code and constraint name as PostgreSQL.
+package fake
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+type options struct {
+	now func() time.Time
+}
+
+func defaultOptions() *options {
+	return &options{
+		now: time.Now,
+	}
+}
+
+type Option func(*options)
+
+// WithNow sets the function used for the created_at and updated_at defaults (time.Now).
+func WithNow(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// table keeps rows in insertion order, like a freshly written heap table.
+type table[T any] struct {
+	ids  []uuid.UUID
+	rows map[uuid.UUID]T
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{rows: map[uuid.UUID]T{}}
+}
+
+func (t *table[T]) insert(id uuid.UUID, row T) {
+	t.ids = append(t.ids, id)
+	t.rows[id] = row
+}
+
+func (t *table[T]) delete(id uuid.UUID) {
+	delete(t.rows, id)
+	t.ids = slices.DeleteFunc(t.ids, func(v uuid.UUID) bool { return v == id })
+}
+
+func (t *table[T]) list() []T {
+	rows := make([]T, 0, len(t.ids))
+	for _, id := range t.ids {
+		rows = append(rows, t.rows[id])
+	}
+
+	return rows
+}
+
+type Querier struct {
+	now func() time.Time
+
+	mu          sync.RWMutex
+	authors     *table[queries.Author]
+	books       *table[queries.Book]
+	bookReviews *table[queries.BookReview]
+}
+
+var _ queries.Querier = (*Querier)(nil)
+
+func NewQuerier(opts ...Option) *Querier {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Querier{
+		now:         o.now,
+		authors:     newTable[queries.Author](),
+		books:       newTable[queries.Book](),
+		bookReviews: newTable[queries.BookReview](),
+	}
+}
+
+// timestamp returns the current time with the microsecond precision of timestamptz.
+func (q *Querier) timestamp() time.Time {
+	return q.now().Truncate(time.Microsecond)
+}
+
+func uniqueViolation(table string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           pgUniqueViolation,
+		Message:        `duplicate key value violates unique constraint "` + table + `_pkey"`,
+		TableName:      table,
+		ConstraintName: table + "_pkey",
+	}
+}
+
+func foreignKeyViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           pgForeignKeyViolation,
+		Message:        `insert or update on table "` + table + `" violates foreign key constraint "` + constraint + `"`,
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func (q *Querier) CreateAuthor(_ context.Context, arg queries.CreateAuthorParams) (queries.Author, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.authors.rows[arg.ID]; ok {
+		return queries.Author{}, uniqueViolation("authors")
+	}
+
+	now := q.timestamp()
+	author := queries.Author{
+		ID:        arg.ID,
+		Name:      arg.Name,
+		Bio:       arg.Bio,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	q.authors.insert(author.ID, author)
+
+	return author, nil
+}
+
+func (q *Querier) CreateBook(_ context.Context, arg queries.CreateBookParams) (queries.Book, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.books.rows[arg.ID]; ok {
+		return queries.Book{}, uniqueViolation("books")
+	}
+	if _, ok := q.authors.rows[arg.AuthorID]; !ok {
+		return queries.Book{}, foreignKeyViolation("books", "books_author_id_fkey")
+	}
+
+	now := q.timestamp()
+	book := queries.Book{
+		ID:          arg.ID,
+		Title:       arg.Title,
+		AuthorID:    arg.AuthorID,
+		Description: arg.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	q.books.insert(book.ID, book)
+
+	return book, nil
+}
+
+func (q *Querier) CreateBookReview(_ context.Context, arg queries.CreateBookReviewParams) (queries.BookReview, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.bookReviews.rows[arg.ID]; ok {
+		return queries.BookReview{}, uniqueViolation("book_reviews")
+	}
+	if _, ok := q.books.rows[arg.BookID]; !ok {
+		return queries.BookReview{}, foreignKeyViolation("book_reviews", "book_reviews_book_id_fkey")
+	}
+
+	now := q.timestamp()
+	review := queries.BookReview{
+		ID:        arg.ID,
+		BookID:    arg.BookID,
+		Rating:    arg.Rating,
+		Text:      arg.Text,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	q.bookReviews.insert(review.ID, review)
+
+	return review, nil
+}
+
+// DeleteAuthor deletes the author and cascades to its books and their reviews.
+func (q *Querier) DeleteAuthor(_ context.Context, id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, book := range q.books.list() {
+		if book.AuthorID == id {
+			q.deleteBook(book.ID)
+		}
+	}
+	q.authors.delete(id)
+
+	return nil
+}
+
+// DeleteBook deletes the book and cascades to its reviews.
+func (q *Querier) DeleteBook(_ context.Context, id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.deleteBook(id)
+
+	return nil
+}
+
+func (q *Querier) deleteBook(id uuid.UUID) {
+	for _, review := range q.bookReviews.list() {
+		if review.BookID == id {
+			q.bookReviews.delete(review.ID)
+		}
+	}
+	q.books.delete(id)
+}
+
+func (q *Querier) GetAuthor(_ context.Context, id uuid.UUID) (queries.Author, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	author, ok := q.authors.rows[id]
+	if !ok {
+		return queries.Author{}, pgx.ErrNoRows
+	}
+
+	return author, nil
+}
+
+func (q *Querier) GetBook(_ context.Context, id uuid.UUID) (queries.Book, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	book, ok := q.books.rows[id]
+	if !ok {
+		return queries.Book{}, pgx.ErrNoRows
+	}
+
+	return book, nil
+}
+
+func (q *Querier) ListAuthors(_ context.Context) ([]queries.Author, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.authors.list(), nil
+}
+
+func (q *Querier) ListBooks(_ context.Context) ([]queries.Book, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.books.list(), nil
+}
+
+// UpdateAuthor keeps the current value of invalid (NULL) parameters, like coalesce.
+func (q *Querier) UpdateAuthor(_ context.Context, arg queries.UpdateAuthorParams) (queries.Author, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	author, ok := q.authors.rows[arg.ID]
+	if !ok {
+		return queries.Author{}, pgx.ErrNoRows
+	}
+
+	if arg.Name.Valid {
+		author.Name = arg.Name.String
+	}
+	if arg.Bio.Valid {
+		author.Bio = arg.Bio.String
+	}
+	author.UpdatedAt = arg.UpdatedAt.Truncate(time.Microsecond)
+	q.authors.rows[author.ID] = author
+
+	return author, nil
+}
+
+// UpdateBook keeps the current value of invalid (NULL) parameters, like coalesce.
+func (q *Querier) UpdateBook(_ context.Context, arg queries.UpdateBookParams) (queries.Book, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	book, ok := q.books.rows[arg.ID]
+	if !ok {
+		return queries.Book{}, pgx.ErrNoRows
+	}
+
+	if arg.Title.Valid {
+		book.Title = arg.Title.String
+	}
+	if arg.Description.Valid {
+		book.Description = arg.Description.String
+	}
+	book.UpdatedAt = arg.UpdatedAt.Truncate(time.Microsecond)
+	q.books.rows[book.ID] = book
+
+	return book, nil
+}
@@ -1,11 +1,54 @@
 package bookv1
 
-import "github.com/FotiadisM/service-template/internal/services/book/v1/queries"
+import (
+	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/pkg/clock"
+	"github.com/FotiadisM/service-template/pkg/idgen"
+)
+
+type options struct {
+	clock clock.Clock
+	ids   idgen.Generator
+}
+
+func defaultOptions() *options {
+	return &options{
+		clock: clock.Real(),
+		ids:   idgen.UUIDv7(),
+	}
+}
+
+type Option func(*options)
+
+// WithClock sets the clock used for timestamps set by the service (clock.Real).
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithIDGenerator sets the generator of new resource IDs (idgen.UUIDv7).
+func WithIDGenerator(g idgen.Generator) Option {
+	return func(o *options) {
+		o.ids = g
+	}
+}
 
 type Service struct {
-	db queries.Querier
+	db    database.Store
+	clock clock.Clock
+	ids   idgen.Generator
 }
 
-func NewService(db queries.Querier) *Service {
-	return &Service{db: db}
+func NewService(db database.Store, opts ...Option) *Service {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Service{
+		db:    db,
+		clock: o.clock,
+		ids:   o.ids,
+	}
 }
@@ -2,25 +2,66 @@ package bookv1
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
+	"io"
 	"net/http"
-	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/require"
-	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/FotiadisM/service-template/api/gen/go/book/v1/bookv1connect"
 	"github.com/FotiadisM/service-template/internal/database"
+	"github.com/FotiadisM/service-template/internal/fixtures"
+	"github.com/FotiadisM/service-template/internal/services/book/v1/queries"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/queries/mocks"
 	"github.com/FotiadisM/service-template/internal/test"
+	"github.com/FotiadisM/service-template/pkg/clock"
+	"github.com/FotiadisM/service-template/pkg/idgen"
 	"github.com/FotiadisM/service-template/pkg/suite"
+	"github.com/FotiadisM/service-template/pkg/testdb"
 )
 
+// testNow is the time of the clock used by the service in the testing suites.
+var testNow = time.Date(2025, time.February, 17, 12, 0, 0, 0, time.UTC)
+
+// testIDSeed seeds the ID generator of the service, which is reset before every test.
+const testIDSeed = 1
+
+// wantIDs returns the first n IDs that the service generates in a test.
+func wantIDs(t *testing.T, n int) []uuid.UUID {
+	t.Helper()
+
+	ids := idgen.NewSeeded(testIDSeed, clock.Fixed(testNow))
+	want := make([]uuid.UUID, 0, n)
+	for range n {
+		id, err := ids.NewID()
+		require.NoError(t, err)
+		want = append(want, id)
+	}
+
+	return want
+}
+
+// decodeProtoJSON decodes the JSON body of res into msg.
+func decodeProtoJSON(t *testing.T, res *http.Response, msg proto.Message) {
+	t.Helper()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, protojson.Unmarshal(body, msg))
+}
+
+func newTestService(db database.Store) *Service {
+	return NewService(db, WithClock(clock.Fixed(testNow)))
+}
+
 type unitTestingSuiteInternal struct {
 	server *test.Server
 }
@@ -40,7 +81,7 @@ func (s *UnitTestingSuite) SetupSuite(t *testing.T) {
 	t.Helper()
 
 	s.DB = mocks.NewMockQuerier(t)
-	s.Service = &Service{db: s.DB}
+	s.Service = newTestService(database.NewQuerierStore(s.DB))
 
 	config := test.NewConfig()
 	svcPath, svcHandler := bookv1connect.NewBookServiceHandler(
@@ -65,23 +106,27 @@ func (s *UnitTestingSuite) TearDownSuite(t *testing.T) {
 	s._internal.server.CleanUp()
 }
 
+func (s *UnitTestingSuite) SetupTest(t *testing.T) {
+	t.Helper()
+
+	s.Service.ids = idgen.NewSeeded(testIDSeed, s.Service.clock)
+}
+
 func TestUnitTestingSuite(t *testing.T) {
 	t.Parallel()
 	suite.Run(t, new(UnitTestingSuite))
 }
 
 type endpointTestingSuiteInternal struct {
-	postgresContainer *postgres.PostgresContainer
-	templateDBName    string
-	rootDB            *sql.DB
-	server            *test.Server
+	testDB *testdb.Server
+	server *test.Server
 }
 
 type EndpointTestingSuite struct {
 	_internal *endpointTestingSuiteInternal
 
 	DBs      sync.Map
-	Fixtures *test.Fixtures
+	Fixtures *fixtures.Loaded
 
 	Service *Service
 
@@ -95,41 +140,17 @@ func (s *EndpointTestingSuite) SetupSuite(t *testing.T) {
 
 	ctx := context.Background()
 
-	postgresContainer, err := postgres.Run(ctx, "postgres:15.1-alpine",
-		postgres.WithUsername("postgres"),
-		postgres.WithPassword("postgres"),
-		postgres.WithDatabase("test_db"),
-		postgres.WithSQLDriver("pgx"),
-		postgres.BasicWaitStrategies(),
+	testDB, err := testdb.New(ctx,
+		testdb.WithMigrations(database.Migrations()),
+		testdb.WithSetup(func(ctx context.Context, conn *pgx.Conn) (err error) {
+			s.Fixtures, err = fixtures.Load(ctx, queries.New(conn), fixtures.Default())
+			return err
+		}),
 	)
-	require.NoError(t, err, "failed to create postgres test container")
-
-	postgresConnURL, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
-	require.NoError(t, err, "failed to create postgres connection URL")
-
-	rootDB, err := sql.Open("pgx", postgresConnURL)
-	require.NoError(t, err, "failed to open DB connection")
-
-	rootDB.SetMaxOpenConns(1)
-	rootDB.SetMaxIdleConns(1)
-
-	templateDBName := "template_db"
-	_, err = rootDB.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", templateDBName))
-	require.NoError(t, err, "failed to drop template database")
-
-	_, err = rootDB.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s", templateDBName))
+	testdb.SkipUnavailable(t, err)
 	require.NoError(t, err, "failed to create template database")
 
-	templateDBConnURL := strings.ReplaceAll(postgresConnURL, "test_db", templateDBName)
-	test.ApplyMigrations(ctx, t, templateDBConnURL)
-	s.Service = &Service{db: nil}
-
-	templateDB, err := sql.Open("pgx", templateDBConnURL)
-	require.NoError(t, err, "failed to open template DB connection")
-	s.Fixtures = test.NewFixtures(t)
-	s.Fixtures.Load(ctx, t, templateDB)
-	err = templateDB.Close()
-	require.NoError(t, err, "failed to close template DB connection")
+	s.Service = newTestService(nil)
 
 	config := test.NewConfig()
 	svcPath, svcHandler := bookv1connect.NewBookServiceHandler(
@@ -144,10 +165,8 @@ func (s *EndpointTestingSuite) SetupSuite(t *testing.T) {
 	s.Client = bookv1connect.NewBookServiceClient(server.Client, server.URL)
 
 	s._internal = &endpointTestingSuiteInternal{
-		postgresContainer: postgresContainer,
-		templateDBName:    templateDBName,
-		rootDB:            rootDB,
-		server:            server,
+		testDB: testDB,
+		server: server,
 	}
 }
 
@@ -155,64 +174,35 @@ func (s *EndpointTestingSuite) TearDownSuite(t *testing.T) {
 	t.Helper()
 
 	s._internal.server.CleanUp()
+
+	err := s._internal.testDB.Close(context.Background())
+	if err != nil {
+		t.Logf("failed to close test database server: %v\n", err)
+	}
 }
 
 func (s *EndpointTestingSuite) SetupTest(t *testing.T) {
 	t.Helper()
-	ctx := context.Background()
-
-	testDBName := strings.ReplaceAll(s._internal.templateDBName+"_"+t.Name(), "/", "_")
-	testDBName = strings.ToLower(testDBName)
-
-	_, err := s._internal.rootDB.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", testDBName))
-	require.NoError(t, err, "failed to drop test database")
-
-	_, err = s._internal.rootDB.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", testDBName, s._internal.templateDBName))
-	require.NoError(t, err, "failed to create test database from template")
-
-	postgresConnURL, err := s._internal.postgresContainer.ConnectionString(ctx, "sslmode=disable")
-	require.NoError(t, err, "failed to create postgres connection URL")
-
-	conn, err := sql.Open("pgx", strings.ReplaceAll(postgresConnURL, "test_db", testDBName))
-	require.NoError(t, err, "failed to open test database")
 
-	conn.SetMaxOpenConns(1)
-	conn.SetMaxIdleConns(1)
-	s.DBs.Store(t.Name(), conn)
+	pool := s._internal.testDB.NewDatabase(t)
+	s.DBs.Store(t.Name(), pool)
+	t.Cleanup(func() { s.DBs.Delete(t.Name()) })
 
-	db, err := database.NewFromDBConn(conn)
-	require.NoError(t, err, "failed to create db.DB")
-
-	s.Service.db = db
+	s.Service.db = database.NewFromPool(pool)
+	s.Service.ids = idgen.NewSeeded(testIDSeed, s.Service.clock)
 }
 
-func (s *EndpointTestingSuite) TearDownTest(t *testing.T) {
+// LoadFixtures inserts additional fixtures into the database of the running
+// test. Records may reference the suite fixtures by name.
+func (s *EndpointTestingSuite) LoadFixtures(t *testing.T, sets ...*fixtures.Set) *fixtures.Loaded {
 	t.Helper()
 
 	v, ok := s.DBs.Load(t.Name())
-	if !ok {
-		t.Errorf("failed to load testing db for %s", t.Name())
-		return
-	}
-	db, ok := v.(*sql.DB)
-	if !ok {
-		t.Errorf("db type is not *sql.DB")
-		return
-	}
+	require.True(t, ok, "failed to load testing db for %s", t.Name())
+	pool, ok := v.(*pgxpool.Pool)
+	require.True(t, ok, "db type is not *pgxpool.Pool")
 
-	err := db.Close()
-	if err != nil {
-		t.Errorf("failed to close test db connection: %v\n", err)
-	}
-}
-
-func (s *EndpointTestingSuite) TearDownSuit(t *testing.T) {
-	t.Helper()
-
-	err := testcontainers.TerminateContainer(s._internal.postgresContainer)
-	if err != nil {
-		t.Logf("failed to terminate container: %v\n", err)
-	}
+	return test.LoadFixtures(context.Background(), t, pool, sets...)
 }
 
 func TestEndpointTestingSuite(t *testing.T) {
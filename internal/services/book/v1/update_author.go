@@ -5,11 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"time"
 
 	"connectrpc.com/connect"
-
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/encoder"
@@ -22,12 +21,12 @@ func (s *Service) UpdateAuthor(ctx context.Context, req *connect.Request[bookv1.
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("failed to parse book id: %w", err))
 	}
 
-	updateParams := queries.UpdateAuthorParams{ID: id, UpdatedAt: time.Now()}
+	updateParams := queries.UpdateAuthorParams{ID: id, UpdatedAt: s.clock.Now()}
 	if req.Msg.Name != nil {
-		updateParams.Name = sql.NullString{String: *req.Msg.Name, Valid: true}
+		updateParams.Name = pgtype.Text{String: *req.Msg.Name, Valid: true}
 	}
 	if req.Msg.Bio != nil {
-		updateParams.Bio = sql.NullString{String: *req.Msg.Bio, Valid: true}
+		updateParams.Bio = pgtype.Text{String: *req.Msg.Bio, Valid: true}
 	}
 
 	author, err := s.db.UpdateAuthor(ctx, updateParams)
@@ -5,11 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"time"
 
 	"connectrpc.com/connect"
-
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	bookv1 "github.com/FotiadisM/service-template/api/gen/go/book/v1"
 	"github.com/FotiadisM/service-template/internal/services/book/v1/encoder"
@@ -22,12 +21,12 @@ func (s *Service) UpdateBook(ctx context.Context, req *connect.Request[bookv1.Up
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("failed to parse book id: %w", err))
 	}
 
-	updateParams := queries.UpdateBookParams{ID: id, UpdatedAt: time.Now()}
+	updateParams := queries.UpdateBookParams{ID: id, UpdatedAt: s.clock.Now()}
 	if req.Msg.Title != nil {
-		updateParams.Title = sql.NullString{String: *req.Msg.Title, Valid: true}
+		updateParams.Title = pgtype.Text{String: *req.Msg.Title, Valid: true}
 	}
 	if req.Msg.Description != nil {
-		updateParams.Description = sql.NullString{String: *req.Msg.Description, Valid: true}
+		updateParams.Description = pgtype.Text{String: *req.Msg.Description, Valid: true}
 	}
 
 	book, err := s.db.UpdateBook(ctx, updateParams)
@@ -0,0 +1,128 @@
+// Package contract checks HTTP responses against a swagger 2.0 document, as
+// generated by protoc-gen-openapiv2.
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type Spec struct {
+	Paths       map[string]map[string]*Operation `json:"paths"`
+	Definitions map[string]*Schema               `json:"definitions"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	Responses   map[string]*Response `json:"responses"`
+
+	// Method and Path are filled in by Operations.
+	Method string `json:"-"`
+	Path   string `json:"-"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Type     string  `json:"type"`
+	Schema   *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string  `json:"description"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Required             []string           `json:"required"`
+	Enum                 []any              `json:"enum"`
+}
+
+// Load reads the swagger document at path in fsys.
+func Load(fsys fs.FS, path string) (*Spec, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{}
+	if err = json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return spec, nil
+}
+
+// Operations returns every operation of the spec, sorted by path and method.
+func (s *Spec) Operations() []*Operation {
+	ops := []*Operation{}
+	for path, methods := range s.Paths {
+		for method, op := range methods {
+			op.Method = strings.ToUpper(method)
+			op.Path = path
+			ops = append(ops, op)
+		}
+	}
+	slices.SortFunc(ops, func(a, b *Operation) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+
+	return ops
+}
+
+// BodySchema returns the schema of the request body, or nil if the operation
+// takes none.
+func (op *Operation) BodySchema() *Schema {
+	for _, p := range op.Parameters {
+		if p.In == "body" {
+			return p.Schema
+		}
+	}
+
+	return nil
+}
+
+// URL expands the path parameters of the operation.
+func (op *Operation) URL(params map[string]string) (string, error) {
+	url := op.Path
+	for _, p := range op.Parameters {
+		if p.In != "path" {
+			continue
+		}
+		v, ok := params[p.Name]
+		if !ok {
+			return "", fmt.Errorf("%s: missing path parameter %q", op.OperationID, p.Name)
+		}
+		url = strings.ReplaceAll(url, "{"+p.Name+"}", v)
+	}
+
+	return url, nil
+}
+
+// ResponseSchema returns the documented schema for a response status code.
+// Errors use the default response, unless their status code is documented.
+func (op *Operation) ResponseSchema(statusCode int) (*Schema, error) {
+	if r, ok := op.Responses[fmt.Sprint(statusCode)]; ok {
+		return r.Schema, nil
+	}
+	if r, ok := op.Responses["default"]; ok && statusCode != http.StatusOK {
+		return r.Schema, nil
+	}
+
+	return nil, fmt.Errorf("%s: status %d is not documented", op.OperationID, statusCode)
+}
@@ -0,0 +1,140 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Validate checks that data is a JSON document matching schema. Unlike plain
+// swagger, properties that are not documented are errors unless the schema
+// sets additionalProperties, so that undocumented fields show up.
+func (s *Spec) Validate(schema *Schema, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var errs []error
+	s.validate(schema, v, "$", &errs)
+
+	return errors.Join(errs...)
+}
+
+func (s *Spec) resolve(schema *Schema) (*Schema, error) {
+	for schema.Ref != "" {
+		name, ok := strings.CutPrefix(schema.Ref, "#/definitions/")
+		if !ok {
+			return nil, fmt.Errorf("unsupported $ref %q", schema.Ref)
+		}
+		def, ok := s.Definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown definition %q", name)
+		}
+		schema = def
+	}
+
+	return schema, nil
+}
+
+func (s *Spec) validate(schema *Schema, v any, path string, errs *[]error) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, fmt.Errorf("%s: "+format, append([]any{path}, args...)...))
+	}
+
+	schema, err := s.resolve(schema)
+	if err != nil {
+		fail("%v", err)
+		return
+	}
+
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(v) }) {
+		fail("%v is not one of %v", v, schema.Enum)
+	}
+
+	switch schema.Type {
+	case "object", "":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			if schema.Type != "" {
+				fail("expected object, got %T", v)
+			}
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		for name, value := range obj {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if len(schema.AdditionalProperties) == 0 {
+					fail("undocumented property %q", name)
+				}
+				continue
+			}
+			s.validate(prop, value, path+"."+name, errs)
+		}
+
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			fail("expected array, got %T", v)
+			return
+		}
+		for i, item := range arr {
+			if schema.Items != nil {
+				s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("expected string, got %T", v)
+			return
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				fail("invalid date-time %q", str)
+			}
+		}
+
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			fail("expected integer, got %T", v)
+			return
+		}
+		i, err := n.Int64()
+		if err != nil {
+			fail("expected integer, got %s", n)
+			return
+		}
+		if schema.Format == "int32" && (i < math.MinInt32 || i > math.MaxInt32) {
+			fail("%d overflows int32", i)
+		}
+
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			fail("expected number, got %T", v)
+		}
+
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("expected boolean, got %T", v)
+		}
+
+	default:
+		fail("unsupported schema type %q", schema.Type)
+	}
+}
@@ -0,0 +1,28 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AssertGolden compares got with the content of the golden file at path.
+// If update is set the golden file is written instead.
+func AssertGolden(t *testing.T, path string, got []byte, update bool) {
+	t.Helper()
+
+	if update {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		require.NoError(t, err, "failed to create golden file directory")
+		err = os.WriteFile(path, got, 0o600)
+		require.NoError(t, err, "failed to write golden file")
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read golden file, run the test with -update to create it")
+	assert.Equal(t, string(want), string(got), "response differs from %s, run the test with -update to accept it", path)
+}
@@ -11,14 +11,14 @@ import (
 	"github.com/FotiadisM/service-template/internal/server"
 )
 
-func ChainMiddleware(t *testing.T, _ *config.Config) []connect.Interceptor {
+func ChainMiddleware(t *testing.T, config *config.Config) []connect.Interceptor {
 	t.Helper()
 
 	validationInterceptor, err := server.ValidationMiddleware()
 	require.NoError(t, err, "failed to create validation interceptor")
 
 	return []connect.Interceptor{
-		server.LoggingMiddleware(slog.Default()),
+		server.LoggingMiddleware(config.Logging, slog.Default()),
 		validationInterceptor,
 	}
 }
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -27,7 +28,7 @@ func NewServer(t *testing.T, config *config.Config, services map[string]http.Han
 			mux.Handle(path, handler)
 		}
 	} else {
-		err := server.HTTPTranscoderHandler(mux, services)
+		err := server.HTTPTranscoderHandler(mux, slog.Default(), services)
 		require.NoError(t, err, "failed to create HTTP transcoder handler")
 	}
 
@@ -0,0 +1,68 @@
+// Package clock abstracts the current time so that code reading it can be
+// tested deterministically.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Real returns a Clock backed by time.Now.
+func Real() Clock {
+	return realClock{}
+}
+
+type fixedClock struct {
+	t time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.t
+}
+
+// Fixed returns a Clock that always returns t.
+func Fixed(t time.Time) Clock {
+	return fixedClock{t: t}
+}
+
+// Stepping is a Clock that starts at a given time and moves forward by a
+// fixed step on every call to Now. It is safe for concurrent use.
+type Stepping struct {
+	mu   sync.Mutex
+	next time.Time
+	step time.Duration
+}
+
+var _ Clock = (*Stepping)(nil)
+
+func NewStepping(start time.Time, step time.Duration) *Stepping {
+	return &Stepping{next: start, step: step}
+}
+
+func (c *Stepping) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.next
+	c.next = c.next.Add(c.step)
+
+	return now
+}
+
+// Set moves the clock to t.
+func (c *Stepping) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next = t
+}
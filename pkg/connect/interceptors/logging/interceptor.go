@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log/slog"
 	"net"
+	"net/http"
 	"slices"
 	"strings"
 	"time"
@@ -16,13 +17,20 @@ import (
 )
 
 const (
-	systemKey         = "rpc.system"
-	methodKey         = "rpc.method"
-	serviceKey        = "rpc.service"
-	peerNameKey       = "net.peer.name"
-	peerPortKey       = "net.peer.port"
-	serverDurationKey = "rpc.server.duration"
-	metadataPrefixKey = "rpc.connect_rpc.request.metadata."
+	systemKey            = "rpc.system"
+	methodKey            = "rpc.method"
+	serviceKey           = "rpc.service"
+	peerNameKey          = "net.peer.name"
+	peerPortKey          = "net.peer.port"
+	serverDurationKey    = "rpc.server.duration"
+	clientDurationKey    = "rpc.client.duration"
+	errorCodeKey         = "rpc.connect_rpc.error_code"
+	metadataPrefixKey    = "rpc.connect_rpc.request.metadata."
+	messagesSentKey      = "rpc.messages.sent"
+	messagesReceivedKey  = "rpc.messages.received"
+	bytesSentKey         = "rpc.bytes.sent"
+	bytesReceivedKey     = "rpc.bytes.received"
+	firstMessageDelayKey = "rpc.first_message.latency"
 
 	grpcProtocol    = "grpc"
 	grpcwebString   = "grpcweb"
@@ -80,8 +88,9 @@ func procedureAttributes(procedure string) []any {
 }
 
 type Interceptor struct {
-	opts   *options
-	logger *slog.Logger
+	opts     *options
+	logger   *slog.Logger
+	renderer *payloadRenderer
 }
 
 var _ connect.Interceptor = &Interceptor{}
@@ -91,20 +100,132 @@ func NewInterceptor(logger *slog.Logger, opts ...Option) *Interceptor {
 	for _, fn := range opts {
 		fn(options)
 	}
-	return &Interceptor{opts: options, logger: logger}
+	return &Interceptor{
+		opts:   options,
+		logger: logger,
+		renderer: &payloadRenderer{
+			limit:     options.payloadLimit,
+			sensitive: options.sensitiveExtensions,
+		},
+	}
+}
+
+// debugRequested reports whether the logs of a request should be elevated to
+// DEBUG, because it carries a valid debug token or its trace is sampled.
+func (i *Interceptor) debugRequested(span trace.SpanContext, header http.Header) bool {
+	if i.opts.debugSampledTraces && span.IsSampled() {
+		return true
+	}
+
+	token := header.Get(ilog.DebugHeader)
+	if token == "" || len(i.opts.debugKey) == 0 {
+		return false
+	}
+	if err := ilog.VerifyDebugToken(i.opts.debugKey, token, time.Now()); err != nil {
+		i.logger.Warn("ignoring debug log header", ilog.Err(err))
+		return false
+	}
+
+	return true
+}
+
+// endAttrs returns the level and the attributes of the log of a finished
+// call, common to clients and handlers, unary and streaming.
+func (i *Interceptor) endAttrs(peer connect.Peer, header http.Header, durationKey string, duration time.Duration, err error) (slog.Level, []slog.Attr) {
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		protocolAttribute(peer.Protocol),
+		slog.Int64(durationKey, duration.Milliseconds()),
+	}
+	if i.opts.withPeer {
+		attrs = append(attrs, addressAttributes(peer.Addr)...)
+	}
+	if err != nil {
+		if connectErr := new(connect.Error); errors.As(err, &connectErr) {
+			level = i.opts.codeToLevelFunc(connectErr.Code())
+			attrs = append(attrs,
+				ilog.Err(errors.New(connectErr.Message())), //nolint:err113
+				slog.String(errorCodeKey, connectErr.Code().String()),
+			)
+			attrs = append(attrs, i.opts.errorDetailsAttrFunc(connectErr.Details())...)
+		} else {
+			level = slog.LevelError
+			attrs = append(attrs,
+				ilog.Err(err),
+				slog.String(errorCodeKey, connect.CodeInternal.String()),
+			)
+		}
+	}
+
+	if i.opts.withRequestsHeaders {
+		for k, v := range header {
+			if slices.Index(i.opts.hiddenRequestHeaders, k) != -1 {
+				continue
+			}
+			attrs = append(attrs, slog.Any(metadataPrefixKey+strings.ToLower(k), v))
+		}
+	}
+
+	return level, attrs
+}
+
+// unaryAttrs returns the counts, the sizes and, if enabled, the payloads of
+// the messages of a unary call, with the same keys as streams. The response
+// is ignored if the call failed, since it may be a typed nil.
+func (i *Interceptor) unaryAttrs(ctx context.Context, req connect.AnyRequest, res connect.AnyResponse, err error) []slog.Attr {
+	if err != nil {
+		res = nil
+	}
+
+	requestCount, responseCount := messagesReceivedKey, messagesSentKey
+	requestKey, responseKey := bytesReceivedKey, bytesSentKey
+	if req.Spec().IsClient {
+		requestCount, responseCount = messagesSentKey, messagesReceivedKey
+		requestKey, responseKey = bytesSentKey, bytesReceivedKey
+	}
+
+	responses := 0
+	if res != nil {
+		responses = 1
+	}
+	attrs := []slog.Attr{
+		slog.Int(requestCount, 1),
+		slog.Int(responseCount, responses),
+		slog.Int(requestKey, messageSize(req.Any())),
+	}
+	if res != nil {
+		attrs = append(attrs, slog.Int(responseKey, messageSize(res.Any())))
+	}
+
+	if i.opts.payloadFilterFunc(ctx, req.Spec()) {
+		if a, ok := i.renderer.attr(requestPayloadKey, req.Any()); ok {
+			attrs = append(attrs, a)
+		}
+		if res != nil {
+			if a, ok := i.renderer.attr(responsePayloadKey, res.Any()); ok {
+				attrs = append(attrs, a)
+			}
+		}
+	}
+
+	return attrs
 }
 
 func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-		if req.Spec().IsClient || !i.opts.filterFunc(ctx, req.Spec()) {
+		if !i.opts.filterFunc(ctx, req.Spec()) {
 			return next(ctx, req)
 		}
+		if req.Spec().IsClient {
+			return i.unaryClient(ctx, req, next)
+		}
 
 		ctxLogger := i.logger.With(procedureAttributes(req.Spec().Procedure)...)
 
+		// trace.id and span.id are added from ctx by ilog.ContextHandler
 		span := trace.SpanContextFromContext(ctx)
-		if span.IsValid() {
-			ctxLogger = ctxLogger.With("trace.id", span.TraceID(), "span.id", span.SpanID())
+		if i.debugRequested(span, req.Header()) {
+			ctxLogger = ilog.WithMinLevel(ctxLogger, slog.LevelDebug)
 		}
 
 		ctx = ilog.ContextWithLogger(ctx, ctxLogger)
@@ -112,48 +233,76 @@ func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 		res, err := next(ctx, req)
 		duration := time.Since(start)
 
-		level := slog.LevelInfo
-		logAttrs := []slog.Attr{
-			protocolAttribute(req.Peer().Protocol),
-			slog.Int64(serverDurationKey, duration.Milliseconds()),
-		}
-		if i.opts.withPeer {
-			logAttrs = append(logAttrs, addressAttributes(req.Peer().Addr)...)
-		}
-		if err != nil {
-			if connectErr := new(connect.Error); errors.As(err, &connectErr) {
-				level = i.opts.codeToLevelFunc(connectErr.Code())
-				logAttrs = append(logAttrs,
-					ilog.Err(errors.New(connectErr.Message())), //nolint:err113
-					slog.String("rpc.connect_rpc.error_code", connectErr.Code().String()),
-				)
-				logAttrs = append(logAttrs, i.opts.errorDetailsAttrFunc(connectErr.Details())...)
-			} else {
-				level = slog.LevelError
-				logAttrs = append(logAttrs,
-					ilog.Err(err),
-					slog.String("rpc.connect_rpc.error_code", connect.CodeInternal.String()),
-				)
-			}
+		level, logAttrs := i.endAttrs(req.Peer(), req.Header(), serverDurationKey, duration, err)
+		// the sizes and payloads of the messages are only computed if logged
+		if ctxLogger.Enabled(ctx, level) {
+			logAttrs = append(logAttrs, i.unaryAttrs(ctx, req, res, err)...)
+			ctxLogger.LogAttrs(ctx, level, "request_end", logAttrs...)
 		}
 
-		if i.opts.withRequestsHeaders {
-			for k, v := range req.Header() {
-				if slices.Index(i.opts.hiddenRequestHeaders, k) != -1 {
-					continue
-				}
-				logAttrs = append(logAttrs, slog.Any(metadataPrefixKey+strings.ToLower(k), v))
-			}
-		}
+		return res, err
+	}
+}
 
-		ctxLogger.LogAttrs(ctx, level, "request_end", logAttrs...)
+func (i *Interceptor) unaryClient(ctx context.Context, req connect.AnyRequest, next connect.UnaryFunc) (connect.AnyResponse, error) {
+	start := time.Now()
+	res, err := next(ctx, req)
+	duration := time.Since(start)
 
-		return res, err
+	level, logAttrs := i.endAttrs(req.Peer(), req.Header(), clientDurationKey, duration, err)
+	if i.logger.Enabled(ctx, level) {
+		logAttrs = append(logAttrs, i.unaryAttrs(ctx, req, res, err)...)
+		i.logger.With(procedureAttributes(req.Spec().Procedure)...).LogAttrs(ctx, level, "call_end", logAttrs...)
+	}
+
+	return res, err
+}
+
+func (i *Interceptor) newStreamStats(ctx context.Context, spec connect.Spec, logger *slog.Logger) *streamStats {
+	// the bytes of the messages are only counted if the stream is logged when
+	// it succeeds, at INFO, or if its payloads are logged. Otherwise they are
+	// left out of the log of a failed stream.
+	stats := &streamStats{
+		start:  time.Now(),
+		client: spec.IsClient,
+		sizes:  logger.Enabled(ctx, slog.LevelInfo),
 	}
+	if i.opts.payloadFilterFunc(ctx, spec) {
+		stats.sizes = true
+		stats.payloads = &payloadLogger{
+			ctx:      ctx,
+			logger:   logger,
+			renderer: i.renderer,
+			sampler: streamSampler{
+				initial:    i.opts.streamPayloadInitial,
+				thereafter: i.opts.streamPayloadThereafter,
+			},
+		}
+	}
+
+	return stats
 }
 
 func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
-	return next
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		if !i.opts.filterFunc(ctx, spec) {
+			return conn
+		}
+
+		ctxLogger := i.logger.With(procedureAttributes(spec.Procedure)...)
+		c := &clientConn{
+			StreamingClientConn: conn,
+			stats:               i.newStreamStats(ctx, spec, ctxLogger),
+		}
+		c.onClose = func(err error) {
+			level, logAttrs := i.endAttrs(conn.Peer(), conn.RequestHeader(), clientDurationKey, time.Since(c.stats.start), err)
+			logAttrs = append(logAttrs, c.stats.attrs()...)
+			ctxLogger.LogAttrs(ctx, level, "call_end", logAttrs...)
+		}
+
+		return c
+	}
 }
 
 func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
@@ -164,43 +313,19 @@ func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) co
 
 		ctxLogger := i.logger.With(procedureAttributes(conn.Spec().Procedure)...)
 
+		// trace.id and span.id are added from ctx by ilog.ContextHandler
 		span := trace.SpanContextFromContext(ctx)
-		if span.IsValid() {
-			ctxLogger = ctxLogger.With("trace.id", span.TraceID(), "span.id", span.SpanID())
+		if i.debugRequested(span, conn.RequestHeader()) {
+			ctxLogger = ilog.WithMinLevel(ctxLogger, slog.LevelDebug)
 		}
 
 		ctx = ilog.ContextWithLogger(ctx, ctxLogger)
-		start := time.Now()
-		err := next(ctx, conn)
-		duration := time.Since(start)
-
-		level := slog.LevelInfo
-		logAttrs := []slog.Attr{
-			protocolAttribute(conn.Peer().Protocol),
-			slog.Int64(serverDurationKey, duration.Milliseconds()),
-		}
-		if i.opts.withPeer {
-			logAttrs = append(logAttrs, addressAttributes(conn.Peer().Addr)...)
-		}
-		if err != nil {
-			if connectErr := new(connect.Error); errors.As(err, &connectErr) {
-				level = i.opts.codeToLevelFunc(connectErr.Code())
-				logAttrs = append(logAttrs, ilog.Err(errors.New(connectErr.Message()))) //nolint:err113
-				logAttrs = append(logAttrs, i.opts.errorDetailsAttrFunc(connectErr.Details())...)
-			} else {
-				level = slog.LevelError
-				logAttrs = append(logAttrs, ilog.Err(err))
-			}
-		}
+		stats := i.newStreamStats(ctx, conn.Spec(), ctxLogger)
+		err := next(ctx, &handlerConn{StreamingHandlerConn: conn, stats: stats})
+		duration := time.Since(stats.start)
 
-		if i.opts.withRequestsHeaders {
-			for k, v := range conn.RequestHeader() {
-				if slices.Index(i.opts.hiddenRequestHeaders, k) != -1 {
-					continue
-				}
-				logAttrs = append(logAttrs, slog.Any(metadataPrefixKey+strings.ToLower(k), v))
-			}
-		}
+		level, logAttrs := i.endAttrs(conn.Peer(), conn.RequestHeader(), serverDurationKey, duration, err)
+		logAttrs = append(logAttrs, stats.attrs()...)
 
 		ctxLogger.LogAttrs(ctx, level, "request_end", logAttrs...)
 
@@ -0,0 +1,331 @@
+package logging_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/FotiadisM/service-template/pkg/connect/interceptors/logging"
+)
+
+const (
+	unaryProcedure  = "/test.v1.TestService/Echo"
+	streamProcedure = "/test.v1.TestService/Repeat"
+)
+
+// logBuffer collects the JSON logs of handlers and clients, which are written
+// from different goroutines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) entries(t *testing.T) []map[string]any {
+	t.Helper()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+
+	return entries
+}
+
+func newInterceptor(buf *logBuffer) *logging.Interceptor {
+	return logging.NewInterceptor(slog.New(slog.NewJSONHandler(buf, nil)))
+}
+
+func size(s string) float64 {
+	return float64(proto.Size(wrapperspb.String(s)))
+}
+
+// drain receives the messages of stream and returns their count.
+func drain(stream *connect.ServerStreamForClient[wrapperspb.StringValue]) int {
+	received := 0
+	for stream.Receive() {
+		received++
+	}
+
+	return received
+}
+
+// newServer serves an Echo unary procedure and a Repeat server streaming
+// procedure, that sends its request count times. A request of "fail" fails
+// with CodeAborted after the messages are sent.
+func newServer(t *testing.T, count int, opts ...connect.HandlerOption) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle(unaryProcedure, connect.NewUnaryHandler(unaryProcedure,
+		func(_ context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+			if req.Msg.GetValue() == "fail" {
+				return nil, connect.NewError(connect.CodeAborted, errors.New("conflict"))
+			}
+			return connect.NewResponse(wrapperspb.String(req.Msg.GetValue() + "!")), nil
+		},
+		opts...,
+	))
+	mux.Handle(streamProcedure, connect.NewServerStreamHandler(streamProcedure,
+		func(_ context.Context, req *connect.Request[wrapperspb.StringValue], stream *connect.ServerStream[wrapperspb.StringValue]) error {
+			for range count {
+				if err := stream.Send(req.Msg); err != nil {
+					return err
+				}
+			}
+			if req.Msg.GetValue() == "fail" {
+				return connect.NewError(connect.CodeAborted, errors.New("conflict"))
+			}
+			return nil
+		},
+		opts...,
+	))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestInterceptorUnaryHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf logBuffer
+	srv := newServer(t, 0, connect.WithInterceptors(newInterceptor(&buf)))
+
+	client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](srv.Client(), srv.URL+unaryProcedure)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+	require.NoError(t, err)
+	// handlers log before the server is closed
+	srv.Close()
+
+	entries := buf.entries(t)
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, "request_end", entry["msg"])
+	assert.Equal(t, "test.v1.TestService", entry["rpc.service"])
+	assert.Equal(t, "Echo", entry["rpc.method"])
+	assert.Equal(t, "connect_rpc", entry["rpc.system"])
+	assert.Contains(t, entry, "rpc.server.duration")
+	assert.InDelta(t, 1, entry["rpc.messages.received"], 0)
+	assert.InDelta(t, 1, entry["rpc.messages.sent"], 0)
+	assert.InDelta(t, size("hello"), entry["rpc.bytes.received"], 0)
+	assert.InDelta(t, size("hello!"), entry["rpc.bytes.sent"], 0)
+}
+
+func TestInterceptorUnaryClient(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer(t, 0)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		var buf logBuffer
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+			srv.Client(), srv.URL+unaryProcedure, connect.WithInterceptors(newInterceptor(&buf)),
+		)
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+		require.NoError(t, err)
+
+		entries := buf.entries(t)
+		require.Len(t, entries, 1)
+		entry := entries[0]
+		assert.Equal(t, "call_end", entry["msg"])
+		assert.Equal(t, "Echo", entry["rpc.method"])
+		assert.Contains(t, entry, "rpc.client.duration")
+		assert.NotContains(t, entry, "rpc.server.duration")
+		assert.InDelta(t, 1, entry["rpc.messages.sent"], 0)
+		assert.InDelta(t, 1, entry["rpc.messages.received"], 0)
+		assert.InDelta(t, size("hello"), entry["rpc.bytes.sent"], 0)
+		assert.InDelta(t, size("hello!"), entry["rpc.bytes.received"], 0)
+		assert.NotContains(t, entry, "rpc.connect_rpc.error_code")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+
+		var buf logBuffer
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+			srv.Client(), srv.URL+unaryProcedure, connect.WithInterceptors(newInterceptor(&buf)),
+		)
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("fail")))
+		require.Error(t, err)
+
+		entries := buf.entries(t)
+		require.Len(t, entries, 1)
+		entry := entries[0]
+		assert.Equal(t, "WARN", entry["level"])
+		assert.Equal(t, "aborted", entry["rpc.connect_rpc.error_code"])
+		assert.InDelta(t, 1, entry["rpc.messages.sent"], 0)
+		assert.InDelta(t, 0, entry["rpc.messages.received"], 0)
+		assert.NotContains(t, entry, "rpc.bytes.received")
+	})
+}
+
+func TestInterceptorStreamingClient(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer(t, 3)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		var buf logBuffer
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+			srv.Client(), srv.URL+streamProcedure, connect.WithInterceptors(newInterceptor(&buf)),
+		)
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, drain(stream))
+		require.NoError(t, stream.Err())
+		// the stream is logged once its response is closed
+		assert.Empty(t, buf.entries(t))
+
+		require.NoError(t, stream.Close())
+		// closing again does not log the stream twice
+		require.NoError(t, stream.Close())
+
+		entries := buf.entries(t)
+		require.Len(t, entries, 1)
+		entry := entries[0]
+		assert.Equal(t, "call_end", entry["msg"])
+		assert.Equal(t, "Repeat", entry["rpc.method"])
+		assert.Contains(t, entry, "rpc.client.duration")
+		assert.Contains(t, entry, "rpc.first_message.latency")
+		assert.InDelta(t, 1, entry["rpc.messages.sent"], 0)
+		assert.InDelta(t, 3, entry["rpc.messages.received"], 0)
+		assert.InDelta(t, size("hello"), entry["rpc.bytes.sent"], 0)
+		assert.InDelta(t, 3*size("hello"), entry["rpc.bytes.received"], 0)
+		assert.NotContains(t, entry, "rpc.connect_rpc.error_code")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+
+		var buf logBuffer
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+			srv.Client(), srv.URL+streamProcedure, connect.WithInterceptors(newInterceptor(&buf)),
+		)
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(wrapperspb.String("fail")))
+		require.NoError(t, err)
+		drain(stream)
+		require.Error(t, stream.Err())
+		require.NoError(t, stream.Close())
+
+		entries := buf.entries(t)
+		require.Len(t, entries, 1)
+		entry := entries[0]
+		assert.Equal(t, "aborted", entry["rpc.connect_rpc.error_code"])
+		assert.InDelta(t, 3, entry["rpc.messages.received"], 0)
+	})
+}
+
+func TestInterceptorStreamingHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		request  string
+		count    int
+		wantCode any
+	}{
+		{"Success", "hello", 3, nil},
+		{"Error", "fail", 2, "aborted"},
+		{"NoMessages", "hello", 0, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf logBuffer
+			srv := newServer(t, tc.count, connect.WithInterceptors(newInterceptor(&buf)))
+
+			client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](srv.Client(), srv.URL+streamProcedure)
+			stream, err := client.CallServerStream(context.Background(), connect.NewRequest(wrapperspb.String(tc.request)))
+			require.NoError(t, err)
+			drain(stream)
+			require.NoError(t, stream.Close())
+			srv.Close()
+
+			entries := buf.entries(t)
+			require.Len(t, entries, 1)
+			entry := entries[0]
+			assert.Equal(t, "request_end", entry["msg"])
+			assert.Contains(t, entry, "rpc.server.duration")
+			assert.InDelta(t, 1, entry["rpc.messages.received"], 0)
+			assert.InDelta(t, tc.count, entry["rpc.messages.sent"], 0)
+			assert.InDelta(t, size(tc.request), entry["rpc.bytes.received"], 0)
+			assert.InDelta(t, float64(tc.count)*size(tc.request), entry["rpc.bytes.sent"], 0)
+			assert.Equal(t, tc.wantCode, entry["rpc.connect_rpc.error_code"])
+			// the latency is the time until the first message is sent
+			if tc.count > 0 {
+				assert.Contains(t, entry, "rpc.first_message.latency")
+			} else {
+				assert.NotContains(t, entry, "rpc.first_message.latency")
+			}
+		})
+	}
+}
+
+// TestInterceptorSizesNotLogged checks that the sizes of messages, which are
+// only computed if they can be logged, are left out of failed streams when
+// calls that succeed are not logged.
+func TestInterceptorSizesNotLogged(t *testing.T) {
+	t.Parallel()
+
+	var buf logBuffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	srv := newServer(t, 2, connect.WithInterceptors(logging.NewInterceptor(log)))
+
+	unary := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](srv.Client(), srv.URL+unaryProcedure)
+	_, err := unary.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+	require.NoError(t, err)
+	_, err = unary.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("fail")))
+	require.Error(t, err)
+
+	client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](srv.Client(), srv.URL+streamProcedure)
+	for _, request := range []string{"hello", "fail"} {
+		stream, err := client.CallServerStream(context.Background(), connect.NewRequest(wrapperspb.String(request)))
+		require.NoError(t, err)
+		drain(stream)
+		require.NoError(t, stream.Close())
+	}
+	srv.Close()
+
+	entries := buf.entries(t)
+	require.Len(t, entries, 2)
+	// the size of the request of a failed unary call is known when it is logged
+	assert.Equal(t, "Echo", entries[0]["rpc.method"])
+	assert.InDelta(t, size("fail"), entries[0]["rpc.bytes.received"], 0)
+
+	assert.Equal(t, "Repeat", entries[1]["rpc.method"])
+	assert.InDelta(t, 2, entries[1]["rpc.messages.sent"], 0)
+	assert.NotContains(t, entries[1], "rpc.bytes.sent")
+	assert.NotContains(t, entries[1], "rpc.bytes.received")
+}
@@ -5,6 +5,9 @@ import (
 	"log/slog"
 
 	"connectrpc.com/connect"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
 )
 
 type FilterFunc func(ctx context.Context, spec connect.Spec) bool
@@ -21,6 +24,15 @@ type options struct {
 	withPeer             bool
 	withRequestsHeaders  bool
 	hiddenRequestHeaders []string
+
+	debugKey           []byte
+	debugSampledTraces bool
+
+	payloadFilterFunc       FilterFunc
+	payloadLimit            int
+	sensitiveExtensions     []protoreflect.ExtensionType
+	streamPayloadInitial    int
+	streamPayloadThereafter int
 }
 
 func defaultOptions() *options {
@@ -30,7 +42,12 @@ func defaultOptions() *options {
 		errorDetailsAttrFunc: DefaultErrorDetailsAttrFunc,
 		withPeer:             true,
 		withRequestsHeaders:  false,
-		hiddenRequestHeaders: []string{"Authorization"},
+		hiddenRequestHeaders: []string{"Authorization", ilog.DebugHeader},
+		payloadFilterFunc:    func(_ context.Context, _ connect.Spec) bool { return false },
+		payloadLimit:         4096,
+
+		streamPayloadInitial:    10,
+		streamPayloadThereafter: 100,
 	}
 }
 
@@ -71,3 +88,53 @@ func WithHiddenRequestHeaders(headers []string) Option {
 		o.hiddenRequestHeaders = headers
 	}
 }
+
+// WithDebugKey elevates the logs of requests carrying a valid ilog.DebugHeader
+// token signed with key to DEBUG.
+func WithDebugKey(key []byte) Option {
+	return func(o *options) {
+		o.debugKey = key
+	}
+}
+
+// WithDebugSampledTraces elevates the logs of requests whose trace is sampled
+// to DEBUG. It is meant to be used with a ratio based sampler.
+func WithDebugSampledTraces(enabled bool) Option {
+	return func(o *options) {
+		o.debugSampledTraces = enabled
+	}
+}
+
+// WithPayloads logs the request and response messages of the procedures
+// for which f returns true, rendered with protojson. Fields annotated with
+// debug_redact or with one of the WithSensitiveExtensions are redacted.
+func WithPayloads(f FilterFunc) Option {
+	return func(o *options) {
+		o.payloadFilterFunc = f
+	}
+}
+
+// WithPayloadLimit truncates logged messages to limit bytes (4096), zero
+// disables truncation.
+func WithPayloadLimit(limit int) Option {
+	return func(o *options) {
+		o.payloadLimit = limit
+	}
+}
+
+// WithSensitiveExtensions redacts the fields whose options set one of the
+// boolean extensions, like (book.v1.sensitive), to true.
+func WithSensitiveExtensions(extensions ...protoreflect.ExtensionType) Option {
+	return func(o *options) {
+		o.sensitiveExtensions = extensions
+	}
+}
+
+// WithStreamPayloadSampling logs the first initial messages sent and received
+// on a stream (10), then one in thereafter (100).
+func WithStreamPayloadSampling(initial, thereafter int) Option {
+	return func(o *options) {
+		o.streamPayloadInitial = initial
+		o.streamPayloadThereafter = thereafter
+	}
+}
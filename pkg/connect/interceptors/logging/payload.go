@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"log/slog"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	requestPayloadKey  = "rpc.request.payload"
+	responsePayloadKey = "rpc.response.payload"
+	payloadTruncated   = "…(truncated)"
+	payloadRedacted    = "[REDACTED]"
+)
+
+// payloadRenderer renders messages as JSON, redacting sensitive fields.
+type payloadRenderer struct {
+	limit     int
+	sensitive []protoreflect.ExtensionType
+}
+
+// isSensitive reports whether the values of fd must not be logged, because it
+// is annotated with debug_redact or with one of the sensitive extensions.
+func (p *payloadRenderer) isSensitive(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return false
+	}
+	if opts.GetDebugRedact() {
+		return true
+	}
+	for _, ext := range p.sensitive {
+		if !proto.HasExtension(opts, ext) {
+			continue
+		}
+		if sensitive, ok := proto.GetExtension(opts, ext).(bool); ok && sensitive {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redact masks the sensitive string and bytes fields of m and clears the
+// other sensitive fields, at any depth.
+func (p *payloadRenderer) redact(m protoreflect.Message) {
+	var sensitive []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case p.isSensitive(fd):
+			// fields are changed after ranging over m
+			sensitive = append(sensitive, fd)
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				return true
+			}
+			v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				p.redact(v.Message())
+				return true
+			})
+		case fd.IsList():
+			if fd.Message() == nil {
+				return true
+			}
+			list := v.List()
+			for i := range list.Len() {
+				p.redact(list.Get(i).Message())
+			}
+		case fd.Message() != nil:
+			p.redact(v.Message())
+		}
+		return true
+	})
+
+	for _, fd := range sensitive {
+		switch {
+		case fd.IsList() || fd.IsMap():
+			m.Clear(fd)
+		case fd.Kind() == protoreflect.StringKind:
+			m.Set(fd, protoreflect.ValueOfString(payloadRedacted))
+		case fd.Kind() == protoreflect.BytesKind:
+			m.Set(fd, protoreflect.ValueOfBytes([]byte(payloadRedacted)))
+		default:
+			m.Clear(fd)
+		}
+	}
+}
+
+// render returns msg as JSON, truncated to the size limit. Values that are
+// not proto messages are not rendered.
+func (p *payloadRenderer) render(msg any) (string, bool) {
+	pm, ok := msg.(proto.Message)
+	if !ok {
+		return "", false
+	}
+
+	pm = proto.Clone(pm)
+	p.redact(pm.ProtoReflect())
+
+	b, err := protojson.Marshal(pm)
+	if err != nil {
+		return "", false
+	}
+
+	return truncate(string(b), p.limit), true
+}
+
+// truncate cuts s to at most limit bytes, without splitting a character.
+func truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return s[:cut] + payloadTruncated
+}
+
+func (p *payloadRenderer) attr(key string, msg any) (slog.Attr, bool) {
+	s, ok := p.render(msg)
+	if !ok {
+		return slog.Attr{}, false
+	}
+
+	return slog.String(key, s), true
+}
+
+// messageSize returns the size of msg in the protobuf wire format, before
+// compression. Values that are not proto messages have a size of zero.
+func messageSize(msg any) int {
+	pm, ok := msg.(proto.Message)
+	if !ok {
+		return 0
+	}
+
+	return proto.Size(pm)
+}
@@ -0,0 +1,353 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// payloadTypes builds the descriptors of the messages used to test redaction,
+// with a sensitive extension like (book.v1.sensitive).
+func payloadTypes(t *testing.T) (protoreflect.MessageDescriptor, protoreflect.ExtensionType) {
+	t.Helper()
+
+	files := &protoregistry.Files{}
+	require.NoError(t, files.RegisterFile(descriptorpb.File_google_protobuf_descriptor_proto))
+
+	extFile, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/options.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{{
+			Name:     proto.String("sensitive"),
+			Number:   proto.Int32(50000),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum(),
+			Extendee: proto.String(".google.protobuf.FieldOptions"),
+			JsonName: proto.String("sensitive"),
+		}},
+	}, files)
+	require.NoError(t, err)
+	require.NoError(t, files.RegisterFile(extFile))
+	sensitive := dynamicpb.NewExtensionType(extFile.Extensions().Get(0))
+
+	redacted := &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+	annotated := func(v bool) *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{}
+		proto.SetExtension(opts, sensitive, v)
+		return opts
+	}
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+			Options:  opts,
+		}
+	}
+	message := func(name string, number int32, typeName string, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		f := field(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, opts)
+		f.TypeName = proto.String(typeName)
+		return f
+	}
+	repeated := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return f
+	}
+	oneof := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.OneofIndex = proto.Int32(0)
+		return f
+	}
+	mapEntry := func(name string, value *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+				value,
+			},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+	}
+
+	const (
+		typeString = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		typeBytes  = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+		typeInt64  = descriptorpb.FieldDescriptorProto_TYPE_INT64
+		typeBool   = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	)
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/payload.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"test/v1/options.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Inner"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("secret", 1, typeString, redacted),
+					field("visible", 2, typeString, nil),
+				},
+			},
+			{
+				Name: proto.String("Payload"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, typeString, nil),
+					field("password", 2, typeString, redacted),
+					field("token", 3, typeBytes, annotated(true)),
+					field("pin", 4, typeInt64, redacted),
+					field("flag", 5, typeBool, annotated(false)),
+					message("hidden", 6, ".test.v1.Inner", redacted),
+					message("inner", 7, ".test.v1.Inner", nil),
+					repeated(message("items", 8, ".test.v1.Inner", nil)),
+					repeated(field("tags", 9, typeString, redacted)),
+					repeated(message("by_key", 10, ".test.v1.Payload.ByKeyEntry", nil)),
+					repeated(message("labels", 11, ".test.v1.Payload.LabelsEntry", annotated(true))),
+					oneof(field("card", 12, typeString, annotated(true))),
+					oneof(field("code", 13, typeInt64, nil)),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					mapEntry("ByKeyEntry", message("value", 2, ".test.v1.Inner", nil)),
+					mapEntry("LabelsEntry", field("value", 2, typeString, nil)),
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("choice")}},
+			},
+		},
+	}, files)
+	require.NoError(t, err)
+
+	return file.Messages().ByName("Payload"), sensitive
+}
+
+func TestPayloadRendererRedact(t *testing.T) {
+	t.Parallel()
+
+	md, sensitive := payloadTypes(t)
+	inner := md.Fields().ByName("inner").Message()
+
+	newInner := func(secret, visible string) protoreflect.Value {
+		m := dynamicpb.NewMessage(inner)
+		m.Set(inner.Fields().ByName("secret"), protoreflect.ValueOfString(secret))
+		m.Set(inner.Fields().ByName("visible"), protoreflect.ValueOfString(visible))
+		return protoreflect.ValueOfMessage(m)
+	}
+	fd := func(name protoreflect.Name) protoreflect.FieldDescriptor {
+		return md.Fields().ByName(name)
+	}
+
+	tests := []struct {
+		name string
+		set  func(m *dynamicpb.Message)
+		want map[string]any
+	}{
+		{
+			name: "NotSensitive",
+			set: func(m *dynamicpb.Message) {
+				m.Set(fd("name"), protoreflect.ValueOfString("Le Guin"))
+				m.Set(fd("flag"), protoreflect.ValueOfBool(true))
+			},
+			want: map[string]any{"name": "Le Guin", "flag": true},
+		},
+		{
+			name: "StringMasked",
+			set: func(m *dynamicpb.Message) {
+				m.Set(fd("password"), protoreflect.ValueOfString("hunter2"))
+			},
+			want: map[string]any{"password": "[REDACTED]"},
+		},
+		{
+			name: "BytesMasked",
+			set: func(m *dynamicpb.Message) {
+				m.Set(fd("token"), protoreflect.ValueOfBytes([]byte("secret token")))
+			},
+			// bytes are rendered in base64
+			want: map[string]any{"token": "W1JFREFDVEVEXQ=="},
+		},
+		{
+			name: "OtherKindsCleared",
+			set: func(m *dynamicpb.Message) {
+				m.Set(fd("pin"), protoreflect.ValueOfInt64(1234))
+				m.Set(fd("hidden"), newInner("a", "b"))
+			},
+			want: map[string]any{},
+		},
+		{
+			name: "Nested",
+			set: func(m *dynamicpb.Message) {
+				m.Set(fd("inner"), newInner("a", "b"))
+			},
+			want: map[string]any{"inner": map[string]any{"secret": "[REDACTED]", "visible": "b"}},
+		},
+		{
+			name: "ListOfMessages",
+			set: func(m *dynamicpb.Message) {
+				list := m.Mutable(fd("items")).List()
+				list.Append(newInner("a", "b"))
+				list.Append(newInner("c", "d"))
+			},
+			want: map[string]any{"items": []any{
+				map[string]any{"secret": "[REDACTED]", "visible": "b"},
+				map[string]any{"secret": "[REDACTED]", "visible": "d"},
+			}},
+		},
+		{
+			name: "SensitiveListCleared",
+			set: func(m *dynamicpb.Message) {
+				m.Mutable(fd("tags")).List().Append(protoreflect.ValueOfString("a"))
+			},
+			want: map[string]any{},
+		},
+		{
+			name: "MapOfMessages",
+			set: func(m *dynamicpb.Message) {
+				m.Mutable(fd("by_key")).Map().Set(protoreflect.ValueOfString("k").MapKey(), newInner("a", "b"))
+			},
+			want: map[string]any{"by_key": map[string]any{
+				"k": map[string]any{"secret": "[REDACTED]", "visible": "b"},
+			}},
+		},
+		{
+			name: "SensitiveMapCleared",
+			set: func(m *dynamicpb.Message) {
+				m.Mutable(fd("labels")).Map().Set(protoreflect.ValueOfString("k").MapKey(), protoreflect.ValueOfString("v"))
+			},
+			want: map[string]any{},
+		},
+		{
+			name: "OneofSensitive",
+			set: func(m *dynamicpb.Message) {
+				m.Set(fd("card"), protoreflect.ValueOfString("4111 1111 1111 1111"))
+			},
+			want: map[string]any{"card": "[REDACTED]"},
+		},
+		{
+			name: "OneofNotSensitive",
+			set: func(m *dynamicpb.Message) {
+				m.Set(fd("code"), protoreflect.ValueOfInt64(7))
+			},
+			want: map[string]any{"code": "7"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := dynamicpb.NewMessage(md)
+			tc.set(m)
+			original := proto.Clone(m)
+
+			p := &payloadRenderer{sensitive: []protoreflect.ExtensionType{sensitive}}
+			s, ok := p.render(m)
+			require.True(t, ok)
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal([]byte(s), &got))
+			assert.Equal(t, tc.want, got)
+			// the logged message is a copy
+			assert.True(t, proto.Equal(original, m), "render modified the message")
+		})
+	}
+
+	t.Run("WithoutExtensions", func(t *testing.T) {
+		t.Parallel()
+
+		m := dynamicpb.NewMessage(md)
+		m.Set(fd("token"), protoreflect.ValueOfBytes([]byte("a")))
+		m.Set(fd("password"), protoreflect.ValueOfString("b"))
+
+		// debug_redact applies without any extension
+		s, ok := (&payloadRenderer{}).render(m)
+		require.True(t, ok)
+		var got map[string]any
+		require.NoError(t, json.Unmarshal([]byte(s), &got))
+		assert.Equal(t, map[string]any{"token": "YQ==", "password": "[REDACTED]"}, got)
+	})
+
+	t.Run("NotProto", func(t *testing.T) {
+		t.Parallel()
+
+		_, ok := (&payloadRenderer{}).render(struct{}{})
+		assert.False(t, ok)
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		s     string
+		limit int
+		want  string
+	}{
+		{"Shorter", "abc", 4, "abc"},
+		{"Equal", "abcd", 4, "abcd"},
+		{"Longer", "abcdef", 4, "abcd" + payloadTruncated},
+		{"NoLimit", "abcdef", 0, "abcdef"},
+		{"NegativeLimit", "abcdef", -1, "abcdef"},
+		// é is 2 bytes, cutting inside it keeps the bytes before it
+		{"TwoBytesBoundary", "aé", 2, "a" + payloadTruncated},
+		{"TwoBytesAfter", "aéb", 3, "aé" + payloadTruncated},
+		// 🙂 is 4 bytes
+		{"FourBytesFirst", "a🙂b", 2, "a" + payloadTruncated},
+		{"FourBytesLast", "a🙂b", 4, "a" + payloadTruncated},
+		{"FourBytesAfter", "a🙂b", 5, "a🙂" + payloadTruncated},
+		{"FirstRune", "🙂", 3, payloadTruncated},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := truncate(tc.s, tc.limit)
+			assert.Equal(t, tc.want, got)
+			assert.True(t, utf8.ValidString(got))
+			if tc.limit > 0 {
+				assert.LessOrEqual(t, len(strings.TrimSuffix(got, payloadTruncated)), tc.limit)
+			}
+		})
+	}
+}
+
+func TestStreamSamplerKeep(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		sampler streamSampler
+		upTo    int64
+		want    []int64
+	}{
+		{"Default", streamSampler{initial: 10, thereafter: 100}, 250, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 110, 210}},
+		{"Thereafter", streamSampler{initial: 2, thereafter: 3}, 20, []int64{1, 2, 5, 8, 11, 14, 17, 20}},
+		{"OnlyInitial", streamSampler{initial: 3}, 20, []int64{1, 2, 3}},
+		{"NoInitial", streamSampler{thereafter: 5}, 20, []int64{5, 10, 15, 20}},
+		{"Every", streamSampler{thereafter: 1}, 5, []int64{1, 2, 3, 4, 5}},
+		{"None", streamSampler{}, 20, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var kept []int64
+			for n := int64(1); n <= tc.upTo; n++ {
+				if tc.sampler.keep(n) {
+					kept = append(kept, n)
+				}
+			}
+			assert.Equal(t, tc.want, kept)
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// streamStats counts the messages of a stream, its messages can be sent and
+// received concurrently.
+type streamStats struct {
+	start  time.Time
+	client bool
+
+	sent          atomic.Int64
+	received      atomic.Int64
+	bytesSent     atomic.Int64
+	bytesReceived atomic.Int64
+	// firstResponse is the time from the start of the stream until the first
+	// response message, sent by handlers and received by clients.
+	firstResponse atomic.Int64
+
+	// sizes enables counting the bytes of the messages, which are only
+	// computed if they can be logged.
+	sizes bool
+	// payloads logs the messages, it is nil if disabled.
+	payloads *payloadLogger
+}
+
+func (s *streamStats) onSend(msg any) {
+	n := s.sent.Add(1)
+	if s.sizes {
+		s.bytesSent.Add(int64(messageSize(msg)))
+	}
+	if n == 1 && !s.client {
+		s.firstResponse.Store(int64(time.Since(s.start)))
+	}
+	if s.payloads != nil {
+		// clients send requests, handlers send responses
+		key := responsePayloadKey
+		if s.client {
+			key = requestPayloadKey
+		}
+		s.payloads.log("sent", key, n, msg)
+	}
+}
+
+func (s *streamStats) onReceive(msg any) {
+	n := s.received.Add(1)
+	if s.sizes {
+		s.bytesReceived.Add(int64(messageSize(msg)))
+	}
+	if n == 1 && s.client {
+		s.firstResponse.Store(int64(time.Since(s.start)))
+	}
+	if s.payloads != nil {
+		key := requestPayloadKey
+		if s.client {
+			key = responsePayloadKey
+		}
+		s.payloads.log("received", key, n, msg)
+	}
+}
+
+func (s *streamStats) attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int64(messagesSentKey, s.sent.Load()),
+		slog.Int64(messagesReceivedKey, s.received.Load()),
+	}
+	if s.sizes {
+		attrs = append(attrs,
+			slog.Int64(bytesSentKey, s.bytesSent.Load()),
+			slog.Int64(bytesReceivedKey, s.bytesReceived.Load()),
+		)
+	}
+	if first := s.firstResponse.Load(); first > 0 {
+		attrs = append(attrs, slog.Int64(firstMessageDelayKey, time.Duration(first).Milliseconds()))
+	}
+
+	return attrs
+}
+
+// handlerConn records the messages of a streaming handler.
+type handlerConn struct {
+	connect.StreamingHandlerConn
+
+	stats *streamStats
+}
+
+func (c *handlerConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	c.stats.onReceive(msg)
+
+	return nil
+}
+
+func (c *handlerConn) Send(msg any) error {
+	if err := c.StreamingHandlerConn.Send(msg); err != nil {
+		return err
+	}
+	c.stats.onSend(msg)
+
+	return nil
+}
+
+// clientConn records the messages of a streaming client, and logs the stream
+// once its response is closed.
+type clientConn struct {
+	connect.StreamingClientConn
+
+	stats   *streamStats
+	onClose func(err error)
+
+	mu  sync.Mutex
+	err error
+
+	closeOnce sync.Once
+}
+
+// setErr keeps the first error of the stream, io.EOF is the end of the stream.
+func (c *clientConn) setErr(err error) {
+	if err == nil || errors.Is(err, io.EOF) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *clientConn) Send(msg any) error {
+	if err := c.StreamingClientConn.Send(msg); err != nil {
+		c.setErr(err)
+		return err
+	}
+	c.stats.onSend(msg)
+
+	return nil
+}
+
+func (c *clientConn) Receive(msg any) error {
+	if err := c.StreamingClientConn.Receive(msg); err != nil {
+		c.setErr(err)
+		return err
+	}
+	c.stats.onReceive(msg)
+
+	return nil
+}
+
+func (c *clientConn) CloseRequest() error {
+	err := c.StreamingClientConn.CloseRequest()
+	c.setErr(err)
+
+	return err
+}
+
+func (c *clientConn) CloseResponse() error {
+	err := c.StreamingClientConn.CloseResponse()
+	c.setErr(err)
+
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		err := c.err
+		c.mu.Unlock()
+		c.onClose(err)
+	})
+
+	return err
+}
+
+// streamSampler keeps the first initial messages of each direction of a
+// stream, then one in thereafter.
+type streamSampler struct {
+	initial    int
+	thereafter int
+}
+
+func (s streamSampler) keep(n int64) bool {
+	if n <= int64(s.initial) {
+		return true
+	}
+
+	return s.thereafter > 0 && (n-int64(s.initial))%int64(s.thereafter) == 0
+}
+
+// payloadLogger logs the sampled messages of a stream.
+type payloadLogger struct {
+	ctx      context.Context
+	logger   *slog.Logger
+	renderer *payloadRenderer
+	sampler  streamSampler
+}
+
+func (p *payloadLogger) log(direction, key string, n int64, msg any) {
+	if !p.sampler.keep(n) || !p.logger.Enabled(p.ctx, slog.LevelInfo) {
+		return
+	}
+	payload, ok := p.renderer.attr(key, msg)
+	if !ok {
+		return
+	}
+
+	p.logger.LogAttrs(p.ctx, slog.LevelInfo, "stream_message",
+		slog.String("rpc.message.type", direction),
+		slog.Int64("rpc.message.id", n),
+		payload,
+	)
+}
@@ -2,12 +2,23 @@ package recovery
 
 import (
 	"context"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
 
 	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
+const meterName = "github.com/FotiadisM/service-template/pkg/connect/interceptors/recovery"
+
 type Interceptor struct {
 	recoveryFn RecoveryFunc
+	reporters  []PanicReporter
+	panics     metric.Int64Counter
 }
 
 var _ connect.Interceptor = &Interceptor{}
@@ -17,13 +28,75 @@ func NewInterceptor(opts ...Option) *Interceptor {
 	for _, fn := range opts {
 		fn(options)
 	}
-	return &Interceptor{recoveryFn: options.recoveryFn}
+
+	panics, err := otel.Meter(meterName).Int64Counter("rpc.server.panics",
+		metric.WithDescription("Number of panics recovered while handling requests."),
+		metric.WithUnit("{panic}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return &Interceptor{recoveryFn: options.recoveryFn, reporters: options.reporters, panics: panics}
+}
+
+// handlePanic counts and reports the panic p. It must be called by the
+// function deferred by the handler, for the stack to be the one of p.
+func (i *Interceptor) handlePanic(ctx context.Context, procedure string, p any) {
+	service, method, _ := strings.Cut(strings.TrimLeft(procedure, "/"), "/")
+	if i.panics != nil {
+		i.panics.Add(context.WithoutCancel(ctx), 1, metric.WithAttributes(
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", method),
+		))
+	}
+
+	if len(i.reporters) == 0 {
+		return
+	}
+
+	report := &Report{
+		Procedure: procedure,
+		Value:     p,
+		Stack:     debug.Stack(),
+		Frames:    panicFrames(),
+		Time:      time.Now(),
+	}
+	for _, r := range i.reporters {
+		r.ReportPanic(ctx, report)
+	}
+}
+
+// panicFrames returns the frames of the panicking goroutine, starting at
+// the function that panicked.
+func panicFrames() []runtime.Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []runtime.Frame
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		if panicking {
+			stack = append(stack, frame)
+		}
+		if frame.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			break
+		}
+	}
+
+	return stack
 }
 
 func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, req connect.AnyRequest) (res connect.AnyResponse, err error) {
 		defer func() {
 			if p := recover(); p != nil {
+				i.handlePanic(ctx, req.Spec().Procedure, p)
 				err = i.recoveryFn(ctx, p)
 			}
 		}()
@@ -42,6 +115,7 @@ func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) co
 	return func(ctx context.Context, conn connect.StreamingHandlerConn) (err error) {
 		defer func() {
 			if p := recover(); p != nil {
+				i.handlePanic(ctx, conn.Spec().Procedure, p)
 				err = i.recoveryFn(ctx, p)
 			}
 		}()
@@ -0,0 +1,203 @@
+package recovery_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/FotiadisM/service-template/pkg/connect/interceptors/recovery"
+)
+
+const (
+	unaryProcedure  = "/test.v1.TestService/Unary"
+	streamProcedure = "/test.v1.TestService/Stream"
+)
+
+// recordingReporter keeps the reports it is given.
+type recordingReporter struct {
+	mu      sync.Mutex
+	reports []*recovery.Report
+}
+
+func (r *recordingReporter) ReportPanic(_ context.Context, report *recovery.Report) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports = append(r.reports, report)
+}
+
+func (r *recordingReporter) all() []*recovery.Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reports
+}
+
+func panicHandler(value any) {
+	panic(value)
+}
+
+// newPanicServer serves a unary and a server streaming procedure that panic.
+func newPanicServer(t *testing.T, interceptor *recovery.Interceptor) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle(unaryProcedure, connect.NewUnaryHandler(unaryProcedure,
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			panicHandler("unary boom")
+			return nil, nil
+		},
+		connect.WithInterceptors(interceptor),
+	))
+	mux.Handle(streamProcedure, connect.NewServerStreamHandler(streamProcedure,
+		func(context.Context, *connect.Request[emptypb.Empty], *connect.ServerStream[emptypb.Empty]) error {
+			panicHandler(errors.New("stream boom"))
+			return nil
+		},
+		connect.WithInterceptors(interceptor),
+	))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func callUnary(t *testing.T, server *httptest.Server) error {
+	t.Helper()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+unaryProcedure)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.Error(t, err)
+
+	return err
+}
+
+func callStream(t *testing.T, server *httptest.Server) error {
+	t.Helper()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+streamProcedure)
+	stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	for stream.Receive() {
+	}
+	require.Error(t, stream.Err())
+
+	return stream.Err()
+}
+
+// TestInterceptorMetrics replaces the global meter provider, it must not run
+// in parallel with other tests creating interceptors.
+func TestInterceptorMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	prev := otel.GetMeterProvider()
+	otel.SetMeterProvider(provider)
+	t.Cleanup(func() { otel.SetMeterProvider(prev) })
+
+	server := newPanicServer(t, recovery.NewInterceptor())
+	callUnary(t, server)
+	callUnary(t, server)
+	callStream(t, server)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	var panics *metricdata.Sum[int64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "rpc.server.panics" {
+				sum := m.Data.(metricdata.Sum[int64])
+				panics = &sum
+			}
+		}
+	}
+	require.NotNil(t, panics, "rpc.server.panics not recorded")
+	assert.True(t, panics.IsMonotonic)
+
+	counts := map[string]int64{}
+	for _, dp := range panics.DataPoints {
+		service, _ := dp.Attributes.Value(attribute.Key("rpc.service"))
+		method, _ := dp.Attributes.Value(attribute.Key("rpc.method"))
+		counts[service.AsString()+"/"+method.AsString()] = dp.Value
+	}
+	assert.Equal(t, map[string]int64{
+		"test.v1.TestService/Unary":  2,
+		"test.v1.TestService/Stream": 1,
+	}, counts)
+}
+
+func TestInterceptorReporters(t *testing.T) {
+	t.Parallel()
+
+	first, second := &recordingReporter{}, &recordingReporter{}
+	server := newPanicServer(t, recovery.NewInterceptor(recovery.WithPanicReporters(first, second)))
+
+	err := callUnary(t, server)
+	assert.Equal(t, connect.CodeUnknown, connect.CodeOf(err))
+	assert.Contains(t, err.Error(), "panic caught: unary boom")
+	callStream(t, server)
+
+	reports := first.all()
+	require.Len(t, reports, 2)
+	assert.Equal(t, reports, second.all())
+
+	assert.Equal(t, unaryProcedure, reports[0].Procedure)
+	assert.Equal(t, "unary boom", reports[0].Value)
+	assert.Equal(t, streamProcedure, reports[1].Procedure)
+	assert.EqualError(t, reports[1].Value.(error), "stream boom")
+
+	for _, r := range reports {
+		assert.False(t, r.Time.IsZero())
+		assert.Contains(t, string(r.Stack), "panicHandler")
+		// frames start at the function that panicked
+		require.NotEmpty(t, r.Frames)
+		assert.True(t, strings.HasSuffix(r.Frames[0].Function, "recovery_test.panicHandler"), r.Frames[0].Function)
+	}
+}
+
+func TestInterceptorRecoveryFunc(t *testing.T) {
+	t.Parallel()
+
+	server := newPanicServer(t, recovery.NewInterceptor(recovery.WithRecoveryFunc(func(_ context.Context, p any) error {
+		return connect.NewError(connect.CodeInternal, errors.New("recovered"))
+	})))
+
+	err := callUnary(t, server)
+	assert.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+	assert.Equal(t, "recovered", err.(*connect.Error).Message())
+}
+
+func TestLogReporter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	r := recovery.NewLogReporter(slog.New(slog.NewJSONHandler(&buf, nil)))
+	r.ReportPanic(context.Background(), newReport())
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "panic recovered", record["msg"])
+	assert.Equal(t, "/book.v1.BookService/GetBook", record["rpc.procedure"])
+	assert.Equal(t, "boom", record["panic"])
+	assert.Equal(t, "goroutine 1 [running]:", record["stack"])
+}
@@ -6,6 +6,7 @@ type RecoveryFunc func(ctx context.Context, p any) (err error) //nolint
 
 type options struct {
 	recoveryFn RecoveryFunc
+	reporters  []PanicReporter
 }
 
 func defaultOptions() *options {
@@ -21,3 +22,10 @@ func WithRecoveryFunc(fn RecoveryFunc) Option {
 		o.recoveryFn = fn
 	}
 }
+
+// WithPanicReporters reports every recovered panic to reporters.
+func WithPanicReporters(reporters ...PanicReporter) Option {
+	return func(o *options) {
+		o.reporters = reporters
+	}
+}
@@ -0,0 +1,47 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// Report describes a panic recovered while handling a request.
+type Report struct {
+	Procedure string
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the formatted stack of the panicking goroutine.
+	Stack []byte
+	// Frames are the frames of the stack, starting at the function that
+	// panicked.
+	Frames []runtime.Frame
+	Time   time.Time
+}
+
+// PanicReporter is told about every panic recovered by the Interceptor.
+// ReportPanic is called before the request completes, it must not block.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, report *Report)
+}
+
+// LogReporter reports panics as ERROR records.
+type LogReporter struct {
+	log *slog.Logger
+}
+
+var _ PanicReporter = (*LogReporter)(nil)
+
+func NewLogReporter(log *slog.Logger) *LogReporter {
+	return &LogReporter{log: log}
+}
+
+func (r *LogReporter) ReportPanic(ctx context.Context, report *Report) {
+	r.log.LogAttrs(ctx, slog.LevelError, "panic recovered",
+		slog.String("rpc.procedure", report.Procedure),
+		slog.String("panic", fmt.Sprint(report.Value)),
+		slog.String("stack", string(report.Stack)),
+	)
+}
@@ -0,0 +1,355 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+var ErrInvalidDSN = errors.New("invalid sentry DSN")
+
+type sentryOptions struct {
+	environment string
+	release     string
+	client      *http.Client
+	queueSize   int
+	log         *slog.Logger
+}
+
+type SentryOption func(o *sentryOptions)
+
+func WithSentryEnvironment(environment string) SentryOption {
+	return func(o *sentryOptions) {
+		o.environment = environment
+	}
+}
+
+func WithSentryRelease(release string) SentryOption {
+	return func(o *sentryOptions) {
+		o.release = release
+	}
+}
+
+// WithSentryHTTPClient sets the client sending the events, default has a
+// timeout of 5s.
+func WithSentryHTTPClient(client *http.Client) SentryOption {
+	return func(o *sentryOptions) {
+		o.client = client
+	}
+}
+
+// WithSentryQueueSize sets how many events can wait to be sent (32), further
+// events are dropped.
+func WithSentryQueueSize(size int) SentryOption {
+	return func(o *sentryOptions) {
+		o.queueSize = size
+	}
+}
+
+// WithSentryLogger sets the logger of the events that fail to be sent,
+// default is slog.Default.
+func WithSentryLogger(log *slog.Logger) SentryOption {
+	return func(o *sentryOptions) {
+		o.log = log
+	}
+}
+
+// SentryReporter sends panics as events to the envelope endpoint of a Sentry
+// compatible server. Events are sent in the background, Close waits for the
+// queued ones.
+type SentryReporter struct {
+	dsn      string
+	endpoint string
+	auth     string
+	opts     *sentryOptions
+
+	queue chan []byte
+	done  chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+var _ PanicReporter = (*SentryReporter)(nil)
+
+// NewSentryReporter creates a SentryReporter for a DSN of the form
+// https://<key>@<host>/<project>.
+func NewSentryReporter(dsn string, opts ...SentryOption) (*SentryReporter, error) {
+	options := &sentryOptions{
+		client:    &http.Client{Timeout: 5 * time.Second},
+		queueSize: 32,
+	}
+	for _, fn := range opts {
+		fn(options)
+	}
+	if options.log == nil {
+		options.log = slog.Default()
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDSN, err)
+	}
+	key := u.User.Username()
+	prefix, project, _ := cutLast(strings.TrimSuffix(u.Path, "/"), "/")
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || key == "" || project == "" {
+		return nil, ErrInvalidDSN
+	}
+
+	r := &SentryReporter{
+		dsn:      dsn,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project),
+		auth:     "Sentry sentry_version=7, sentry_client=service-template/1.0, sentry_key=" + key,
+		opts:     options,
+		queue:    make(chan []byte, options.queueSize),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+
+	return r, nil
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return "", s, false
+}
+
+func (r *SentryReporter) ReportPanic(ctx context.Context, report *Report) {
+	envelope, err := r.envelope(ctx, report)
+	if err != nil {
+		r.opts.log.Warn("failed to encode panic event", ilog.Err(err))
+		return
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.queue <- envelope:
+	default:
+		r.opts.log.Warn("dropped panic event, the queue is full")
+	}
+}
+
+func (r *SentryReporter) run() {
+	defer close(r.done)
+
+	for envelope := range r.queue {
+		if err := r.send(envelope); err != nil {
+			r.opts.log.Warn("failed to send panic event", ilog.Err(err))
+		}
+	}
+}
+
+func (r *SentryReporter) send(envelope []byte) error {
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(envelope))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	res, err := r.opts.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return nil
+}
+
+// Close stops accepting panics and waits until the queued events are sent,
+// or ctx is done.
+func (r *SentryReporter) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type sentryFrame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+type sentryException struct {
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	Mechanism struct {
+		Type    string `json:"type"`
+		Handled bool   `json:"handled"`
+	} `json:"mechanism"`
+	Stacktrace struct {
+		Frames []sentryFrame `json:"frames"`
+	} `json:"stacktrace"`
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Transaction string            `json:"transaction,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Contexts    map[string]any    `json:"contexts,omitempty"`
+	Exception   struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// sentryFrames converts frames to the order of Sentry, innermost last.
+func sentryFrames(frames []runtime.Frame) []sentryFrame {
+	out := make([]sentryFrame, 0, len(frames))
+	for _, f := range slices.Backward(frames) {
+		module, function := splitFunction(f.Function)
+		out = append(out, sentryFrame{
+			Function: function,
+			Module:   module,
+			Filename: f.File,
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    inApp(module, f.File),
+		})
+	}
+
+	return out
+}
+
+// inApp reports whether a frame belongs to the application, rather than the
+// standard library, whose paths have no dot in their first element, or a
+// dependency in the module cache.
+func inApp(module, file string) bool {
+	first, _, _ := strings.Cut(module, "/")
+
+	return strings.Contains(first, ".") && !strings.Contains(file, "/pkg/mod/")
+}
+
+// splitFunction splits a qualified function name, like
+// github.com/a/b.(*T).M, into its package path and name.
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		i := slash + 1 + dot
+		return name[:i], name[i+1:]
+	}
+
+	return "", name
+}
+
+func (r *SentryReporter) envelope(ctx context.Context, report *Report) ([]byte, error) {
+	service, method, _ := strings.Cut(strings.TrimLeft(report.Procedure, "/"), "/")
+	hostname, _ := os.Hostname()
+
+	event := sentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   report.Time.UTC(),
+		Level:       "fatal",
+		Platform:    "go",
+		Release:     r.opts.release,
+		Environment: r.opts.environment,
+		ServerName:  hostname,
+		Transaction: report.Procedure,
+		Tags: map[string]string{
+			"rpc.service": service,
+			"rpc.method":  method,
+		},
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		event.Contexts = map[string]any{
+			"trace": map[string]string{
+				"trace_id": span.TraceID().String(),
+				"span_id":  span.SpanID().String(),
+			},
+		}
+	}
+
+	exception := sentryException{
+		Type:  fmt.Sprintf("panic: %T", report.Value),
+		Value: fmt.Sprint(report.Value),
+	}
+	exception.Mechanism.Type = "connect.recovery"
+	exception.Stacktrace.Frames = sentryFrames(report.Frames)
+	event.Exception.Values = []sentryException{exception}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := json.Marshal(map[string]any{
+		"event_id": event.EventID,
+		"sent_at":  time.Now().UTC(),
+		"dsn":      r.dsn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	itemHeader, err := json.Marshal(map[string]any{
+		"type":         "event",
+		"content_type": "application/json",
+		"length":       len(payload),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Write(header)
+	b.WriteByte('\n')
+	b.Write(itemHeader)
+	b.WriteByte('\n')
+	b.Write(payload)
+	b.WriteByte('\n')
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,326 @@
+package recovery_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/FotiadisM/service-template/pkg/connect/interceptors/recovery"
+)
+
+// sentryServer is a stand-in for the envelope endpoint of Sentry.
+type sentryServer struct {
+	*httptest.Server
+
+	// received is sent every request once it is read.
+	received chan *http.Request
+	// release unblocks the responses, if block is set.
+	release chan struct{}
+	block   bool
+
+	mu        sync.Mutex
+	envelopes [][]byte
+}
+
+func newSentryServer(t *testing.T, block bool) *sentryServer {
+	t.Helper()
+
+	s := &sentryServer{
+		received: make(chan *http.Request, 16),
+		release:  make(chan struct{}),
+		block:    block,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.envelopes = append(s.envelopes, body)
+		s.mu.Unlock()
+
+		s.received <- r
+		if s.block {
+			<-s.release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *sentryServer) dsn(path string) string {
+	return strings.Replace(s.URL, "http://", "http://public-key@", 1) + path
+}
+
+func (s *sentryServer) envelope(i int) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.envelopes[i]
+}
+
+func (s *sentryServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.envelopes)
+}
+
+func newReport() *recovery.Report {
+	return &recovery.Report{
+		Procedure: "/book.v1.BookService/GetBook",
+		Value:     "boom",
+		Stack:     []byte("goroutine 1 [running]:"),
+		Frames: []runtime.Frame{
+			{Function: "github.com/FotiadisM/service-template/internal/services/book/v1.(*Service).GetBook", File: "/src/service.go", Line: 42},
+			{Function: "net/http.HandlerFunc.ServeHTTP", File: "/usr/local/go/src/net/http/server.go", Line: 2220},
+		},
+		Time: time.Date(2025, time.February, 17, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// parseEnvelope splits an envelope into its header, item header and event.
+func parseEnvelope(t *testing.T, envelope []byte) (header, item, event map[string]any) {
+	t.Helper()
+
+	lines := bytes.Split(bytes.TrimSuffix(envelope, []byte("\n")), []byte("\n"))
+	require.Len(t, lines, 3, "envelope must hold a header and a single item")
+	require.NoError(t, json.Unmarshal(lines[0], &header))
+	require.NoError(t, json.Unmarshal(lines[1], &item))
+	require.NoError(t, json.Unmarshal(lines[2], &event))
+	assert.EqualValues(t, len(lines[2]), item["length"])
+
+	return header, item, event
+}
+
+func TestNewSentryReporterDSN(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		dsn   string
+		valid bool
+	}{
+		{"Valid", "https://key@o1.ingest.sentry.io/42", true},
+		{"PathPrefix", "http://key@localhost:9000/sentry/42", true},
+		{"Malformed", "://key@host/42", false},
+		{"Scheme", "ftp://key@host/42", false},
+		{"NoKey", "https://host/42", false},
+		{"NoHost", "https://key@/42", false},
+		{"NoProject", "https://key@host/", false},
+		{"Empty", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := recovery.NewSentryReporter(tc.dsn)
+			if !tc.valid {
+				require.ErrorIs(t, err, recovery.ErrInvalidDSN)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, r.Close(context.Background()))
+		})
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	t.Parallel()
+	server := newSentryServer(t, false)
+
+	dsn := server.dsn("/prefix/42")
+	r, err := recovery.NewSentryReporter(dsn,
+		recovery.WithSentryEnvironment("staging"),
+		recovery.WithSentryRelease("v1.2.3"),
+	)
+	require.NoError(t, err)
+
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	r.ReportPanic(ctx, newReport())
+	require.NoError(t, r.Close(context.Background()))
+
+	req := <-server.received
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "/prefix/api/42/envelope/", req.URL.Path)
+	assert.Equal(t, "application/x-sentry-envelope", req.Header.Get("Content-Type"))
+	assert.Equal(t, "Sentry sentry_version=7, sentry_client=service-template/1.0, sentry_key=public-key", req.Header.Get("X-Sentry-Auth"))
+
+	header, item, event := parseEnvelope(t, server.envelope(0))
+	assert.Equal(t, dsn, header["dsn"])
+	assert.Equal(t, event["event_id"], header["event_id"])
+	assert.Len(t, event["event_id"], 32)
+	assert.Equal(t, "event", item["type"])
+	assert.Equal(t, "application/json", item["content_type"])
+
+	assert.Equal(t, "fatal", event["level"])
+	assert.Equal(t, "go", event["platform"])
+	assert.Equal(t, "staging", event["environment"])
+	assert.Equal(t, "v1.2.3", event["release"])
+	assert.Equal(t, "2025-02-17T12:00:00Z", event["timestamp"])
+	assert.Equal(t, "/book.v1.BookService/GetBook", event["transaction"])
+	assert.Equal(t, map[string]any{"rpc.service": "book.v1.BookService", "rpc.method": "GetBook"}, event["tags"])
+	assert.Equal(t, map[string]any{
+		"trace": map[string]any{"trace_id": traceID.String(), "span_id": spanID.String()},
+	}, event["contexts"])
+
+	exceptions := event["exception"].(map[string]any)["values"].([]any)
+	require.Len(t, exceptions, 1)
+	exception := exceptions[0].(map[string]any)
+	assert.Equal(t, "panic: string", exception["type"])
+	assert.Equal(t, "boom", exception["value"])
+	assert.Equal(t, map[string]any{"type": "connect.recovery", "handled": false}, exception["mechanism"])
+
+	// frames are ordered innermost last
+	frames := exception["stacktrace"].(map[string]any)["frames"].([]any)
+	require.Len(t, frames, 2)
+	assert.Equal(t, map[string]any{
+		"function": "HandlerFunc.ServeHTTP",
+		"module":   "net/http",
+		"filename": "/usr/local/go/src/net/http/server.go",
+		"abs_path": "/usr/local/go/src/net/http/server.go",
+		"lineno":   float64(2220),
+		"in_app":   false,
+	}, frames[0])
+	assert.Equal(t, map[string]any{
+		"function": "(*Service).GetBook",
+		"module":   "github.com/FotiadisM/service-template/internal/services/book/v1",
+		"filename": "/src/service.go",
+		"abs_path": "/src/service.go",
+		"lineno":   float64(42),
+		"in_app":   true,
+	}, frames[1])
+}
+
+func TestSentryReporterQueueFull(t *testing.T) {
+	t.Parallel()
+	server := newSentryServer(t, true)
+
+	var logs syncBuffer
+	r, err := recovery.NewSentryReporter(server.dsn("/42"),
+		recovery.WithSentryQueueSize(1),
+		recovery.WithSentryLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+	require.NoError(t, err)
+
+	// the first event is being sent, the second waits in the queue
+	r.ReportPanic(context.Background(), newReport())
+	<-server.received
+	r.ReportPanic(context.Background(), newReport())
+	r.ReportPanic(context.Background(), newReport())
+	assert.Contains(t, logs.String(), "dropped panic event, the queue is full")
+
+	close(server.release)
+	require.NoError(t, r.Close(context.Background()))
+	assert.Equal(t, 2, server.count())
+}
+
+func TestSentryReporterClose(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Drains", func(t *testing.T) {
+		t.Parallel()
+		server := newSentryServer(t, true)
+
+		r, err := recovery.NewSentryReporter(server.dsn("/42"))
+		require.NoError(t, err)
+		for range 3 {
+			r.ReportPanic(context.Background(), newReport())
+		}
+
+		closed := make(chan error)
+		go func() { closed <- r.Close(context.Background()) }()
+
+		<-server.received
+		select {
+		case <-closed:
+			t.Fatal("Close returned before the queued events were sent")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(server.release)
+		require.NoError(t, <-closed)
+		assert.Equal(t, 3, server.count())
+
+		// reports after Close are ignored
+		r.ReportPanic(context.Background(), newReport())
+		assert.Equal(t, 3, server.count())
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		t.Parallel()
+		server := newSentryServer(t, true)
+		defer close(server.release)
+
+		r, err := recovery.NewSentryReporter(server.dsn("/42"))
+		require.NoError(t, err)
+		r.ReportPanic(context.Background(), newReport())
+		<-server.received
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, r.Close(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("ServerError", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		var logs syncBuffer
+		r, err := recovery.NewSentryReporter(strings.Replace(server.URL, "http://", "http://key@", 1)+"/42",
+			recovery.WithSentryLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		)
+		require.NoError(t, err)
+		r.ReportPanic(context.Background(), newReport())
+		require.NoError(t, r.Close(context.Background()))
+
+		assert.Contains(t, logs.String(), "failed to send panic event")
+		assert.Contains(t, logs.String(), "429 Too Many Requests")
+	})
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
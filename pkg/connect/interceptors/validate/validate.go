@@ -13,6 +13,12 @@ func DefaultErrorHanlder(err error) error {
 		return nil
 	}
 
+	// the validation errors of the interceptor already are connect errors,
+	// wrapping them again would prefix their message with the code
+	if cErr := new(connect.Error); errors.As(err, &cErr) {
+		return err
+	}
+
 	if tErr := new(protovalidate.ValidationError); errors.As(err, &tErr) {
 		conErr := connect.NewError(connect.CodeInvalidArgument, err)
 
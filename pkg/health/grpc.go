@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var errUnknownService = errors.New("unknown service")
+
+// status resolves a service of the gRPC health protocol. The empty service
+// and the probe names report the probes, any other name a single check.
+func (r *Registry) status(service string) (Status, bool) {
+	switch Probe(service) {
+	case "", ProbeReadiness:
+		return r.Report(ProbeReadiness).Status, true
+	case ProbeStartup, ProbeLiveness:
+		return r.Report(Probe(service)).Status, true
+	}
+
+	res, ok := r.Check(service)
+	return res.Status, ok
+}
+
+func toProto(s Status) healthv1.HealthCheckResponse_ServingStatus {
+	switch s {
+	case StatusServing:
+		return healthv1.HealthCheckResponse_SERVING
+	case StatusNotServing:
+		return healthv1.HealthCheckResponse_NOT_SERVING
+	default:
+		return healthv1.HealthCheckResponse_UNKNOWN
+	}
+}
+
+// NewHandler returns the path and handler of the grpc.health.v1.Health
+// service, including the Watch method that grpchealth leaves unimplemented.
+func NewHandler(r *Registry, opts ...connect.HandlerOption) (string, http.Handler) {
+	const prefix = "/" + grpchealth.HealthV1ServiceName + "/"
+
+	check := connect.NewUnaryHandler(prefix+"Check", r.grpcCheck, opts...)
+	watch := connect.NewServerStreamHandler(prefix+"Watch", r.grpcWatch, opts...)
+
+	mux := http.NewServeMux()
+	mux.Handle(prefix+"Check", check)
+	mux.Handle(prefix+"Watch", watch)
+
+	return prefix, mux
+}
+
+func (r *Registry) grpcCheck(
+	_ context.Context,
+	req *connect.Request[healthv1.HealthCheckRequest],
+) (*connect.Response[healthv1.HealthCheckResponse], error) {
+	status, ok := r.status(req.Msg.GetService())
+	if !ok {
+		return nil, connect.NewError(
+			connect.CodeNotFound,
+			fmt.Errorf("'%s': %w", req.Msg.GetService(), errUnknownService),
+		)
+	}
+
+	return connect.NewResponse(&healthv1.HealthCheckResponse{Status: toProto(status)}), nil
+}
+
+// grpcWatch sends the status of the service, then every change of it until the
+// client goes away or the registry drains, so that streams do not hold up the
+// shutdown of the server. Unknown services report SERVICE_UNKNOWN, as the
+// check may be registered later on.
+func (r *Registry) grpcWatch(
+	ctx context.Context,
+	req *connect.Request[healthv1.HealthCheckRequest],
+	stream *connect.ServerStream[healthv1.HealthCheckResponse],
+) error {
+	last := healthv1.HealthCheckResponse_ServingStatus(-1)
+	for {
+		changed := r.Changed()
+
+		current := healthv1.HealthCheckResponse_SERVICE_UNKNOWN
+		if status, ok := r.status(req.Msg.GetService()); ok {
+			current = toProto(status)
+		}
+
+		if current != last {
+			if err := stream.Send(&healthv1.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+		if r.Draining() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package health_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/FotiadisM/service-template/pkg/health"
+)
+
+const healthPrefix = "/" + grpchealth.HealthV1ServiceName + "/"
+
+func newHealthServer(t *testing.T, r *health.Registry) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle(health.NewHandler(r))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestGRPCCheck(t *testing.T) {
+	t.Parallel()
+
+	r := health.NewRegistry()
+	r.Register("db", passing)
+	r.Register("cache", failing, health.NonCritical())
+	r.Register("deadlock", failing, health.WithProbes(health.ProbeLiveness))
+	start(t, r, "db", "cache", "deadlock")
+
+	srv := newHealthServer(t, r)
+	client := connect.NewClient[healthv1.HealthCheckRequest, healthv1.HealthCheckResponse](
+		srv.Client(), srv.URL+healthPrefix+"Check",
+	)
+
+	tests := []struct {
+		service  string
+		want     healthv1.HealthCheckResponse_ServingStatus
+		wantCode connect.Code
+	}{
+		// the empty service reports readiness
+		{"", healthv1.HealthCheckResponse_SERVING, 0},
+		{"readiness", healthv1.HealthCheckResponse_SERVING, 0},
+		{"startup", healthv1.HealthCheckResponse_SERVING, 0},
+		{"liveness", healthv1.HealthCheckResponse_NOT_SERVING, 0},
+		{"db", healthv1.HealthCheckResponse_SERVING, 0},
+		{"cache", healthv1.HealthCheckResponse_NOT_SERVING, 0},
+		{"missing", 0, connect.CodeNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.service, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := client.CallUnary(t.Context(), connect.NewRequest(&healthv1.HealthCheckRequest{Service: tc.service}))
+			if tc.wantCode != 0 {
+				require.Equal(t, tc.wantCode, connect.CodeOf(err))
+				assert.ErrorContains(t, err, "'missing': unknown service")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, res.Msg.GetStatus())
+		})
+	}
+}
+
+// watch starts watching service and returns the channel of the statuses
+// received, which is closed when the stream ends.
+func watch(t *testing.T, srv *httptest.Server, service string) <-chan healthv1.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	client := connect.NewClient[healthv1.HealthCheckRequest, healthv1.HealthCheckResponse](
+		srv.Client(), srv.URL+healthPrefix+"Watch",
+	)
+	stream, err := client.CallServerStream(t.Context(), connect.NewRequest(&healthv1.HealthCheckRequest{Service: service}))
+	require.NoError(t, err)
+
+	statuses := make(chan healthv1.HealthCheckResponse_ServingStatus, 10)
+	go func() {
+		defer close(statuses)
+		defer stream.Close()
+		for stream.Receive() {
+			statuses <- stream.Msg().GetStatus()
+		}
+		assert.NoError(t, stream.Err())
+	}()
+
+	return statuses
+}
+
+func receive(t *testing.T, statuses <-chan healthv1.HealthCheckResponse_ServingStatus) healthv1.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	select {
+	case status, ok := <-statuses:
+		require.True(t, ok, "the stream ended")
+		return status
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no status received")
+		return 0
+	}
+}
+
+func requireEnded(t *testing.T, statuses <-chan healthv1.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+
+	select {
+	case status, ok := <-statuses:
+		require.False(t, ok, "unexpected status %s", status)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the stream did not end")
+	}
+}
+
+func TestGRPCWatch(t *testing.T) {
+	t.Parallel()
+
+	c := &toggle{}
+	c.healthy.Store(true)
+	r := health.NewRegistry()
+	r.Register("db", c.check, health.WithInterval(time.Millisecond))
+	srv := newHealthServer(t, r)
+
+	readiness := watch(t, srv, "")
+	db := watch(t, srv, "db")
+	missing := watch(t, srv, "missing")
+
+	// the status is sent right away, checks not evaluated yet fail readiness
+	assert.Equal(t, healthv1.HealthCheckResponse_NOT_SERVING, receive(t, readiness))
+	assert.Equal(t, healthv1.HealthCheckResponse_UNKNOWN, receive(t, db))
+	assert.Equal(t, healthv1.HealthCheckResponse_SERVICE_UNKNOWN, receive(t, missing))
+
+	start(t, r, "db")
+	assert.Equal(t, healthv1.HealthCheckResponse_SERVING, receive(t, readiness))
+	assert.Equal(t, healthv1.HealthCheckResponse_SERVING, receive(t, db))
+
+	c.healthy.Store(false)
+	assert.Equal(t, healthv1.HealthCheckResponse_NOT_SERVING, receive(t, readiness))
+	assert.Equal(t, healthv1.HealthCheckResponse_NOT_SERVING, receive(t, db))
+
+	// streams end once draining, after sending a change of status
+	c.healthy.Store(true)
+	assert.Equal(t, healthv1.HealthCheckResponse_SERVING, receive(t, readiness))
+	assert.Equal(t, healthv1.HealthCheckResponse_SERVING, receive(t, db))
+	r.Drain()
+	assert.Equal(t, healthv1.HealthCheckResponse_NOT_SERVING, receive(t, readiness))
+	requireEnded(t, readiness)
+	requireEnded(t, db)
+	requireEnded(t, missing)
+}
+
+func TestGRPCWatchCanceled(t *testing.T) {
+	t.Parallel()
+
+	r := health.NewRegistry()
+	srv := newHealthServer(t, r)
+
+	client := connect.NewClient[healthv1.HealthCheckRequest, healthv1.HealthCheckResponse](
+		srv.Client(), srv.URL+healthPrefix+"Watch",
+	)
+	ctx, cancel := context.WithCancel(t.Context())
+	stream, err := client.CallServerStream(ctx, connect.NewRequest(&healthv1.HealthCheckRequest{}))
+	require.NoError(t, err)
+	t.Cleanup(func() { stream.Close() })
+
+	require.True(t, stream.Receive())
+	// no checks are registered, readiness passes
+	assert.Equal(t, healthv1.HealthCheckResponse_SERVING, stream.Msg().GetStatus())
+
+	cancel()
+	assert.False(t, stream.Receive())
+	assert.Equal(t, connect.CodeCanceled, connect.CodeOf(stream.Err()))
+}
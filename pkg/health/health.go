@@ -0,0 +1,373 @@
+// Package health evaluates named health checks in the background and reports
+// their cached results through the gRPC health protocol and HTTP probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Status int
+
+const (
+	StatusUnknown Status = iota
+	StatusServing
+	StatusNotServing
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusServing:
+		return "SERVING"
+	case StatusNotServing:
+		return "NOT_SERVING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+type Probe string
+
+const (
+	// ProbeStartup reports SERVING once every critical startup check has
+	// passed, and keeps doing so afterwards.
+	ProbeStartup Probe = "startup"
+	// ProbeReadiness reports SERVING while every critical readiness check
+	// passes.
+	ProbeReadiness Probe = "readiness"
+	// ProbeLiveness reports SERVING while every critical liveness check
+	// passes. No check takes part in it unless registered WithProbes.
+	ProbeLiveness Probe = "liveness"
+)
+
+var ErrTimeout = errors.New("health check timed out")
+
+// CheckFunc returns a non nil error if the dependency it checks is unhealthy.
+type CheckFunc func(ctx context.Context) error
+
+type checkOptions struct {
+	timeout  time.Duration
+	interval time.Duration
+	critical bool
+	probes   []Probe
+}
+
+type CheckOption func(*checkOptions)
+
+// WithTimeout sets how long a single evaluation of the check may take (registry default).
+func WithTimeout(d time.Duration) CheckOption {
+	return func(o *checkOptions) {
+		o.timeout = d
+	}
+}
+
+// WithInterval sets how often the check is evaluated (registry default).
+func WithInterval(d time.Duration) CheckOption {
+	return func(o *checkOptions) {
+		o.interval = d
+	}
+}
+
+// NonCritical makes failures of the check visible in the report without
+// affecting the status of the probes.
+func NonCritical() CheckOption {
+	return func(o *checkOptions) {
+		o.critical = false
+	}
+}
+
+// WithProbes sets the probes the check takes part in (startup and readiness).
+func WithProbes(probes ...Probe) CheckOption {
+	return func(o *checkOptions) {
+		o.probes = probes
+	}
+}
+
+type options struct {
+	timeout  time.Duration
+	interval time.Duration
+}
+
+func defaultOptions() *options {
+	return &options{
+		timeout:  time.Second,
+		interval: 10 * time.Second,
+	}
+}
+
+type Option func(*options)
+
+// WithDefaultTimeout sets the timeout of checks registered without one (1s).
+func WithDefaultTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithDefaultInterval sets the interval of checks registered without one (10s).
+func WithDefaultInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// Result is the outcome of the last evaluation of a check.
+type Result struct {
+	Name      string        `json:"name"`
+	Status    Status        `json:"status"`
+	Critical  bool          `json:"critical"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	CheckedAt time.Time     `json:"checked_at,omitzero"`
+}
+
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(struct {
+		result
+		Duration string `json:"duration"`
+	}{result(r), r.Duration.String()})
+}
+
+// Report is the status of a probe along with the results it is derived from.
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+	opts checkOptions
+}
+
+func (c *check) in(probe Probe) bool {
+	return slices.Contains(c.opts.probes, probe)
+}
+
+type Registry struct {
+	opts *options
+
+	mu      sync.RWMutex
+	checks  []*check
+	results map[string]Result
+	started bool
+	// startedUp latches once the startup probe reports SERVING.
+	startedUp bool
+	draining  bool
+	// changed is closed and replaced whenever a result changes status.
+	changed chan struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRegistry(opts ...Option) *Registry {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Registry{
+		opts:    o,
+		results: map[string]Result{},
+		changed: make(chan struct{}),
+	}
+}
+
+// Register adds a check named name. Checks must be registered before Start.
+func (r *Registry) Register(name string, fn CheckFunc, opts ...CheckOption) {
+	o := checkOptions{
+		timeout:  r.opts.timeout,
+		interval: r.opts.interval,
+		critical: true,
+		probes:   []Probe{ProbeStartup, ProbeReadiness},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		panic("health: Register called after Start")
+	}
+	if slices.ContainsFunc(r.checks, func(c *check) bool { return c.name == name }) {
+		panic(fmt.Sprintf("health: check %q registered twice", name))
+	}
+
+	r.checks = append(r.checks, &check{name: name, fn: fn, opts: o})
+	r.results[name] = Result{Name: name, Status: StatusUnknown, Critical: o.critical}
+}
+
+// Start evaluates every check in the background, each at its own interval,
+// until Stop is called or ctx is done.
+func (r *Registry) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		return
+	}
+	r.started = true
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	for _, c := range r.checks {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.run(ctx, c)
+		}()
+	}
+}
+
+// Stop stops the background evaluation and waits for running checks to return.
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	r.wg.Wait()
+}
+
+func (r *Registry) run(ctx context.Context, c *check) {
+	ticker := time.NewTicker(c.opts.interval)
+	defer ticker.Stop()
+
+	for {
+		r.evaluate(ctx, c)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Registry) evaluate(ctx context.Context, c *check) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w after %s: %w", ErrTimeout, c.opts.timeout, err)
+	}
+	if err != nil && ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// the registry is stopping, keep the last result
+		return
+	}
+
+	res := Result{
+		Name:      c.name,
+		Status:    StatusServing,
+		Critical:  c.opts.critical,
+		Duration:  time.Since(start),
+		CheckedAt: start,
+	}
+	if err != nil {
+		res.Status = StatusNotServing
+		res.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev := r.results[c.name]
+	r.results[c.name] = res
+	if prev.Status != res.Status {
+		r.notifyLocked()
+	}
+}
+
+func (r *Registry) notifyLocked() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Drain makes readiness report NOT_SERVING regardless of the checks, so that
+// load balancers stop routing new requests before the server shuts down.
+// Watch streams end after sending the new status.
+func (r *Registry) Drain() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.draining {
+		r.draining = true
+		r.notifyLocked()
+	}
+}
+
+// Draining reports whether Drain was called.
+func (r *Registry) Draining() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.draining
+}
+
+// Changed returns a channel that is closed the next time the status of a
+// check changes.
+func (r *Registry) Changed() <-chan struct{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.changed
+}
+
+// Check returns the cached result of the check named name.
+func (r *Registry) Check(name string) (Result, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res, ok := r.results[name]
+	return res, ok
+}
+
+// Report returns the status of probe along with the results of the checks
+// taking part in it. A critical check that has not been evaluated yet counts
+// as failing, and readiness fails once the registry is draining.
+func (r *Registry) Report(probe Probe) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := Report{Status: StatusServing, Checks: []Result{}}
+	for _, c := range r.checks {
+		if !c.in(probe) {
+			continue
+		}
+
+		res := r.results[c.name]
+		report.Checks = append(report.Checks, res)
+		if res.Critical && res.Status != StatusServing {
+			report.Status = StatusNotServing
+		}
+	}
+
+	if probe == ProbeReadiness && r.draining {
+		report.Status = StatusNotServing
+	}
+
+	if probe == ProbeStartup {
+		if r.startedUp {
+			report.Status = StatusServing
+		}
+		r.startedUp = report.Status == StatusServing
+	}
+
+	return report
+}
@@ -0,0 +1,346 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/health"
+)
+
+var errUnhealthy = errors.New("unhealthy")
+
+func passing(context.Context) error { return nil }
+func failing(context.Context) error { return errUnhealthy }
+
+// toggle is a check whose result is switched by the test.
+type toggle struct {
+	healthy atomic.Bool
+}
+
+func (c *toggle) check(context.Context) error {
+	if c.healthy.Load() {
+		return nil
+	}
+	return errUnhealthy
+}
+
+// start starts r and waits for every check named names to be evaluated.
+func start(t *testing.T, r *health.Registry, names ...string) {
+	t.Helper()
+
+	r.Start(context.Background())
+	t.Cleanup(r.Stop)
+
+	require.Eventually(t, func() bool {
+		for _, name := range names {
+			if res, _ := r.Check(name); res.CheckedAt.IsZero() {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, time.Millisecond)
+}
+
+// waitChanged waits for the channel of Changed to be closed.
+func waitChanged(t *testing.T, changed <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the status did not change")
+	}
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	type check struct {
+		name string
+		fn   health.CheckFunc
+		opts []health.CheckOption
+	}
+	tests := []struct {
+		name   string
+		checks []check
+		probe  health.Probe
+		want   health.Status
+		// wantChecks are the names of the checks in the report.
+		wantChecks []string
+	}{
+		{
+			name:       "NoChecks",
+			probe:      health.ProbeReadiness,
+			want:       health.StatusServing,
+			wantChecks: []string{},
+		},
+		{
+			name:       "Passing",
+			checks:     []check{{"db", passing, nil}, {"cache", passing, nil}},
+			probe:      health.ProbeReadiness,
+			want:       health.StatusServing,
+			wantChecks: []string{"db", "cache"},
+		},
+		{
+			name:       "CriticalFailing",
+			checks:     []check{{"db", passing, nil}, {"cache", failing, nil}},
+			probe:      health.ProbeReadiness,
+			want:       health.StatusNotServing,
+			wantChecks: []string{"db", "cache"},
+		},
+		{
+			name:       "NonCriticalFailing",
+			checks:     []check{{"db", passing, nil}, {"cache", failing, []health.CheckOption{health.NonCritical()}}},
+			probe:      health.ProbeReadiness,
+			want:       health.StatusServing,
+			wantChecks: []string{"db", "cache"},
+		},
+		{
+			name:       "StartupFailing",
+			checks:     []check{{"db", failing, nil}},
+			probe:      health.ProbeStartup,
+			want:       health.StatusNotServing,
+			wantChecks: []string{"db"},
+		},
+		{
+			// checks do not take part in liveness by default
+			name:       "LivenessDefault",
+			checks:     []check{{"db", failing, nil}},
+			probe:      health.ProbeLiveness,
+			want:       health.StatusServing,
+			wantChecks: []string{},
+		},
+		{
+			name: "LivenessFailing",
+			checks: []check{
+				{"db", failing, nil},
+				{"deadlock", failing, []health.CheckOption{health.WithProbes(health.ProbeLiveness)}},
+			},
+			probe:      health.ProbeLiveness,
+			want:       health.StatusNotServing,
+			wantChecks: []string{"deadlock"},
+		},
+		{
+			name: "OtherProbe",
+			checks: []check{
+				{"db", passing, nil},
+				{"deadlock", failing, []health.CheckOption{health.WithProbes(health.ProbeLiveness)}},
+			},
+			probe:      health.ProbeReadiness,
+			want:       health.StatusServing,
+			wantChecks: []string{"db"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := health.NewRegistry()
+			names := []string{}
+			for _, c := range tc.checks {
+				r.Register(c.name, c.fn, c.opts...)
+				names = append(names, c.name)
+			}
+			start(t, r, names...)
+
+			report := r.Report(tc.probe)
+			assert.Equal(t, tc.want, report.Status)
+
+			got := []string{}
+			for _, res := range report.Checks {
+				got = append(got, res.Name)
+			}
+			assert.Equal(t, tc.wantChecks, got)
+		})
+	}
+}
+
+func TestReportNotEvaluated(t *testing.T) {
+	t.Parallel()
+
+	r := health.NewRegistry()
+	r.Register("db", passing)
+	r.Register("cache", passing, health.NonCritical())
+
+	// critical checks that have not been evaluated yet count as failing
+	report := r.Report(health.ProbeReadiness)
+	assert.Equal(t, health.StatusNotServing, report.Status)
+	assert.Equal(t, []health.Result{
+		{Name: "db", Status: health.StatusUnknown, Critical: true},
+		{Name: "cache", Status: health.StatusUnknown, Critical: false},
+	}, report.Checks)
+}
+
+func TestResult(t *testing.T) {
+	t.Parallel()
+
+	r := health.NewRegistry()
+	r.Register("db", passing)
+	r.Register("cache", failing, health.NonCritical())
+	before := time.Now()
+	start(t, r, "db", "cache")
+
+	res, ok := r.Check("db")
+	require.True(t, ok)
+	assert.Equal(t, health.StatusServing, res.Status)
+	assert.True(t, res.Critical)
+	assert.Empty(t, res.Error)
+	assert.False(t, res.CheckedAt.Before(before))
+
+	res, ok = r.Check("cache")
+	require.True(t, ok)
+	assert.Equal(t, health.StatusNotServing, res.Status)
+	assert.False(t, res.Critical)
+	assert.Equal(t, "unhealthy", res.Error)
+
+	_, ok = r.Check("missing")
+	assert.False(t, ok)
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		registry []health.Option
+		check    []health.CheckOption
+		want     string
+	}{
+		{
+			name:  "Check",
+			check: []health.CheckOption{health.WithTimeout(10 * time.Millisecond)},
+			want:  "health check timed out after 10ms: context deadline exceeded",
+		},
+		{
+			name:     "RegistryDefault",
+			registry: []health.Option{health.WithDefaultTimeout(20 * time.Millisecond)},
+			want:     "health check timed out after 20ms: context deadline exceeded",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := health.NewRegistry(tc.registry...)
+			r.Register("slow", func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}, tc.check...)
+			start(t, r, "slow")
+
+			res, _ := r.Check("slow")
+			assert.Equal(t, health.StatusNotServing, res.Status)
+			assert.Equal(t, tc.want, res.Error)
+		})
+	}
+}
+
+func TestInterval(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int64
+	r := health.NewRegistry(health.WithDefaultInterval(time.Hour))
+	r.Register("fast", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}, health.WithInterval(time.Millisecond))
+	r.Register("slow", func(context.Context) error { return nil })
+	start(t, r, "fast", "slow")
+
+	require.Eventually(t, func() bool { return calls.Load() >= 3 }, 5*time.Second, time.Millisecond)
+
+	// the checks are not evaluated after Stop
+	r.Stop()
+	n := calls.Load()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, n, calls.Load())
+}
+
+func TestChanged(t *testing.T) {
+	t.Parallel()
+
+	c := &toggle{}
+	c.healthy.Store(true)
+	r := health.NewRegistry()
+	r.Register("db", c.check, health.WithInterval(time.Millisecond))
+
+	changed := r.Changed()
+	start(t, r, "db")
+	// UNKNOWN to SERVING
+	waitChanged(t, changed)
+	assert.Equal(t, health.StatusServing, r.Report(health.ProbeStartup).Status)
+	assert.Equal(t, health.StatusServing, r.Report(health.ProbeReadiness).Status)
+
+	changed = r.Changed()
+	c.healthy.Store(false)
+	waitChanged(t, changed)
+	assert.Equal(t, health.StatusNotServing, r.Report(health.ProbeReadiness).Status)
+	// startup keeps reporting SERVING once it has
+	assert.Equal(t, health.StatusServing, r.Report(health.ProbeStartup).Status)
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	r := health.NewRegistry()
+	r.Register("db", passing)
+	start(t, r, "db")
+	require.Equal(t, health.StatusServing, r.Report(health.ProbeReadiness).Status)
+	require.False(t, r.Draining())
+
+	changed := r.Changed()
+	r.Drain()
+	waitChanged(t, changed)
+	assert.True(t, r.Draining())
+	assert.Equal(t, health.StatusNotServing, r.Report(health.ProbeReadiness).Status)
+	// the other probes are not affected
+	assert.Equal(t, health.StatusServing, r.Report(health.ProbeStartup).Status)
+	assert.Equal(t, health.StatusServing, r.Report(health.ProbeLiveness).Status)
+
+	// draining again does not notify
+	changed = r.Changed()
+	r.Drain()
+	select {
+	case <-changed:
+		t.Fatal("Drain notified twice")
+	default:
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	t.Parallel()
+
+	r := health.NewRegistry()
+	r.Register("db", passing)
+	assert.PanicsWithValue(t, `health: check "db" registered twice`, func() {
+		r.Register("db", passing)
+	})
+
+	start(t, r, "db")
+	assert.PanicsWithValue(t, "health: Register called after Start", func() {
+		r.Register("cache", passing)
+	})
+}
+
+func TestStatusString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		status health.Status
+		want   string
+	}{
+		{health.StatusUnknown, "UNKNOWN"},
+		{health.StatusServing, "SERVING"},
+		{health.StatusNotServing, "NOT_SERVING"},
+		{health.Status(42), "UNKNOWN"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, tc.status.String())
+	}
+}
@@ -0,0 +1,33 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// RegisterProbes serves the JSON report of the probes on mux: /healthz and
+// /readyz report readiness, /livez liveness and /startupz startup. The status
+// code is 200 if the probe is SERVING and 503 otherwise.
+func RegisterProbes(mux *http.ServeMux, r *Registry) {
+	mux.Handle("GET /healthz", ProbeHandler(r, ProbeReadiness))
+	mux.Handle("GET /readyz", ProbeHandler(r, ProbeReadiness))
+	mux.Handle("GET /livez", ProbeHandler(r, ProbeLiveness))
+	mux.Handle("GET /startupz", ProbeHandler(r, ProbeStartup))
+}
+
+// ProbeHandler returns a handler serving the JSON report of probe.
+func ProbeHandler(r *Registry, probe Probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		report := r.Report(probe)
+
+		code := http.StatusOK
+		if report.Status != StatusServing {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,96 @@
+package health_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/health"
+)
+
+func TestRegisterProbes(t *testing.T) {
+	t.Parallel()
+
+	r := health.NewRegistry()
+	r.Register("db", passing)
+	r.Register("cache", failing, health.NonCritical())
+	r.Register("deadlock", failing, health.WithProbes(health.ProbeLiveness))
+	start(t, r, "db", "cache", "deadlock")
+
+	mux := http.NewServeMux()
+	health.RegisterProbes(mux, r)
+
+	tests := []struct {
+		path       string
+		wantCode   int
+		wantStatus string
+		wantChecks []string
+	}{
+		{"/healthz", http.StatusOK, "SERVING", []string{"db", "cache"}},
+		{"/readyz", http.StatusOK, "SERVING", []string{"db", "cache"}},
+		{"/startupz", http.StatusOK, "SERVING", []string{"db", "cache"}},
+		{"/livez", http.StatusServiceUnavailable, "NOT_SERVING", []string{"deadlock"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+			var report struct {
+				Status string `json:"status"`
+				Checks []struct {
+					Name      string `json:"name"`
+					Status    string `json:"status"`
+					Critical  bool   `json:"critical"`
+					Error     string `json:"error"`
+					Duration  string `json:"duration"`
+					CheckedAt string `json:"checked_at"`
+				} `json:"checks"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+			assert.Equal(t, tc.wantStatus, report.Status)
+
+			names := []string{}
+			for _, c := range report.Checks {
+				names = append(names, c.Name)
+				assert.NotEmpty(t, c.Duration)
+				assert.NotEmpty(t, c.CheckedAt)
+				if c.Name != "db" {
+					assert.Equal(t, "NOT_SERVING", c.Status)
+					assert.Equal(t, "unhealthy", c.Error)
+				}
+			}
+			assert.Equal(t, tc.wantChecks, names)
+		})
+	}
+
+	t.Run("Draining", func(t *testing.T) {
+		t.Parallel()
+
+		r := health.NewRegistry()
+		r.Drain()
+
+		rec := httptest.NewRecorder()
+		health.ProbeHandler(r, health.ProbeReadiness).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.JSONEq(t, `{"status":"NOT_SERVING","checks":[]}`, rec.Body.String())
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		t.Parallel()
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/readyz", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
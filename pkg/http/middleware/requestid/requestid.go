@@ -0,0 +1,46 @@
+// Package requestid assigns every request an ID, available through
+// ilog.RequestIDFromContext and logged as request.id.
+package requestid
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+// Header is the request and response header carrying the request ID.
+const Header = "X-Request-Id"
+
+// maxLength bounds the length of request IDs sent by clients.
+const maxLength = 128
+
+// Handler uses the request ID sent by the client if it is valid, otherwise it
+// generates one. The ID is echoed in the response header.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(ilog.ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+// valid reports whether id is non empty printable ASCII, so that clients
+// cannot inject arbitrary data in the logs.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,66 @@
+package requestid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/http/middleware/requestid"
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		id   string
+		keep bool
+	}{
+		{"UUID", "0f8fad5b-d9cb-469f-a165-70867728950e", true},
+		{"Printable", "req_1:a/b+c=~", true},
+		{"MaxLength", strings.Repeat("a", 128), true},
+		{"Missing", "", false},
+		{"TooLong", strings.Repeat("a", 129), false},
+		{"Space", "request 1", false},
+		{"Newline", "request-1\n{\"level\":\"ERROR\"}", false},
+		{"Tab", "request\t1", false},
+		{"Control", "request\x001", false},
+		{"Delete", "request\x7f", false},
+		{"NonASCII", "requête", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			h := requestid.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				var ok bool
+				got, ok = ilog.RequestIDFromContext(r.Context())
+				assert.True(t, ok)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.id != "" {
+				req.Header[requestid.Header] = []string{tc.id}
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, got, rec.Header().Get(requestid.Header))
+			if tc.keep {
+				assert.Equal(t, tc.id, got)
+				return
+			}
+			// replaced by a generated ID rather than logged
+			_, err := uuid.Parse(got)
+			require.NoError(t, err)
+			assert.NotEqual(t, tc.id, got)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package idgen abstracts the generation of resource IDs so that code
+// creating them can be tested deterministically.
+package idgen
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/FotiadisM/service-template/pkg/clock"
+)
+
+type Generator interface {
+	NewID() (uuid.UUID, error)
+}
+
+type uuidV7 struct{}
+
+func (uuidV7) NewID() (uuid.UUID, error) {
+	return uuid.NewV7()
+}
+
+// UUIDv7 returns a Generator of random, time ordered UUIDv7s.
+func UUIDv7() Generator {
+	return uuidV7{}
+}
+
+// Seeded is a Generator of UUIDv7s whose random bits come from a seeded
+// source, and whose timestamp comes from a Clock. Two generators with the same
+// seed and clock readings return the same IDs. It is safe for concurrent use.
+type Seeded struct {
+	clock clock.Clock
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+var _ Generator = (*Seeded)(nil)
+
+func NewSeeded(seed uint64, clock clock.Clock) *Seeded {
+	return &Seeded{
+		clock: clock,
+		rnd:   rand.New(rand.NewPCG(seed, seed)), //nolint:gosec
+	}
+}
+
+func (g *Seeded) NewID() (uuid.UUID, error) {
+	var id uuid.UUID
+
+	g.mu.Lock()
+	binary.BigEndian.PutUint64(id[0:8], g.rnd.Uint64())
+	binary.BigEndian.PutUint64(id[8:16], g.rnd.Uint64())
+	g.mu.Unlock()
+
+	// 48 bit big endian unix milliseconds, followed by the version and variant
+	ms := uint64(g.clock.Now().UnixMilli()) //nolint:gosec
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	id[6] = 0x70 | (id[6] & 0x0f)
+	id[8] = 0x80 | (id[8] & 0x3f)
+
+	return id, nil
+}
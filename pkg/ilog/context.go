@@ -5,26 +5,72 @@ import (
 	"log/slog"
 )
 
-type ctxKey struct{}
+type (
+	ctxKey          struct{}
+	ctxAttrsKey     struct{}
+	ctxRequestIDKey struct{}
+	ctxTenantKey    struct{}
+)
 
 func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
 	return context.WithValue(ctx, ctxKey{}, logger)
 }
 
+// FromContext returns the logger stored in ctx, or the default logger. If
+// the logger was created by NewLogger it is bound to ctx, so that records
+// carry the attributes of ctx even when logged without a context.
 func FromContext(ctx context.Context) *slog.Logger {
-	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
-		return log
+	log, ok := ctx.Value(ctxKey{}).(*slog.Logger)
+	if !ok {
+		log = slog.Default()
+	}
+
+	if bound, ok := bindContext(log, ctx); ok {
+		return bound
+	}
+
+	// the handler of log does not read ctx, add its attributes explicitly
+	if attrs := contextAttrs(ctx); len(attrs) > 0 {
+		return log.With(attrs...)
 	}
 
-	return slog.Default()
+	return log
 }
 
+// ContextWithAttrs returns a context carrying attrs, which are added to every
+// record logged with it by a logger created by NewLogger.
 func ContextWithAttrs(ctx context.Context, attrs ...any) context.Context {
-	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
-		log = log.With(attrs...)
-		return context.WithValue(ctx, ctxKey{}, log)
-	}
+	prev := contextAttrs(ctx)
+	merged := make([]any, 0, len(prev)+len(attrs))
+	merged = append(merged, prev...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, ctxAttrsKey{}, merged)
+}
+
+func contextAttrs(ctx context.Context) []any {
+	attrs, _ := ctx.Value(ctxAttrsKey{}).([]any)
+	return attrs
+}
+
+// ContextWithRequestID returns a context carrying the ID of the request it
+// belongs to, logged as request.id.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxRequestIDKey{}, id)
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxRequestIDKey{}).(string)
+	return id, ok
+}
+
+// ContextWithTenant returns a context carrying the tenant a request is made
+// on behalf of, logged as tenant.id.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, ctxTenantKey{}, tenant)
+}
 
-	log := slog.Default().With(attrs...)
-	return context.WithValue(ctx, ctxKey{}, log)
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(ctxTenantKey{}).(string)
+	return tenant, ok
 }
@@ -0,0 +1,138 @@
+package ilog
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceIDKey   = "trace.id"
+	SpanIDKey    = "span.id"
+	RequestIDKey = "request.id"
+	TenantKey    = "tenant.id"
+)
+
+// ContextHandler adds the attributes found in the context of a record: the
+// trace and span IDs of the span, the request ID, the tenant and the
+// attributes of ContextWithAttrs. Like the attributes of the record, they
+// belong to the groups of the logger.
+type ContextHandler struct {
+	next slog.Handler
+	// bound is the context of FromContext, read in addition to the context
+	// of the record.
+	bound context.Context
+}
+
+var _ slog.Handler = (*ContextHandler)(nil)
+
+func NewContextHandler(next slog.Handler) slog.Handler {
+	return &ContextHandler{next: next}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := appendContextAttrs(nil, h.bound)
+	if ctx != h.bound {
+		attrs = appendContextAttrs(attrs, ctx)
+	}
+	if len(attrs) == 0 {
+		return h.next.Handle(ctx, r)
+	}
+
+	r.AddAttrs(dedupAttrs(attrs)...)
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+
+	return &c
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+
+	return &c
+}
+
+func (h *ContextHandler) bind(ctx context.Context) *ContextHandler {
+	c := *h
+	c.bound = ctx
+
+	return &c
+}
+
+func appendContextAttrs(attrs []slog.Attr, ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return attrs
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		attrs = append(attrs,
+			slog.String(TraceIDKey, span.TraceID().String()),
+			slog.String(SpanIDKey, span.SpanID().String()),
+		)
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		attrs = append(attrs, slog.String(RequestIDKey, id))
+	}
+	if tenant, ok := TenantFromContext(ctx); ok {
+		attrs = append(attrs, slog.String(TenantKey, tenant))
+	}
+	if args := contextAttrs(ctx); len(args) > 0 {
+		r := slog.Record{}
+		r.Add(args...)
+		r.Attrs(func(a slog.Attr) bool {
+			attrs = append(attrs, a)
+			return true
+		})
+	}
+
+	return attrs
+}
+
+// dedupAttrs keeps the last attribute of every key.
+func dedupAttrs(attrs []slog.Attr) []slog.Attr {
+	last := make(map[string]int, len(attrs))
+	for i, a := range attrs {
+		last[a.Key] = i
+	}
+	if len(last) == len(attrs) {
+		return attrs
+	}
+
+	deduped := make([]slog.Attr, 0, len(last))
+	for i, a := range attrs {
+		if last[a.Key] == i {
+			deduped = append(deduped, a)
+		}
+	}
+
+	return deduped
+}
+
+// bindContext returns log bound to ctx if its handler was created by
+// NewLogger.
+func bindContext(log *slog.Logger, ctx context.Context) (*slog.Logger, bool) {
+	h, ok := log.Handler().(*levelHandler)
+	if !ok {
+		return nil, false
+	}
+	ch, ok := h.next.(*ContextHandler)
+	if !ok {
+		return nil, false
+	}
+
+	c := *h
+	c.next = ch.bind(ctx)
+
+	return slog.New(&c), true
+}
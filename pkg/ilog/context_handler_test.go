@@ -0,0 +1,124 @@
+package ilog_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+var (
+	traceID = trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+	spanID  = trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+)
+
+type otherKey struct{}
+
+func requestContext() context.Context {
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	ctx = ilog.ContextWithRequestID(ctx, "request-1")
+	ctx = ilog.ContextWithTenant(ctx, "tenant-1")
+
+	return ilog.ContextWithAttrs(ctx, "user.id", "user-1")
+}
+
+// lines returns the JSON records written to buf, keeping their raw line to
+// detect duplicate keys, which decoding hides.
+func lines(t *testing.T, buf *lockedBuffer) (raw []string, records []map[string]any) {
+	t.Helper()
+
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf.buf.Bytes()))
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		raw = append(raw, scanner.Text())
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+
+	return raw, records
+}
+
+func TestContextHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	base := ilog.NewLogger(ilog.WithSinks(ilog.WriterSink(&buf, ilog.FormatJSON, nil)))
+	ctx := requestContext()
+	log := ilog.FromContext(ilog.ContextWithLogger(ctx, base))
+
+	log.InfoContext(ctx, "bound context")
+	log.InfoContext(context.WithValue(ctx, otherKey{}, true), "derived context")
+	// the bound context is read when the call site has none
+	log.Info("no context")
+	log.InfoContext(context.Background(), "background context")
+	log.With("key", "value").WithGroup("group").InfoContext(ctx, "group")
+
+	raw, records := lines(t, &buf)
+	require.Len(t, records, 5)
+	for i, record := range records {
+		for key, want := range map[string]string{
+			ilog.TraceIDKey:   traceID.String(),
+			ilog.SpanIDKey:    spanID.String(),
+			ilog.RequestIDKey: "request-1",
+			ilog.TenantKey:    "tenant-1",
+		} {
+			assert.Equal(t, 1, strings.Count(raw[i], `"`+key+`"`), "%s: %s", record["msg"], key)
+			if record["msg"] == "group" {
+				// like the attributes of the record, they belong to the group
+				assert.Equal(t, want, record["group"].(map[string]any)[key])
+				continue
+			}
+			assert.Equal(t, want, record[key], "%s: %s", record["msg"], key)
+		}
+		assert.Equal(t, 1, strings.Count(raw[i], `"user.id"`), record["msg"])
+	}
+}
+
+func TestContextHandlerCallSiteContext(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	base := ilog.NewLogger(ilog.WithSinks(ilog.WriterSink(&buf, ilog.FormatJSON, nil)))
+	log := ilog.FromContext(ilog.ContextWithLogger(requestContext(), base))
+
+	// the context of the call site wins over the bound one
+	log.InfoContext(ilog.ContextWithRequestID(context.Background(), "request-2"), "other request")
+
+	raw, records := lines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, 1, strings.Count(raw[0], `"`+ilog.RequestIDKey+`"`))
+	assert.Equal(t, "request-2", records[0][ilog.RequestIDKey])
+	assert.Equal(t, traceID.String(), records[0][ilog.TraceIDKey])
+}
+
+func TestFromContextNotNewLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	base := slog.New(newJSONHandler(&buf))
+	ctx := requestContext()
+
+	// only the attributes of ContextWithAttrs are added explicitly
+	ilog.FromContext(ilog.ContextWithLogger(ctx, base)).InfoContext(ctx, "request")
+
+	_, records := lines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "user-1", records[0]["user.id"])
+	assert.NotContains(t, records[0], ilog.RequestIDKey)
+}
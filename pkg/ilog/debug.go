@@ -0,0 +1,104 @@
+package ilog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugHeader is the request header carrying a debug token, which elevates
+// the logs of the request to DEBUG.
+const DebugHeader = "X-Debug-Log"
+
+const debugTokenVersion = "v1"
+
+// MaxDebugTokenTTL is the longest a debug token may be valid for. Tokens
+// expiring later than that from now are rejected, so that a leaked token can
+// not elevate logs for longer.
+const MaxDebugTokenTTL = 24 * time.Hour
+
+var (
+	ErrInvalidDebugToken = errors.New("invalid debug token")
+	ErrExpiredDebugToken = errors.New("expired debug token")
+)
+
+func debugTokenSignature(key []byte, expires string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(debugTokenVersion + "." + expires))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignDebugToken returns a token for DebugHeader that is valid until expires,
+// which should be at most MaxDebugTokenTTL away. Tokens are signed with
+// HMAC-SHA256 and have the form v1.<unix expiry>.<signature>.
+func SignDebugToken(key []byte, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	return debugTokenVersion + "." + exp + "." + debugTokenSignature(key, exp)
+}
+
+// VerifyDebugToken checks that token was signed with key, has not expired and
+// does not expire more than MaxDebugTokenTTL after now.
+func VerifyDebugToken(key []byte, token string, now time.Time) error {
+	if len(key) == 0 {
+		return ErrInvalidDebugToken
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != debugTokenVersion {
+		return ErrInvalidDebugToken
+	}
+
+	expected := debugTokenSignature(key, parts[1])
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return ErrInvalidDebugToken
+	}
+
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrInvalidDebugToken
+	}
+	if now.After(time.Unix(exp, 0)) {
+		return fmt.Errorf("%w at %s", ErrExpiredDebugToken, time.Unix(exp, 0).UTC().Format(time.RFC3339))
+	}
+	if time.Unix(exp, 0).Sub(now) > MaxDebugTokenTTL {
+		return fmt.Errorf("%w: expires more than %s from now", ErrInvalidDebugToken, MaxDebugTokenTTL)
+	}
+
+	return nil
+}
+
+// minLeveler is the lowest of two levels.
+type minLeveler struct {
+	a, b slog.Leveler
+}
+
+func (l minLeveler) Level() slog.Level {
+	return min(l.a.Level(), l.b.Level())
+}
+
+// WithMinLevel returns a logger that logs records of at least level, in
+// addition to those enabled by the level of log. It is meant to elevate the
+// logs of a single request, stored with ContextWithLogger, without affecting
+// other requests.
+//
+// Only loggers created by NewLogger, and the ones derived from them, can be
+// elevated. Any other logger is returned as is and keeps its level.
+func WithMinLevel(log *slog.Logger, level slog.Level) *slog.Logger {
+	h, ok := log.Handler().(*levelHandler)
+	if !ok {
+		return log
+	}
+
+	c := *h
+	c.level = minLeveler{a: h.level, b: level}
+
+	return slog.New(&c)
+}
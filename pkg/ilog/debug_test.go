@@ -0,0 +1,101 @@
+package ilog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+func TestVerifyDebugToken(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("debug-key")
+	now := time.Unix(1_700_000_000, 0)
+	valid := ilog.SignDebugToken(key, now.Add(time.Hour))
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name    string
+		key     []byte
+		token   string
+		wantErr error
+	}{
+		{"Valid", key, valid, nil},
+		{"ExpiresNow", key, ilog.SignDebugToken(key, now), nil},
+		{"MaxTTL", key, ilog.SignDebugToken(key, now.Add(ilog.MaxDebugTokenTTL)), nil},
+		{"Expired", key, ilog.SignDebugToken(key, now.Add(-time.Second)), ilog.ErrExpiredDebugToken},
+		{"TTLTooLong", key, ilog.SignDebugToken(key, now.Add(ilog.MaxDebugTokenTTL+time.Second)), ilog.ErrInvalidDebugToken},
+		{"OtherKey", []byte("other-key"), valid, ilog.ErrInvalidDebugToken},
+		{"EmptyKey", nil, ilog.SignDebugToken(nil, now.Add(time.Hour)), ilog.ErrInvalidDebugToken},
+		{"TamperedMAC", key, parts[0] + "." + parts[1] + "." + strings.Repeat("0", len(parts[2])), ilog.ErrInvalidDebugToken},
+		{"TamperedExpiry", key, parts[0] + "." + "1700000100" + "." + parts[2], ilog.ErrInvalidDebugToken},
+		{"Version", key, "v2." + parts[1] + "." + parts[2], ilog.ErrInvalidDebugToken},
+		{"MissingPart", key, parts[0] + "." + parts[1], ilog.ErrInvalidDebugToken},
+		{"ExtraPart", key, valid + ".x", ilog.ErrInvalidDebugToken},
+		{"Empty", key, "", ilog.ErrInvalidDebugToken},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ilog.VerifyDebugToken(tc.key, tc.token, now)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestSignDebugToken(t *testing.T) {
+	t.Parallel()
+
+	expires := time.Unix(1_700_000_000, 0)
+	token := ilog.SignDebugToken([]byte("key"), expires)
+	assert.Regexp(t, `^v1\.1700000000\.[0-9a-f]{64}$`, token)
+	assert.Equal(t, token, ilog.SignDebugToken([]byte("key"), expires))
+	assert.NotEqual(t, token, ilog.SignDebugToken([]byte("other"), expires))
+}
+
+func TestWithMinLevel(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	log := ilog.NewLogger(
+		ilog.WithLogLevel(slog.LevelInfo),
+		ilog.WithSinks(ilog.WriterSink(&buf, ilog.FormatJSON, nil)),
+	)
+
+	ctx := ilog.ContextWithLogger(context.Background(), ilog.WithMinLevel(log, slog.LevelDebug))
+	ilog.FromContext(ctx).Debug("request")
+	ilog.FromContext(ctx).With("key", "value").Debug("request with attrs")
+	// other requests and the logger itself keep their level
+	ilog.FromContext(ilog.ContextWithLogger(context.Background(), log)).Debug("other request")
+	log.Debug("logger")
+	// the level of the logger still applies when it is lower
+	ilog.WithMinLevel(log, slog.LevelError).Info("lower")
+
+	assert.Equal(t, []string{"request", "request with attrs", "lower"}, buf.records(t))
+}
+
+func TestWithMinLevelNotNewLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	// loggers not created by NewLogger are returned as is
+	elevated := ilog.WithMinLevel(log, slog.LevelDebug)
+	assert.Same(t, log, elevated)
+	elevated.Debug("debug")
+	assert.Empty(t, buf.String())
+}
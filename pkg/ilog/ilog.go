@@ -1,11 +1,6 @@
 package ilog
 
-import (
-	"log/slog"
-
-	"github.com/lmittmann/tint"
-	"go.opentelemetry.io/contrib/bridges/otelslog"
-)
+import "log/slog"
 
 func Err(err error) slog.Attr {
 	return slog.String("error", err.Error())
@@ -40,28 +35,50 @@ func NewLogger(opts ...Option) *slog.Logger {
 		return a
 	}
 
+	levels := options.Levels
+	if levels == nil {
+		levels = NewLevels(options.LogLevel)
+	}
+
+	// filtering happens in the levelHandler wrapping the handlers
 	handlerOptions := &slog.HandlerOptions{
-		Level:       options.LogLevel,
+		Level:       allLevels,
 		AddSource:   options.AddSource,
 		ReplaceAttr: replaceAttrFunc,
 	}
 
-	var logger *slog.Logger
-	if options.JSON {
-		logger = slog.New(NewFanoutHandler(
-			otelslog.NewHandler("book-svc", otelslog.WithSource(options.AddSource)),
-			slog.NewJSONHandler(options.Writer, handlerOptions),
-		))
-	} else {
-		logger = slog.New(NewFanoutHandler(
-			otelslog.NewHandler("book-svc", otelslog.WithSource(options.AddSource)),
-			tint.NewHandler(options.Writer, &tint.Options{
-				Level:       options.LogLevel,
-				AddSource:   options.AddSource,
-				ReplaceAttr: replaceAttrFunc,
-			}),
-		))
+	sinks := options.Sinks
+	if len(sinks) == 0 {
+		format := FormatPretty
+		if options.JSON {
+			format = FormatJSON
+		}
+		sinks = []Sink{WriterSink(options.Writer, format, nil)}
+	}
+
+	handlers := make([]slog.Handler, 0, len(sinks))
+	for _, sink := range sinks {
+		handlers = append(handlers, sink.newHandler(handlerOptions))
 	}
+	handler := NewFanoutHandler(handlers...)
+
+	// redacting before the fanout covers both the local and the otel handlers
+	if options.Redactor != nil {
+		handler = NewRedactHandler(handler, options.Redactor)
+	}
+	// dropping records before redaction saves redacting them
+	if options.DedupWindow > 0 {
+		handler = NewDedupHandler(handler, options.DedupWindow)
+	}
+	if options.SampleInitial > 0 && options.SampleInterval > 0 {
+		handler = NewSampleHandler(handler, options.SampleInterval, options.SampleInitial, options.SampleThereafter)
+	}
+
+	logger := slog.New(&levelHandler{
+		next:   NewContextHandler(handler),
+		levels: levels,
+		level:  levels.Leveler(""),
+	})
 
 	if len(options.Tags) > 0 {
 		group := []any{}
@@ -73,3 +90,25 @@ func NewLogger(opts ...Option) *slog.Logger {
 
 	return logger
 }
+
+// Close passes on the records a logger created by NewLogger holds back for
+// deduplication and stops holding them back, see DedupHandler.Close. It must
+// be called before closing the sinks of the logger.
+func Close(log *slog.Logger) {
+	h := log.Handler()
+	for {
+		switch v := h.(type) {
+		case *levelHandler:
+			h = v.next
+		case *ContextHandler:
+			h = v.next
+		case *SampleHandler:
+			h = v.next
+		case *DedupHandler:
+			v.Close()
+			return
+		default:
+			return
+		}
+	}
+}
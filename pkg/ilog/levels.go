@@ -0,0 +1,295 @@
+package ilog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoggerKey is the attribute naming the sub-logger a record is logged with.
+const LoggerKey = "logger"
+
+// allLevels is the level of the handlers wrapped by a levelHandler, which
+// does the filtering itself.
+const allLevels = slog.Level(math.MinInt32)
+
+var ErrInvalidLevel = errors.New("invalid log level")
+
+// ParseLevel parses a level name, like debug or WARN, or a number.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err == nil {
+		return level, nil
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(s, "%d", &n); err == nil && fmt.Sprint(n) == s {
+		return slog.Level(n), nil
+	}
+
+	return 0, fmt.Errorf("%q: %w", s, ErrInvalidLevel)
+}
+
+type namedLevel struct {
+	// configured is the level set by configuration, nil follows the root.
+	configured *slog.Level
+	// override is a temporary level that reverts at expiresAt.
+	override  *slog.Level
+	expiresAt time.Time
+	revert    *time.Timer
+
+	effective slog.LevelVar
+}
+
+func (l *namedLevel) resolve(root slog.Level) {
+	switch {
+	case l.override != nil:
+		l.effective.Set(*l.override)
+	case l.configured != nil:
+		l.effective.Set(*l.configured)
+	default:
+		l.effective.Set(root)
+	}
+}
+
+func (l *namedLevel) stopRevert() {
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+	}
+	l.override = nil
+	l.expiresAt = time.Time{}
+}
+
+// Levels holds the level of the root logger and of named sub-loggers. Named
+// loggers follow the root level unless configured otherwise. Levels can be
+// overridden at runtime for a limited time, after which they revert to the
+// configured ones.
+type Levels struct {
+	mu    sync.Mutex
+	root  *namedLevel
+	named map[string]*namedLevel
+}
+
+func NewLevels(root slog.Level) *Levels {
+	l := &Levels{
+		root:  &namedLevel{configured: &root},
+		named: map[string]*namedLevel{},
+	}
+	l.root.resolve(root)
+
+	return l
+}
+
+// get returns the level of name, creating it if needed. The root logger is
+// named "".
+func (l *Levels) get(name string) *namedLevel {
+	if name == "" {
+		return l.root
+	}
+
+	nl, ok := l.named[name]
+	if !ok {
+		nl = &namedLevel{}
+		nl.resolve(l.root.effective.Level())
+		l.named[name] = nl
+	}
+
+	return nl
+}
+
+// Leveler returns the effective level of the logger name, which tracks every
+// later change.
+func (l *Levels) Leveler(name string) slog.Leveler {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return &l.get(name).effective
+}
+
+// resolveLocked recomputes the effective levels after a change.
+func (l *Levels) resolveLocked() {
+	// the root level is always configured
+	l.root.resolve(0)
+
+	root := l.root.effective.Level()
+	for _, nl := range l.named {
+		nl.resolve(root)
+	}
+}
+
+// Configure sets the configured levels, replacing previous ones and
+// cancelling every override. Named loggers missing from named follow root.
+func (l *Levels) Configure(root slog.Level, named map[string]slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.root.stopRevert()
+	l.root.configured = &root
+
+	for _, nl := range l.named {
+		nl.stopRevert()
+		nl.configured = nil
+	}
+	for name, level := range named {
+		l.get(name).configured = &level
+	}
+	l.resolveLocked()
+}
+
+// Set overrides the level of the logger name until ttl elapses. A ttl of zero
+// makes the override permanent, until the next Configure or Reset.
+func (l *Levels) Set(name string, level slog.Level, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	nl := l.get(name)
+	nl.stopRevert()
+	nl.override = &level
+	if ttl > 0 {
+		nl.expiresAt = time.Now().Add(ttl)
+		var revert *time.Timer
+		revert = time.AfterFunc(ttl, func() { l.expire(name, revert) })
+		nl.revert = revert
+	}
+	l.resolveLocked()
+}
+
+// Reset removes the override of the logger name.
+func (l *Levels) Reset(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.get(name).stopRevert()
+	l.resolveLocked()
+}
+
+// expire removes the override of name, unless it was replaced since revert
+// was scheduled.
+func (l *Levels) expire(name string, revert *time.Timer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	nl := l.get(name)
+	if nl.revert != revert {
+		return
+	}
+	nl.stopRevert()
+	l.resolveLocked()
+}
+
+// LevelInfo describes the level of a logger.
+type LevelInfo struct {
+	Name       string      `json:"name"`
+	Level      slog.Level  `json:"level"`
+	Configured *slog.Level `json:"configured,omitempty"`
+	ExpiresAt  time.Time   `json:"expires_at,omitzero"`
+}
+
+// List returns the levels of the root logger, named "", and of every named
+// logger, ordered by name.
+func (l *Levels) List() []LevelInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info := func(name string, nl *namedLevel) LevelInfo {
+		return LevelInfo{
+			Name:       name,
+			Level:      nl.effective.Level(),
+			Configured: nl.configured,
+			ExpiresAt:  nl.expiresAt,
+		}
+	}
+
+	list := []LevelInfo{info("", l.root)}
+	for _, name := range slices.Sorted(maps.Keys(l.named)) {
+		list = append(list, info(name, l.named[name]))
+	}
+
+	return list
+}
+
+// levelHandler filters records by the level of a logger of Levels.
+type levelHandler struct {
+	next   slog.Handler
+	levels *Levels
+	name   string
+	level  slog.Leveler
+}
+
+var _ slog.Handler = (*levelHandler)(nil)
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.name != "" {
+		r.AddAttrs(slog.String(LoggerKey, h.name))
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+
+	return &c
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+
+	return &c
+}
+
+// Named returns a sub-logger of log whose level is set independently, under
+// name, in the Levels of log. Records carry the name in the logger attribute.
+// If log was not created by NewLogger the sub-logger shares its level.
+func Named(log *slog.Logger, name string) *slog.Logger {
+	h, ok := log.Handler().(*levelHandler)
+	if !ok {
+		return log.With(LoggerKey, name)
+	}
+
+	return slog.New(&levelHandler{
+		next:   h.next,
+		levels: h.levels,
+		name:   name,
+		level:  h.levels.Leveler(name),
+	})
+}
+
+// LevelsOf returns the Levels of a logger created by NewLogger.
+func LevelsOf(log *slog.Logger) (*Levels, bool) {
+	h, ok := log.Handler().(*levelHandler)
+	if !ok {
+		return nil, false
+	}
+
+	return h.levels, true
+}
+
+// ParseLevels parses levels of named loggers from a map of logger names to
+// level names, like the LOGGING_LEVELS configuration.
+func ParseLevels(named map[string]string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level, len(named))
+	for name, s := range named {
+		level, err := ParseLevel(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("logger %s: %w", name, err)
+		}
+		levels[name] = level
+	}
+
+	return levels, nil
+}
@@ -0,0 +1,169 @@
+package ilog_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		s       string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"WARN", slog.LevelWarn, false},
+		{"info+2", slog.LevelInfo + 2, false},
+		{"-4", slog.LevelDebug, false},
+		{"12", slog.Level(12), false},
+		{"", 0, true},
+		{"verbose", 0, true},
+		{"1.5", 0, true},
+		{"4x", 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.s, func(t *testing.T) {
+			t.Parallel()
+
+			level, err := ilog.ParseLevel(tc.s)
+			if tc.wantErr {
+				require.ErrorIs(t, err, ilog.ErrInvalidLevel)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, level)
+		})
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	t.Parallel()
+
+	levels, err := ilog.ParseLevels(map[string]string{"db": "debug", "rpc": " warn "})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]slog.Level{"db": slog.LevelDebug, "rpc": slog.LevelWarn}, levels)
+
+	_, err = ilog.ParseLevels(map[string]string{"db": "debug", "rpc": "loud"})
+	require.ErrorIs(t, err, ilog.ErrInvalidLevel)
+	assert.ErrorContains(t, err, "logger rpc")
+}
+
+func TestLevelsFollowRoot(t *testing.T) {
+	t.Parallel()
+
+	levels := ilog.NewLevels(slog.LevelInfo)
+	db := levels.Leveler("db")
+	assert.Equal(t, slog.LevelInfo, db.Level())
+
+	levels.Set("", slog.LevelDebug, 0)
+	assert.Equal(t, slog.LevelDebug, db.Level())
+
+	levels.Configure(slog.LevelWarn, map[string]slog.Level{"rpc": slog.LevelError})
+	assert.Equal(t, slog.LevelWarn, db.Level())
+	assert.Equal(t, slog.LevelError, levels.Leveler("rpc").Level())
+
+	// loggers no longer configured follow the root again
+	levels.Configure(slog.LevelInfo, nil)
+	assert.Equal(t, slog.LevelInfo, levels.Leveler("rpc").Level())
+}
+
+func TestLevelsSet(t *testing.T) {
+	t.Parallel()
+
+	levels := ilog.NewLevels(slog.LevelInfo)
+	levels.Configure(slog.LevelInfo, map[string]slog.Level{"db": slog.LevelWarn})
+	db := levels.Leveler("db")
+
+	levels.Set("db", slog.LevelDebug, 0)
+	assert.Equal(t, slog.LevelDebug, db.Level())
+	// the root level does not change overridden loggers
+	levels.Set("", slog.LevelError, 0)
+	assert.Equal(t, slog.LevelDebug, db.Level())
+
+	levels.Reset("db")
+	assert.Equal(t, slog.LevelWarn, db.Level())
+	levels.Reset("")
+	assert.Equal(t, slog.LevelInfo, levels.Leveler("").Level())
+}
+
+func TestLevelsSetTTL(t *testing.T) {
+	t.Parallel()
+
+	levels := ilog.NewLevels(slog.LevelInfo)
+	db := levels.Leveler("db")
+
+	before := time.Now()
+	levels.Set("db", slog.LevelDebug, 50*time.Millisecond)
+	assert.Equal(t, slog.LevelDebug, db.Level())
+	info := levels.List()[1]
+	assert.Equal(t, "db", info.Name)
+	assert.WithinRange(t, info.ExpiresAt, before.Add(50*time.Millisecond), time.Now().Add(50*time.Millisecond))
+
+	require.Eventually(t, func() bool { return db.Level() == slog.LevelInfo }, time.Second, 5*time.Millisecond)
+	assert.True(t, levels.List()[1].ExpiresAt.IsZero())
+
+	// the revert of a replaced override does not remove the new one
+	levels.Set("db", slog.LevelDebug, 50*time.Millisecond)
+	levels.Set("db", slog.LevelWarn, time.Hour)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, slog.LevelWarn, db.Level())
+}
+
+func TestLevelsConfigureCancelsOverrides(t *testing.T) {
+	t.Parallel()
+
+	levels := ilog.NewLevels(slog.LevelInfo)
+	levels.Set("", slog.LevelDebug, time.Hour)
+	levels.Set("db", slog.LevelError, time.Hour)
+
+	// a reload applies the configured levels, even to overridden loggers
+	levels.Configure(slog.LevelWarn, map[string]slog.Level{"rpc": slog.LevelDebug})
+	assert.Equal(t, []ilog.LevelInfo{
+		{Name: "", Level: slog.LevelWarn, Configured: ptr(slog.LevelWarn)},
+		{Name: "db", Level: slog.LevelWarn},
+		{Name: "rpc", Level: slog.LevelDebug, Configured: ptr(slog.LevelDebug)},
+	}, levels.List())
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestNamed(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	levels := ilog.NewLevels(slog.LevelInfo)
+	log := ilog.NewLogger(ilog.WithLevels(levels), ilog.WithSinks(ilog.WriterSink(&buf, ilog.FormatJSON, nil)))
+	db := ilog.Named(log, "db")
+
+	db.Debug("db debug")
+	levels.Set("", slog.LevelDebug, 0)
+	db.Debug("db follows root")
+
+	levels.Set("db", slog.LevelWarn, 0)
+	db.Info("db info")
+	log.Info("root info")
+
+	assert.Equal(t, []string{"db follows root", "root info"}, buf.records(t))
+}
+
+func TestNamedNotNewLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	log := slog.New(newJSONHandler(&buf))
+
+	// the sub-logger shares the level of log and is only named
+	ilog.Named(log, "db").Debug("db debug")
+	assert.Equal(t, []string{"db debug"}, buf.records(t))
+	assert.Contains(t, buf.buf.String(), `"logger":"db"`)
+}
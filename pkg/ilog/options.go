@@ -13,7 +13,12 @@ type options struct {
 	// slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError
 	LogLevel slog.Level
 
-	// JSON enables structured logging output in json. Should be enabled in production
+	// Levels holds the level of the logger and of its named sub-loggers,
+	// allowing to change them at runtime. Default is NewLevels(LogLevel).
+	Levels *Levels
+
+	// JSON enables structured logging output in json. Should be enabled in production.
+	// Ignored if Sinks are set.
 	JSON bool
 
 	// LogLevelFieldName sets the field name for the log level or severity.
@@ -41,14 +46,33 @@ type options struct {
 	// Default is slog.SourceKey
 	SourceFieldName string
 
-	// Writer is the log writer, default is os.Stdout
+	// Writer is the log writer, default is os.Stdout. Ignored if Sinks are set.
 	Writer io.Writer
 
+	// Sinks are the destinations of the records, each with its own level and
+	// format. Default is a single sink writing to Writer.
+	Sinks []Sink
+
 	// Tags are additional fields included at the root level of all logs.
 	// These can be useful for example the commit hash of a build, or an environment
 	// name like prod/stg/dev
 	Tags map[string]string
 
+	// Redactor redacts sensitive data from every record, before it reaches
+	// any handler. Default redacts DefaultRedactKeys and the matches of
+	// DefaultDetectors with RedactMask, nil disables redaction.
+	Redactor *Redactor
+
+	// SampleInterval, SampleInitial and SampleThereafter sample records, see
+	// NewSampleHandler. Sampling is disabled if SampleInitial is zero.
+	SampleInterval   time.Duration
+	SampleInitial    int
+	SampleThereafter int
+
+	// DedupWindow collapses identical records logged within the window, see
+	// NewDedupHandler. Zero disables deduplication.
+	DedupWindow time.Duration
+
 	// ReplaceAttrsOverride allows to add custom logic to replace attributes
 	// in addition to the default logic set in this package.
 	ReplaceAttrsOverride func(groups []string, a slog.Attr) slog.Attr
@@ -63,6 +87,7 @@ func defaultOptions() *options {
 		TimeFieldFormat:   time.RFC3339,
 		MessageFieldName:  slog.MessageKey,
 		SourceFieldName:   slog.SourceKey,
+		Redactor:          newRedactor(RedactMask, DefaultRedactKeys, DefaultDetectors(), nil),
 	}
 }
 
@@ -74,6 +99,12 @@ func WithLogLevel(lv slog.Level) Option {
 	}
 }
 
+func WithLevels(levels *Levels) Option {
+	return func(o *options) {
+		o.Levels = levels
+	}
+}
+
 func WithJSON(enabled bool) Option {
 	return func(o *options) {
 		o.JSON = enabled
@@ -122,12 +153,38 @@ func WithWrite(w io.Writer) Option {
 	}
 }
 
+func WithSinks(sinks ...Sink) Option {
+	return func(o *options) {
+		o.Sinks = sinks
+	}
+}
+
 func WithTags(tags map[string]string) Option {
 	return func(o *options) {
 		o.Tags = tags
 	}
 }
 
+func WithRedactor(r *Redactor) Option {
+	return func(o *options) {
+		o.Redactor = r
+	}
+}
+
+func WithSampling(interval time.Duration, initial, thereafter int) Option {
+	return func(o *options) {
+		o.SampleInterval = interval
+		o.SampleInitial = initial
+		o.SampleThereafter = thereafter
+	}
+}
+
+func WithDedupWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.DedupWindow = window
+	}
+}
+
 func WitReplaceAttrsOverride(fn func(groups []string, a slog.Attr) slog.Attr) Option {
 	return func(o *options) {
 		o.ReplaceAttrsOverride = fn
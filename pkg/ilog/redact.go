@@ -0,0 +1,330 @@
+package ilog
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+type RedactMode int
+
+const (
+	// RedactMask replaces sensitive values with [REDACTED].
+	RedactMask RedactMode = iota
+	// RedactHash replaces sensitive values with a truncated HMAC-SHA256, so
+	// that records about the same value can still be correlated.
+	RedactHash
+	// RedactDrop removes attributes holding sensitive values. Sensitive parts
+	// of messages are masked.
+	RedactDrop
+)
+
+func (m RedactMode) String() string {
+	switch m {
+	case RedactHash:
+		return "hash"
+	case RedactDrop:
+		return "drop"
+	default:
+		return "mask"
+	}
+}
+
+// ParseRedactMode parses mask, hash or drop.
+func ParseRedactMode(s string) (RedactMode, error) {
+	for _, m := range []RedactMode{RedactMask, RedactHash, RedactDrop} {
+		if strings.EqualFold(s, m.String()) {
+			return m, nil
+		}
+	}
+
+	return RedactMask, fmt.Errorf("invalid redaction mode %q, expected mask, hash or drop", s)
+}
+
+// Secret is a string that is never logged. Its value is redacted according
+// to the redaction mode, and printed as [REDACTED] by handlers that do not
+// redact.
+type Secret string
+
+func (s Secret) LogValue() slog.Value {
+	return slog.AnyValue(secretValue(s))
+}
+
+type secretValue string
+
+func (secretValue) String() string {
+	return redacted
+}
+
+func (secretValue) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// Detector finds sensitive data inside string values.
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Validate filters matches of Pattern, it is optional.
+	Validate func(match string) bool
+}
+
+// DefaultRedactKeys are attribute keys whose values are always redacted.
+var DefaultRedactKeys = []string{
+	"password", "passwd", "secret", "token", "access_token", "refresh_token",
+	"api_key", "apikey", "authorization", "cookie", "set-cookie",
+}
+
+// DefaultDetectors detect bearer tokens, JWTs, emails and card numbers.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		{
+			Name:    "bearer",
+			Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*`),
+		},
+		{
+			Name:    "jwt",
+			Pattern: regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		},
+		{
+			Name:    "email",
+			Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		},
+		{
+			Name:     "card",
+			Pattern:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+			Validate: luhn,
+		},
+	}
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum of card numbers.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+
+	return n >= 13 && sum%10 == 0
+}
+
+// Redactor redacts sensitive attributes and the sensitive parts of strings.
+type Redactor struct {
+	mode      RedactMode
+	keys      []string
+	detectors []Detector
+	hashKey   []byte
+}
+
+// ErrNoHashKey is returned by NewRedactor for RedactHash without a key, as
+// unkeyed hashes of low entropy values, like emails, are easily reversed.
+var ErrNoHashKey = errors.New("the hash redaction mode requires a key")
+
+// NewRedactor creates a Redactor redacting the values of attributes named
+// after keys, case insensitively and at any depth, and the matches of
+// detectors in strings. RedactHash requires a hashKey.
+func NewRedactor(mode RedactMode, keys []string, detectors []Detector, hashKey []byte) (*Redactor, error) {
+	if mode == RedactHash && len(hashKey) == 0 {
+		return nil, ErrNoHashKey
+	}
+
+	return newRedactor(mode, keys, detectors, hashKey), nil
+}
+
+func newRedactor(mode RedactMode, keys []string, detectors []Detector, hashKey []byte) *Redactor {
+	lower := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lower = append(lower, strings.ToLower(k))
+	}
+
+	return &Redactor{mode: mode, keys: lower, detectors: detectors, hashKey: hashKey}
+}
+
+// deniedKey reports whether key, or its last dot separated segment, is in the
+// deny-list, so that rpc.request.metadata.authorization is denied too.
+func (r *Redactor) deniedKey(key string) bool {
+	key = strings.ToLower(key)
+	if i := strings.LastIndexByte(key, '.'); i >= 0 && slices.Contains(r.keys, key[i+1:]) {
+		return true
+	}
+
+	return slices.Contains(r.keys, key)
+}
+
+func (r *Redactor) hash(s string) string {
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(s))
+
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+func (r *Redactor) replacement(s string) string {
+	if r.mode == RedactHash {
+		return r.hash(s)
+	}
+
+	return redacted
+}
+
+// String redacts the sensitive parts of s, and reports whether it found any.
+func (r *Redactor) String(s string) (string, bool) {
+	found := false
+	for _, d := range r.detectors {
+		s = d.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if d.Validate != nil && !d.Validate(match) {
+				return match
+			}
+			found = true
+			if r.mode == RedactHash {
+				return r.hash(match)
+			}
+			return "[REDACTED:" + d.Name + "]"
+		})
+	}
+
+	return s, found
+}
+
+// Attr redacts a, it returns false if a has to be dropped.
+func (r *Redactor) Attr(a slog.Attr) (slog.Attr, bool) {
+	a.Value = a.Value.Resolve()
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := r.Attrs(a.Value.Group())
+		if len(attrs) == 0 && r.mode == RedactDrop {
+			return a, false
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}, true
+	}
+
+	if secret, ok := a.Value.Any().(secretValue); ok && a.Value.Kind() == slog.KindAny {
+		return r.sensitive(a, string(secret))
+	}
+
+	if r.deniedKey(a.Key) {
+		return r.sensitive(a, a.Value.String())
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		s, found := r.String(a.Value.String())
+		if found && r.mode == RedactDrop {
+			return a, false
+		}
+		a.Value = slog.StringValue(s)
+	case slog.KindAny:
+		return r.any(a)
+	}
+
+	return a, true
+}
+
+func (r *Redactor) sensitive(a slog.Attr, value string) (slog.Attr, bool) {
+	if r.mode == RedactDrop {
+		return a, false
+	}
+	a.Value = slog.StringValue(r.replacement(value))
+
+	return a, true
+}
+
+// any redacts errors and string slices, like request headers.
+func (r *Redactor) any(a slog.Attr) (slog.Attr, bool) {
+	switch v := a.Value.Any().(type) {
+	case error:
+		s, found := r.String(v.Error())
+		if !found {
+			return a, true
+		}
+		if r.mode == RedactDrop {
+			return a, false
+		}
+		a.Value = slog.StringValue(s)
+	case []string:
+		values := make([]string, len(v))
+		redactedAny := false
+		for i, s := range v {
+			var found bool
+			values[i], found = r.String(s)
+			redactedAny = redactedAny || found
+		}
+		if redactedAny && r.mode == RedactDrop {
+			return a, false
+		}
+		a.Value = slog.AnyValue(values)
+	}
+
+	return a, true
+}
+
+// Attrs redacts attrs, leaving out the dropped ones.
+func (r *Redactor) Attrs(attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a, ok := r.Attr(a); ok {
+			out = append(out, a)
+		}
+	}
+
+	return out
+}
+
+// RedactHandler redacts the message and attributes of records before passing
+// them to the next handler.
+type RedactHandler struct {
+	next     slog.Handler
+	redactor *Redactor
+}
+
+var _ slog.Handler = (*RedactHandler)(nil)
+
+func NewRedactHandler(next slog.Handler, redactor *Redactor) slog.Handler {
+	return &RedactHandler{next: next, redactor: redactor}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	msg, _ := h.redactor.String(r.Message)
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	redactedRecord := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	redactedRecord.AddAttrs(h.redactor.Attrs(attrs)...)
+
+	return h.next.Handle(ctx, redactedRecord)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &RedactHandler{next: h.next.WithAttrs(h.redactor.Attrs(attrs)), redactor: h.redactor}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
@@ -0,0 +1,295 @@
+package ilog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+func newRedactor(t *testing.T, mode ilog.RedactMode) *ilog.Redactor {
+	t.Helper()
+
+	r, err := ilog.NewRedactor(mode, slices.Concat(ilog.DefaultRedactKeys, []string{"SSN"}), ilog.DefaultDetectors(), []byte("key"))
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestNewRedactorHashKey(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range [][]byte{nil, {}} {
+		_, err := ilog.NewRedactor(ilog.RedactHash, nil, ilog.DefaultDetectors(), key)
+		require.ErrorIs(t, err, ilog.ErrNoHashKey)
+	}
+
+	// the other modes do not need a key
+	for _, mode := range []ilog.RedactMode{ilog.RedactMask, ilog.RedactDrop} {
+		_, err := ilog.NewRedactor(mode, nil, ilog.DefaultDetectors(), nil)
+		require.NoError(t, err, mode)
+	}
+}
+
+func TestParseRedactMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		s       string
+		want    ilog.RedactMode
+		wantErr bool
+	}{
+		{"mask", ilog.RedactMask, false},
+		{"hash", ilog.RedactHash, false},
+		{"DROP", ilog.RedactDrop, false},
+		{"", ilog.RedactMask, true},
+		{"remove", ilog.RedactMask, true},
+	}
+	for _, tc := range tests {
+		got, err := ilog.ParseRedactMode(tc.s)
+		if tc.wantErr {
+			require.Error(t, err, tc.s)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestRedactorString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"Clean", "book created", "book created"},
+		{"Email", "sent to jane.doe+books@example.co.uk today", "sent to [REDACTED:email] today"},
+		{"Emails", "a@example.com, b@example.org", "[REDACTED:email], [REDACTED:email]"},
+		{"Card", "card 4111111111111111 declined", "card [REDACTED:card] declined"},
+		{"CardSpaces", "4111 1111 1111 1111", "[REDACTED:card]"},
+		{"CardDashes", "5500-0000-0000-0004", "[REDACTED:card]"},
+		{"CardInvalidLuhn", "4111111111111112", "4111111111111112"},
+		{"Bearer", "header Bearer abc.DEF-123~+/= sent", "header [REDACTED:bearer] sent"},
+		{"JWT", "token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln", "token [REDACTED:jwt]"},
+
+		// pinned false positives and negatives of the detectors, change them
+		// knowingly
+		{"LocalEmail", "user@localhost", "user@localhost"},
+		{"VersionAt", "module@v1.2.3", "module@v1.2.3"},
+		{"UUID", "01950b20-756a-7056-bd6a-272db29cb3d1", "01950b20-756a-7056-bd6a-272db29cb3d1"},
+		{"Phone", "call +1 555 123 4567", "call +1 555 123 4567"},
+		{"ISBN", "978-0-306-40615-7", "978-0-306-40615-7"},
+		{"TwelveDigits", "411111111116", "411111111116"},
+		{"TimestampPassingLuhn", "at 1700000000004", "at [REDACTED:card]"},
+		{"Zeros", "order 0000000000000", "order [REDACTED:card]"},
+		{"TimestampFailingLuhn", "at 1700000000000", "at 1700000000000"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, found := newRedactor(t, ilog.RedactMask).String(tc.s)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.want != tc.s, found)
+		})
+	}
+}
+
+var hashed = regexp.MustCompile(`^sha256:[0-9a-f]{16}$`)
+
+func TestRedactorHash(t *testing.T) {
+	t.Parallel()
+
+	r := newRedactor(t, ilog.RedactHash)
+	a, found := r.String("a@example.com")
+	require.True(t, found)
+	assert.Regexp(t, hashed, a)
+
+	// the same value hashes the same, so that records can be correlated
+	again, _ := r.String("a@example.com")
+	assert.Equal(t, a, again)
+	b, _ := r.String("b@example.com")
+	assert.NotEqual(t, a, b)
+
+	// the hash depends on the key
+	otherRedactor, err := ilog.NewRedactor(ilog.RedactHash, nil, ilog.DefaultDetectors(), []byte("other"))
+	require.NoError(t, err)
+	other, _ := otherRedactor.String("a@example.com")
+	assert.NotEqual(t, a, other)
+}
+
+// redactRecord logs through a RedactHandler and returns the JSON record,
+// without time and level.
+func redactRecord(t *testing.T, mode ilog.RedactMode, log func(log *slog.Logger)) map[string]any {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log(slog.New(ilog.NewRedactHandler(slog.NewJSONHandler(&buf, nil), newRedactor(t, mode))))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	delete(record, slog.TimeKey)
+	delete(record, slog.LevelKey)
+
+	return record
+}
+
+func TestRedactHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		log  func(log *slog.Logger)
+		want map[string]any
+	}{
+		{
+			name: "DeniedKeys",
+			log: func(log *slog.Logger) {
+				log.Info("login", "password", "hunter2", "Authorization", "Basic dXNlcg==", "ssn", 123, "user", "jane")
+			},
+			want: map[string]any{
+				"msg": "login", "password": "[REDACTED]", "Authorization": "[REDACTED]", "ssn": "[REDACTED]", "user": "jane",
+			},
+		},
+		{
+			name: "DottedKey",
+			log: func(log *slog.Logger) {
+				log.Info("call", "rpc.request.metadata.authorization", "x", "rpc.token_count", 3)
+			},
+			want: map[string]any{
+				"msg": "call", "rpc.request.metadata.authorization": "[REDACTED]", "rpc.token_count": float64(3),
+			},
+		},
+		{
+			name: "Message",
+			log:  func(log *slog.Logger) { log.Info("sent to a@example.com") },
+			want: map[string]any{"msg": "sent to [REDACTED:email]"},
+		},
+		{
+			name: "Detectors",
+			log: func(log *slog.Logger) {
+				log.Info("pay", "note", "card 4111111111111111", "err", errors.New("no user b@example.com"))
+			},
+			want: map[string]any{
+				"msg": "pay", "note": "card [REDACTED:card]", "err": "no user [REDACTED:email]",
+			},
+		},
+		{
+			name: "Headers",
+			log: func(log *slog.Logger) {
+				log.Info("request", "x-forwarded-for", []string{"10.0.0.1", "a@example.com"})
+			},
+			want: map[string]any{
+				"msg": "request", "x-forwarded-for": []any{"10.0.0.1", "[REDACTED:email]"},
+			},
+		},
+		{
+			name: "Secret",
+			log:  func(log *slog.Logger) { log.Info("connect", "dsn", ilog.Secret("postgres://u:p@db")) },
+			want: map[string]any{"msg": "connect", "dsn": "[REDACTED]"},
+		},
+		{
+			name: "Groups",
+			log: func(log *slog.Logger) {
+				log.Info("request", slog.Group("http",
+					slog.Group("headers", "cookie", "session=1", "accept", "*/*"),
+					"client", "a@example.com",
+				))
+			},
+			want: map[string]any{
+				"msg": "request",
+				"http": map[string]any{
+					"headers": map[string]any{"cookie": "[REDACTED]", "accept": "*/*"},
+					"client":  "[REDACTED:email]",
+				},
+			},
+		},
+		{
+			name: "HandlerGroupsAndAttrs",
+			log: func(log *slog.Logger) {
+				log.With("token", "abc").WithGroup("user").With("email", "a@example.com").Info("hello", "secret", "x")
+			},
+			want: map[string]any{
+				"msg":   "hello",
+				"token": "[REDACTED]",
+				"user":  map[string]any{"email": "[REDACTED:email]", "secret": "[REDACTED]"},
+			},
+		},
+		{
+			// the key of a group is not checked, only the keys inside it
+			name: "DeniedGroupKey",
+			log:  func(log *slog.Logger) { log.Info("hello", slog.Group("secret", "a", 1)) },
+			want: map[string]any{"msg": "hello", "secret": map[string]any{"a": float64(1)}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, redactRecord(t, ilog.RedactMask, tc.log))
+		})
+	}
+}
+
+func TestRedactHandlerModes(t *testing.T) {
+	t.Parallel()
+
+	log := func(log *slog.Logger) {
+		log.Info("sent to a@example.com",
+			"password", "hunter2",
+			"dsn", ilog.Secret("postgres://u:p@db"),
+			"email", "a@example.com",
+			"user", "jane",
+			slog.Group("auth", "token", "abc"),
+			slog.Group("client", "name", "app"),
+		)
+	}
+
+	t.Run("Hash", func(t *testing.T) {
+		t.Parallel()
+
+		record := redactRecord(t, ilog.RedactHash, log)
+		assert.Regexp(t, `^sent to sha256:[0-9a-f]{16}$`, record["msg"])
+		assert.Regexp(t, hashed, record["password"])
+		assert.Regexp(t, hashed, record["dsn"])
+		assert.Regexp(t, hashed, record["email"])
+		assert.Regexp(t, hashed, record["auth"].(map[string]any)["token"])
+		assert.Equal(t, "jane", record["user"])
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		t.Parallel()
+
+		// messages are masked, sensitive attributes and emptied groups dropped
+		assert.Equal(t, map[string]any{
+			"msg":    "sent to [REDACTED:email]",
+			"user":   "jane",
+			"client": map[string]any{"name": "app"},
+		}, redactRecord(t, ilog.RedactDrop, log))
+	})
+}
+
+func TestSecret(t *testing.T) {
+	t.Parallel()
+
+	// handlers that do not redact print secrets as [REDACTED] too
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("connect", "dsn", ilog.Secret("postgres://u:p@db"))
+	assert.Contains(t, buf.String(), `"dsn":"[REDACTED]"`)
+	assert.NotContains(t, buf.String(), "postgres")
+
+	buf.Reset()
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("connect", "dsn", ilog.Secret("postgres://u:p@db"))
+	assert.Contains(t, buf.String(), `dsn=[REDACTED]`)
+	assert.NotContains(t, buf.String(), "postgres")
+}
@@ -0,0 +1,269 @@
+package ilog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000000000"
+
+// Rotation configures when a RotatingFile is rotated and which backups are
+// kept.
+type Rotation struct {
+	// MaxSize is the size in bytes after which the file is rotated, zero
+	// disables rotation by size.
+	MaxSize int64
+	// MaxAge is how long a file is written before it is rotated, zero
+	// disables rotation by age.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept, zero keeps all of them.
+	MaxBackups int
+	// Compress compresses rotated files with gzip.
+	Compress bool
+	// OnError is called with the errors of rotating the file while writing,
+	// after which it keeps being appended to, and of compressing and pruning
+	// rotated files, which happen in the background. It may write to the
+	// file. Nil ignores them.
+	OnError func(err error)
+}
+
+// RotatingFile is a log file that is rotated by size and age. Rotated files
+// are renamed after the time of rotation, e.g. app.log becomes
+// app-20060102T150405.000000000.log, and compressed in the background.
+type RotatingFile struct {
+	path     string
+	rotation Rotation
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	// background compresses and prunes rotated files, one at a time
+	background   sync.WaitGroup
+	backgroundMu sync.Mutex
+}
+
+var _ io.WriteCloser = (*RotatingFile)(nil)
+
+// OpenRotatingFile opens path for appending, creating it and its directory
+// if needed.
+func OpenRotatingFile(path string, rotation Rotation) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f := &RotatingFile{path: path, rotation: rotation}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+
+	if f.file == nil {
+		f.mu.Unlock()
+		return 0, os.ErrClosed
+	}
+
+	var rotateErr error
+	if f.shouldRotate(int64(len(p))) {
+		rotateErr = f.rotate()
+		if f.file == nil {
+			f.mu.Unlock()
+			return 0, rotateErr
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	f.mu.Unlock()
+
+	// reported once unlocked, as OnError may log to the file
+	if rotateErr != nil {
+		f.onError(rotateErr)
+	}
+
+	return n, err
+}
+
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.rotation.MaxSize > 0 && f.size+n > f.rotation.MaxSize {
+		return true
+	}
+
+	return f.rotation.MaxAge > 0 && time.Since(f.opened) >= f.rotation.MaxAge
+}
+
+// Rotate rotates the file, regardless of its size and age.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+
+	return f.rotate()
+}
+
+// rotate renames the file to a backup and opens a new one. If that fails, the
+// file is reopened and appended to, and only rotated again once MaxSize bytes
+// are written or MaxAge elapsed. f.file is nil if it could not be reopened.
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to close log file: %w", err), f.reopen())
+	}
+
+	ext := filepath.Ext(f.path)
+	backup := strings.TrimSuffix(f.path, ext) + "-" + time.Now().UTC().Format(backupTimeFormat) + ext
+	if err := os.Rename(f.path, backup); err != nil {
+		return errors.Join(fmt.Errorf("failed to rename log file: %w", err), f.reopen())
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.background.Add(1)
+	go func() {
+		defer f.background.Done()
+
+		f.backgroundMu.Lock()
+		defer f.backgroundMu.Unlock()
+
+		if f.rotation.Compress {
+			if err := compress(backup); err != nil {
+				f.onError(err)
+			}
+		}
+		if err := f.prune(); err != nil {
+			f.onError(fmt.Errorf("failed to prune rotated log files: %w", err))
+		}
+	}()
+
+	return nil
+}
+
+// reopen opens the file after a failed rotation, counting its size from zero
+// so that the rotation is not retried on every write.
+func (f *RotatingFile) reopen() error {
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.size = 0
+
+	return nil
+}
+
+func (f *RotatingFile) onError(err error) {
+	if f.rotation.OnError != nil {
+		f.rotation.OnError(err)
+	}
+}
+
+// compress replaces path with a gzip compressed path.gz.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open rotated log file: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed log file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(dst.Name())
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to compress rotated log file: %w", err)
+	}
+	if err = errors.Join(zw.Close(), dst.Close()); err != nil {
+		return fmt.Errorf("failed to compress rotated log file: %w", err)
+	}
+
+	return os.Remove(path)
+}
+
+// prune removes the oldest rotated files beyond MaxBackups.
+func (f *RotatingFile) prune() error {
+	if f.rotation.MaxBackups <= 0 {
+		return nil
+	}
+
+	ext := filepath.Ext(f.path)
+	backups, err := filepath.Glob(strings.TrimSuffix(f.path, ext) + "-*" + ext + "*")
+	if err != nil {
+		return err
+	}
+	// backup names sort by time of rotation
+	slices.Sort(backups)
+
+	var errs []error
+	for len(backups) > f.rotation.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		backups = backups[1:]
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes the file, waiting for rotated files to be compressed. Writes
+// fail with os.ErrClosed meanwhile, OnError included.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.file == nil {
+		f.mu.Unlock()
+		return os.ErrClosed
+	}
+	err := f.file.Close()
+	f.file = nil
+	f.mu.Unlock()
+
+	f.background.Wait()
+
+	return err
+}
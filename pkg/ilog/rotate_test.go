@@ -0,0 +1,290 @@
+package ilog_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+var backupName = regexp.MustCompile(`^app-\d{8}T\d{6}\.\d{9}\.log(\.gz)?$`)
+
+func openRotatingFile(t *testing.T, rotation ilog.Rotation) (*ilog.RotatingFile, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	f, err := ilog.OpenRotatingFile(filepath.Join(dir, "logs", "app.log"), rotation)
+	require.NoError(t, err)
+
+	return f, filepath.Join(dir, "logs")
+}
+
+// backups returns the names of the rotated files in dir, oldest first.
+func backups(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, e := range entries {
+		if e.Name() != "app.log" {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func write(t *testing.T, w io.Writer, s string) {
+	t.Helper()
+
+	n, err := w.Write([]byte(s))
+	require.NoError(t, err)
+	require.Equal(t, len(s), n)
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	t.Parallel()
+
+	f, dir := openRotatingFile(t, ilog.Rotation{MaxSize: 10})
+	write(t, f, "line 1\n")
+	write(t, f, "line 2\n")
+	write(t, f, "line 3\n")
+	// a write larger than MaxSize goes to an empty file
+	write(t, f, "a much longer line\n")
+	require.NoError(t, f.Close())
+
+	names := backups(t, dir)
+	require.Len(t, names, 3)
+	for _, name := range names {
+		assert.Regexp(t, backupName, name)
+	}
+	assert.Equal(t, "line 1\n", readFile(t, filepath.Join(dir, names[0])))
+	assert.Equal(t, "line 2\n", readFile(t, filepath.Join(dir, names[1])))
+	assert.Equal(t, "line 3\n", readFile(t, filepath.Join(dir, names[2])))
+	assert.Equal(t, "a much longer line\n", readFile(t, filepath.Join(dir, "app.log")))
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	t.Parallel()
+
+	const maxAge = 50 * time.Millisecond
+
+	f, dir := openRotatingFile(t, ilog.Rotation{MaxAge: maxAge})
+	write(t, f, "old\n")
+	write(t, f, "still young\n")
+	assert.Empty(t, backups(t, dir))
+
+	time.Sleep(maxAge)
+	write(t, f, "new\n")
+	require.NoError(t, f.Close())
+
+	names := backups(t, dir)
+	require.Len(t, names, 1)
+	assert.Regexp(t, backupName, names[0])
+	assert.Equal(t, "old\nstill young\n", readFile(t, filepath.Join(dir, names[0])))
+	assert.Equal(t, "new\n", readFile(t, filepath.Join(dir, "app.log")))
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("before\n"), 0o600))
+
+	// the size of the existing file counts towards MaxSize
+	f, err := ilog.OpenRotatingFile(path, ilog.Rotation{MaxSize: 10})
+	require.NoError(t, err)
+	write(t, f, "after\n")
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, "after\n", readFile(t, path))
+	names := backups(t, filepath.Dir(path))
+	require.Len(t, names, 1)
+	assert.Equal(t, "before\n", readFile(t, filepath.Join(filepath.Dir(path), names[0])))
+}
+
+func TestRotatingFileMaxBackups(t *testing.T) {
+	t.Parallel()
+
+	f, dir := openRotatingFile(t, ilog.Rotation{MaxBackups: 2})
+	for _, line := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		write(t, f, line)
+		require.NoError(t, f.Rotate())
+	}
+	require.NoError(t, f.Close())
+
+	// the oldest backups are removed
+	names := backups(t, dir)
+	require.Len(t, names, 2)
+	assert.Equal(t, "3\n", readFile(t, filepath.Join(dir, names[0])))
+	assert.Equal(t, "4\n", readFile(t, filepath.Join(dir, names[1])))
+}
+
+func TestRotatingFileCompress(t *testing.T) {
+	t.Parallel()
+
+	f, dir := openRotatingFile(t, ilog.Rotation{Compress: true, MaxBackups: 2})
+	for _, line := range []string{"1\n", "2\n", "3\n"} {
+		write(t, f, line)
+		require.NoError(t, f.Rotate())
+	}
+	// Close waits for the backups to be compressed
+	require.NoError(t, f.Close())
+
+	names := backups(t, dir)
+	require.Len(t, names, 2)
+	for i, want := range []string{"2\n", "3\n"} {
+		assert.Regexp(t, backupName, names[i])
+		assert.Equal(t, ".gz", filepath.Ext(names[i]))
+
+		file, err := os.Open(filepath.Join(dir, names[i]))
+		require.NoError(t, err)
+		zr, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		data, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+		assert.Equal(t, want, string(data))
+	}
+}
+
+func TestRotatingFileOnError(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	f, dir := openRotatingFile(t, ilog.Rotation{
+		MaxBackups: 1,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	// a directory that matches the backup names and can not be removed
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app-0.log", "keep"), 0o755))
+
+	write(t, f, "line\n")
+	require.NoError(t, f.Rotate())
+	require.NoError(t, f.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "failed to prune rotated log files")
+}
+
+func TestRotatingFileRenameFails(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	f, dir := openRotatingFile(t, ilog.Rotation{
+		MaxSize: 10,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	path := filepath.Join(dir, "app.log")
+
+	// the file was removed from under the writer, it can not be renamed
+	write(t, f, "line 1\n")
+	require.NoError(t, os.Remove(path))
+	require.ErrorContains(t, f.Rotate(), "failed to rename log file")
+	write(t, f, "line 2\n")
+
+	// the failed rotation of a write is reported, the write goes through
+	require.NoError(t, os.Remove(path))
+	write(t, f, "line 3\n")
+	// and the rotation is not retried until MaxSize bytes are written again
+	write(t, f, "4\n")
+	write(t, f, "line 5\n")
+	require.NoError(t, f.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "failed to rename log file")
+
+	names := backups(t, dir)
+	require.Len(t, names, 1)
+	assert.Equal(t, "line 3\n4\n", readFile(t, filepath.Join(dir, names[0])))
+	assert.Equal(t, "line 5\n", readFile(t, path))
+}
+
+func TestRotatingFileCloseWhileReporting(t *testing.T) {
+	t.Parallel()
+
+	var f *ilog.RotatingFile
+	closing := make(chan struct{})
+	writeErr := make(chan error, 1)
+	f, dir := openRotatingFile(t, ilog.Rotation{
+		MaxBackups: 1,
+		// like a logger writing to the file it reports the errors of
+		OnError: func(err error) {
+			<-closing
+			time.Sleep(10 * time.Millisecond)
+			_, err = f.Write([]byte(err.Error() + "\n"))
+			writeErr <- err
+		},
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app-0.log", "keep"), 0o755))
+
+	write(t, f, "line\n")
+	require.NoError(t, f.Rotate())
+
+	closed := make(chan error, 1)
+	go func() { closed <- f.Close() }()
+	close(closing)
+
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Close deadlocked")
+	}
+	// the write happened before or after the file was closed
+	if err := <-writeErr; err != nil {
+		require.ErrorIs(t, err, os.ErrClosed)
+	}
+}
+
+func TestRotatingFileClosed(t *testing.T) {
+	t.Parallel()
+
+	f, _ := openRotatingFile(t, ilog.Rotation{})
+	require.NoError(t, f.Close())
+
+	_, err := f.Write([]byte("line\n"))
+	require.ErrorIs(t, err, os.ErrClosed)
+	require.ErrorIs(t, f.Rotate(), os.ErrClosed)
+	require.ErrorIs(t, f.Close(), os.ErrClosed)
+}
@@ -0,0 +1,296 @@
+package ilog
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RepeatedKey is the attribute holding how many identical records a
+// deduplicated record stands for.
+const RepeatedKey = "repeated"
+
+const meterName = "github.com/FotiadisM/service-template/pkg/ilog"
+
+// droppedCounter counts the records dropped by the sampling and deduplication
+// handlers as log.records.dropped, by reason and level.
+type droppedCounter struct {
+	counter metric.Int64Counter
+}
+
+func newDroppedCounter() droppedCounter {
+	counter, err := otel.Meter(meterName).Int64Counter("log.records.dropped",
+		metric.WithDescription("Number of log records dropped by sampling or deduplication."),
+		metric.WithUnit("{record}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return droppedCounter{counter: counter}
+}
+
+func (c droppedCounter) add(ctx context.Context, n int64, reason string, level slog.Level) {
+	if c.counter == nil || n == 0 {
+		return
+	}
+	c.counter.Add(context.WithoutCancel(ctx), n, metric.WithAttributes(
+		attribute.String("reason", reason),
+		attribute.String("level", level.String()),
+	))
+}
+
+type sampleCount struct {
+	start time.Time
+	n     int
+}
+
+type sampler struct {
+	interval   time.Duration
+	initial    int
+	thereafter int
+	dropped    droppedCounter
+
+	mu     sync.Mutex
+	counts map[string]*sampleCount
+	reset  time.Time
+}
+
+// SampleHandler keeps the first initial records of every message and level in
+// each interval, then one in thereafter. Records of level ERROR and above are
+// always kept.
+type SampleHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+var _ slog.Handler = (*SampleHandler)(nil)
+
+// NewSampleHandler creates a SampleHandler, thereafter of zero drops every
+// record after the initial ones.
+func NewSampleHandler(next slog.Handler, interval time.Duration, initial, thereafter int) slog.Handler {
+	return &SampleHandler{
+		next: next,
+		sampler: &sampler{
+			interval:   interval,
+			initial:    initial,
+			thereafter: thereafter,
+			dropped:    newDroppedCounter(),
+			counts:     map[string]*sampleCount{},
+		},
+	}
+}
+
+func (h *SampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError || h.sampler.keep(r.Time, r.Level.String()+"\x00"+r.Message) {
+		return h.next.Handle(ctx, r)
+	}
+	h.sampler.dropped.add(ctx, 1, "sampled", r.Level)
+
+	return nil
+}
+
+func (s *sampler) keep(now time.Time, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// forget the counters of messages that stopped being logged
+	if now.Sub(s.reset) > 10*s.interval {
+		clear(s.counts)
+		s.reset = now
+	}
+
+	c, ok := s.counts[key]
+	if !ok || now.Sub(c.start) >= s.interval {
+		c = &sampleCount{start: now}
+		s.counts[key] = c
+	}
+	c.n++
+
+	if c.n <= s.initial {
+		return true
+	}
+
+	return s.thereafter > 0 && (c.n-s.initial)%s.thereafter == 0
+}
+
+func (h *SampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SampleHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *SampleHandler) WithGroup(name string) slog.Handler {
+	return &SampleHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+type dedupEntry struct {
+	next     slog.Handler
+	ctx      context.Context
+	record   slog.Record
+	repeated int
+	// seq orders the entries passed on by Flush.
+	seq   uint64
+	timer *time.Timer
+}
+
+type deduper struct {
+	window  time.Duration
+	dropped droppedCounter
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+	seq     uint64
+	closed  bool
+}
+
+// DedupHandler collapses identical records logged within a window. The first
+// record is passed on right away, the following ones are counted and, once
+// the window ends, the last of them is passed on with a repeated attribute.
+// Records differing only by trace, span or request ID are identical.
+type DedupHandler struct {
+	next    slog.Handler
+	deduper *deduper
+	// prefix identifies the attributes and groups of the handler.
+	prefix string
+}
+
+var _ slog.Handler = (*DedupHandler)(nil)
+
+func NewDedupHandler(next slog.Handler, window time.Duration) slog.Handler {
+	return &DedupHandler{
+		next: next,
+		deduper: &deduper{
+			window:  window,
+			dropped: newDroppedCounter(),
+			entries: map[string]*dedupEntry{},
+		},
+	}
+}
+
+func (h *DedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *DedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	key := h.key(r)
+
+	h.deduper.mu.Lock()
+	if h.deduper.closed {
+		h.deduper.mu.Unlock()
+		return h.next.Handle(ctx, r)
+	}
+	if e, ok := h.deduper.entries[key]; ok {
+		e.repeated++
+		e.ctx = ctx
+		e.record = r.Clone()
+		h.deduper.mu.Unlock()
+		return nil
+	}
+	h.deduper.seq++
+	e := &dedupEntry{next: h.next, seq: h.deduper.seq}
+	e.timer = time.AfterFunc(h.deduper.window, func() { h.deduper.flush(key, e) })
+	h.deduper.entries[key] = e
+	h.deduper.mu.Unlock()
+
+	return h.next.Handle(ctx, r)
+}
+
+// Flush ends the window of every record, passing on the repeated ones right
+// away in the order they were first logged.
+func (h *DedupHandler) Flush() {
+	h.deduper.flushAll(false)
+}
+
+// Close flushes the handler, after which records are passed on without
+// deduplication. It must be called before closing the handlers it wraps, so
+// that no window ends after them.
+func (h *DedupHandler) Close() {
+	h.deduper.flushAll(true)
+}
+
+func (d *deduper) flushAll(closed bool) {
+	d.mu.Lock()
+	d.closed = d.closed || closed
+	entries := slices.SortedFunc(maps.Values(d.entries), func(a, b *dedupEntry) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	clear(d.entries)
+	d.mu.Unlock()
+
+	for _, e := range entries {
+		e.timer.Stop()
+		d.pass(e)
+	}
+}
+
+// flush ends the window of key, passing on the last repeated record. It does
+// nothing if e was already flushed.
+func (d *deduper) flush(key string, e *dedupEntry) {
+	d.mu.Lock()
+	if d.entries[key] != e {
+		d.mu.Unlock()
+		return
+	}
+	delete(d.entries, key)
+	d.mu.Unlock()
+
+	d.pass(e)
+}
+
+// pass passes on the last repeated record of e.
+func (d *deduper) pass(e *dedupEntry) {
+	if e.repeated == 0 {
+		return
+	}
+
+	// the summary stands for every repeated record, only the others are dropped
+	d.dropped.add(e.ctx, int64(e.repeated-1), "deduplicated", e.record.Level)
+	e.record.AddAttrs(slog.Int(RepeatedKey, e.repeated))
+	_ = e.next.Handle(context.WithoutCancel(e.ctx), e.record)
+}
+
+func (h *DedupHandler) key(r slog.Record) string {
+	var b strings.Builder
+	b.WriteString(h.prefix)
+	b.WriteString(r.Level.String())
+	b.WriteByte(0)
+	b.WriteString(r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case TraceIDKey, SpanIDKey, RequestIDKey:
+			return true
+		}
+		b.WriteByte(0)
+		b.WriteString(a.String())
+		return true
+	})
+
+	return b.String()
+}
+
+func (h *DedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.prefix)
+	for _, a := range attrs {
+		b.WriteString(a.String())
+		b.WriteByte(0)
+	}
+
+	return &DedupHandler{next: h.next.WithAttrs(attrs), deduper: h.deduper, prefix: b.String()}
+}
+
+func (h *DedupHandler) WithGroup(name string) slog.Handler {
+	return &DedupHandler{next: h.next.WithGroup(name), deduper: h.deduper, prefix: h.prefix + name + ".\x00"}
+}
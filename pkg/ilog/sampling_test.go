@@ -0,0 +1,302 @@
+package ilog_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+// lockedBuffer is written by the timers of the DedupHandler.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records returns the msg and repeated attribute of the JSON records written,
+// as "msg" or "msg x3".
+func (b *lockedBuffer) records(t *testing.T) []string {
+	t.Helper()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	records := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		s := record["msg"].(string)
+		if n, ok := record[ilog.RepeatedKey]; ok {
+			s = fmt.Sprintf("%s x%v", s, n)
+		}
+		records = append(records, s)
+	}
+	require.NoError(t, scanner.Err())
+
+	return records
+}
+
+func newJSONHandler(w *lockedBuffer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+}
+
+func TestSampleHandler(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type entry struct {
+		after time.Duration
+		level slog.Level
+		msg   string
+		n     int
+	}
+	tests := []struct {
+		name       string
+		initial    int
+		thereafter int
+		entries    []entry
+		want       []string
+	}{
+		{
+			name:       "InitialThenThereafter",
+			initial:    2,
+			thereafter: 3,
+			entries:    []entry{{0, slog.LevelInfo, "a", 10}},
+			// the 1st, 2nd, 5th and 8th records are kept
+			want: []string{"a", "a", "a", "a"},
+		},
+		{
+			name:    "NoThereafter",
+			initial: 2,
+			entries: []entry{{0, slog.LevelInfo, "a", 10}},
+			want:    []string{"a", "a"},
+		},
+		{
+			name:    "ErrorsAlwaysKept",
+			initial: 1,
+			entries: []entry{{0, slog.LevelError, "a", 3}, {0, slog.LevelError + 4, "b", 2}},
+			want:    []string{"a", "a", "a", "b", "b"},
+		},
+		{
+			name:    "PerMessageAndLevel",
+			initial: 1,
+			entries: []entry{
+				{0, slog.LevelInfo, "a", 2},
+				{0, slog.LevelWarn, "a", 2},
+				{0, slog.LevelInfo, "b", 2},
+			},
+			want: []string{"a", "a", "b"},
+		},
+		{
+			name:    "NewInterval",
+			initial: 1,
+			entries: []entry{
+				{0, slog.LevelInfo, "a", 2},
+				{999 * time.Millisecond, slog.LevelInfo, "a", 1},
+				{time.Second, slog.LevelInfo, "a", 2},
+				// the counters are forgotten after ten intervals
+				{time.Hour, slog.LevelInfo, "a", 2},
+			},
+			want: []string{"a", "a", "a"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf lockedBuffer
+			h := ilog.NewSampleHandler(newJSONHandler(&buf), time.Second, tc.initial, tc.thereafter)
+			for _, e := range tc.entries {
+				for range e.n {
+					r := slog.NewRecord(start.Add(e.after), e.level, e.msg, 0)
+					require.NoError(t, h.Handle(context.Background(), r))
+				}
+			}
+
+			assert.Equal(t, tc.want, buf.records(t))
+		})
+	}
+}
+
+func TestSampleHandlerShared(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	log := slog.New(ilog.NewSampleHandler(newJSONHandler(&buf), time.Hour, 1, 0))
+
+	// loggers derived from the same handler share its counters
+	log.Info("a")
+	log.With("key", "value").Info("a")
+	log.WithGroup("group").Info("a")
+	log.Info("b")
+
+	assert.Equal(t, []string{"a", "b"}, buf.records(t))
+}
+
+// newDedupHandler returns a DedupHandler whose windows only end when flushed.
+func newDedupHandler(buf *lockedBuffer) *ilog.DedupHandler {
+	return ilog.NewDedupHandler(newJSONHandler(buf), time.Hour).(*ilog.DedupHandler)
+}
+
+func TestDedupHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		log  func(log *slog.Logger)
+		want []string
+	}{
+		{
+			name: "Single",
+			log:  func(log *slog.Logger) { log.Info("a") },
+			want: []string{"a"},
+		},
+		{
+			name: "Repeated",
+			log: func(log *slog.Logger) {
+				for range 4 {
+					log.Info("a")
+				}
+				log.Info("b")
+				log.Info("b")
+			},
+			// flushed in the order the records were first logged
+			want: []string{"a", "b", "a x3", "b x1"},
+		},
+		{
+			name: "DifferentAttributes",
+			log: func(log *slog.Logger) {
+				log.Info("a", "n", 1)
+				log.Info("a", "n", 2)
+				log.Warn("a", "n", 1)
+				log.Info("a", "n", 1)
+			},
+			want: []string{"a", "a", "a", "a x1"},
+		},
+		{
+			name: "IgnoredAttributes",
+			log: func(log *slog.Logger) {
+				log.Info("a", ilog.RequestIDKey, "1", ilog.TraceIDKey, "1", ilog.SpanIDKey, "1")
+				log.Info("a", ilog.RequestIDKey, "2", ilog.TraceIDKey, "2", ilog.SpanIDKey, "2")
+			},
+			want: []string{"a", "a x1"},
+		},
+		{
+			name: "HandlerAttributes",
+			log: func(log *slog.Logger) {
+				log.With("n", 1).Info("a")
+				log.With("n", 2).Info("a")
+				log.WithGroup("n").Info("a")
+				log.With("n", 1).Info("a")
+			},
+			want: []string{"a", "a", "a", "a x1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf lockedBuffer
+			h := newDedupHandler(&buf)
+			tc.log(slog.New(h))
+			h.Flush()
+
+			assert.Equal(t, tc.want, buf.records(t))
+		})
+	}
+}
+
+func TestDedupHandlerFlush(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	h := newDedupHandler(&buf)
+	log := slog.New(h)
+
+	log.Info("a")
+	log.Info("a")
+	h.Flush()
+	// a new window starts after a flush
+	log.Info("a")
+	log.Info("a")
+	h.Flush()
+	h.Flush()
+
+	assert.Equal(t, []string{"a", "a x1", "a", "a x1"}, buf.records(t))
+}
+
+func TestDedupHandlerClose(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	h := newDedupHandler(&buf)
+	log := slog.New(h)
+
+	log.Info("a")
+	log.Info("a")
+	h.Close()
+	// records are passed on right away once closed
+	log.Info("a")
+	log.Info("a")
+	h.Flush()
+
+	assert.Equal(t, []string{"a", "a x1", "a", "a"}, buf.records(t))
+}
+
+func TestDedupHandlerWindow(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	log := slog.New(ilog.NewDedupHandler(newJSONHandler(&buf), 10*time.Millisecond))
+
+	log.Info("a")
+	log.Info("a")
+	log.Info("a")
+
+	// the condition runs in another goroutine, where records can not fail t
+	require.Eventually(t, func() bool {
+		buf.mu.Lock()
+		defer buf.mu.Unlock()
+		return bytes.Count(buf.buf.Bytes(), []byte("\n")) == 2
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"a", "a x2"}, buf.records(t))
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	log := ilog.NewLogger(
+		ilog.WithDedupWindow(time.Hour),
+		ilog.WithSampling(time.Hour, 10, 0),
+		ilog.WithSinks(ilog.WriterSink(&buf, ilog.FormatJSON, nil)),
+		ilog.WithTags(map[string]string{"env": "test"}),
+	)
+
+	log.Info("a")
+	log.Info("a")
+	ilog.Close(log)
+	assert.Equal(t, []string{"a", "a x1"}, buf.records(t))
+
+	// loggers without deduplication are left as they are
+	ilog.Close(ilog.NewLogger(ilog.WithSinks(ilog.WriterSink(&buf, ilog.FormatJSON, nil))))
+	ilog.Close(slog.New(slog.DiscardHandler))
+}
@@ -0,0 +1,148 @@
+package ilog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/lmittmann/tint"
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+)
+
+// Format is the output format of a sink.
+type Format int
+
+const (
+	// FormatPretty is a colored, human readable format for terminals.
+	FormatPretty Format = iota
+	// FormatText is the logfmt like format of slog.TextHandler.
+	FormatText
+	// FormatJSON is the format of slog.JSONHandler.
+	FormatJSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatText:
+		return "text"
+	case FormatJSON:
+		return "json"
+	default:
+		return "pretty"
+	}
+}
+
+// ParseFormat parses pretty, text or json.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range []Format{FormatPretty, FormatText, FormatJSON} {
+		if strings.EqualFold(s, f.String()) {
+			return f, nil
+		}
+	}
+
+	return FormatPretty, fmt.Errorf("invalid log format %q, expected pretty, text or json", s)
+}
+
+func newFormatHandler(w io.Writer, format Format, opts *slog.HandlerOptions) slog.Handler {
+	switch format {
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts)
+	case FormatText:
+		return slog.NewTextHandler(w, opts)
+	default:
+		return tint.NewHandler(w, &tint.Options{
+			Level:       opts.Level,
+			AddSource:   opts.AddSource,
+			ReplaceAttr: opts.ReplaceAttr,
+		})
+	}
+}
+
+// Sink is a destination of the records of a logger.
+type Sink struct {
+	// Level is the minimum level of the records written to the sink. The
+	// level of the logger applies first, nil writes every record it logs.
+	Level slog.Leveler
+
+	handler func(opts *slog.HandlerOptions) slog.Handler
+	closer  io.Closer
+}
+
+// Close releases the resources of the sink, like its file.
+func (s Sink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
+
+func (s Sink) newHandler(opts *slog.HandlerOptions) slog.Handler {
+	h := s.handler(opts)
+	if s.Level == nil {
+		return h
+	}
+
+	return &sinkLevelHandler{next: h, level: s.Level}
+}
+
+// WriterSink writes records to w in format.
+func WriterSink(w io.Writer, format Format, level slog.Leveler) Sink {
+	return Sink{
+		Level: level,
+		handler: func(opts *slog.HandlerOptions) slog.Handler {
+			return newFormatHandler(w, format, opts)
+		},
+	}
+}
+
+// FileSink writes records to a RotatingFile at path. The file is closed by
+// Sink.Close.
+func FileSink(path string, rotation Rotation, format Format, level slog.Leveler) (Sink, error) {
+	f, err := OpenRotatingFile(path, rotation)
+	if err != nil {
+		return Sink{}, err
+	}
+
+	s := WriterSink(f, format, level)
+	s.closer = f
+
+	return s, nil
+}
+
+// OTelSink sends records to the global OpenTelemetry logger provider, under
+// the instrumentation scope name.
+func OTelSink(name string, level slog.Leveler, opts ...otelslog.Option) Sink {
+	return Sink{
+		Level: level,
+		handler: func(o *slog.HandlerOptions) slog.Handler {
+			return otelslog.NewHandler(name, append([]otelslog.Option{otelslog.WithSource(o.AddSource)}, opts...)...)
+		},
+	}
+}
+
+// sinkLevelHandler filters the records of a sink by its level.
+type sinkLevelHandler struct {
+	next  slog.Handler
+	level slog.Leveler
+}
+
+var _ slog.Handler = (*sinkLevelHandler)(nil)
+
+func (h *sinkLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *sinkLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *sinkLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sinkLevelHandler{next: h.next.WithAttrs(attrs), level: h.level}
+}
+
+func (h *sinkLevelHandler) WithGroup(name string) slog.Handler {
+	return &sinkLevelHandler{next: h.next.WithGroup(name), level: h.level}
+}
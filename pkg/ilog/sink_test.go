@@ -0,0 +1,149 @@
+package ilog_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+// messages returns the msg of the JSON records in data.
+func messages(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	msgs := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		msgs = append(msgs, record["msg"].(string))
+	}
+	require.NoError(t, scanner.Err())
+
+	return msgs
+}
+
+func logEveryLevel(log *slog.Logger) {
+	log.Debug("debug")
+	log.Info("info")
+	log.Warn("warn")
+	log.Error("error")
+}
+
+func TestSinkLevels(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		loggerLevel slog.Level
+		sinkLevel   slog.Leveler
+		want        []string
+	}{
+		{"NilFollowsLogger", slog.LevelInfo, nil, []string{"info", "warn", "error"}},
+		{"Higher", slog.LevelInfo, slog.LevelWarn, []string{"warn", "error"}},
+		{"Highest", slog.LevelDebug, slog.LevelError, []string{"error"}},
+		// the level of the logger applies first
+		{"Lower", slog.LevelWarn, slog.LevelDebug, []string{"warn", "error"}},
+		{"LoggerDebug", slog.LevelDebug, nil, []string{"debug", "info", "warn", "error"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			log := ilog.NewLogger(
+				ilog.WithLogLevel(tc.loggerLevel),
+				ilog.WithSinks(ilog.WriterSink(&buf, ilog.FormatJSON, tc.sinkLevel)),
+			)
+			logEveryLevel(log)
+
+			assert.Equal(t, tc.want, messages(t, buf.Bytes()))
+		})
+	}
+}
+
+func TestSinksFilterIndependently(t *testing.T) {
+	t.Parallel()
+
+	var all, warn, dynamic bytes.Buffer
+	var levelVar slog.LevelVar
+	levelVar.Set(slog.LevelError)
+
+	log := ilog.NewLogger(
+		ilog.WithLogLevel(slog.LevelDebug),
+		ilog.WithSinks(
+			ilog.WriterSink(&all, ilog.FormatJSON, nil),
+			ilog.WriterSink(&warn, ilog.FormatJSON, slog.LevelWarn),
+			ilog.WriterSink(&dynamic, ilog.FormatJSON, &levelVar),
+		),
+	)
+	logEveryLevel(log)
+	// sink levels are read for every record
+	levelVar.Set(slog.LevelInfo)
+	log.With("key", "value").WithGroup("group").Info("info again")
+
+	assert.Equal(t, []string{"debug", "info", "warn", "error", "info again"}, messages(t, all.Bytes()))
+	assert.Equal(t, []string{"warn", "error"}, messages(t, warn.Bytes()))
+	assert.Equal(t, []string{"error", "info again"}, messages(t, dynamic.Bytes()))
+}
+
+func TestSinkFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format ilog.Format
+		want   string
+	}{
+		{ilog.FormatJSON, `"msg":"hello"`},
+		{ilog.FormatText, `msg=hello`},
+		{ilog.FormatPretty, `hello`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			log := ilog.NewLogger(ilog.WithSinks(ilog.WriterSink(&buf, tc.format, nil)))
+			log.Info("hello")
+
+			assert.Contains(t, buf.String(), tc.want)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"pretty", "text", "json", "JSON"} {
+		f, err := ilog.ParseFormat(s)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToLower(s), f.String())
+	}
+
+	_, err := ilog.ParseFormat("xml")
+	require.Error(t, err)
+}
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	sink, err := ilog.FileSink(path, ilog.Rotation{}, ilog.FormatJSON, slog.LevelWarn)
+	require.NoError(t, err)
+
+	logEveryLevel(ilog.NewLogger(ilog.WithSinks(sink)))
+	require.NoError(t, sink.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"warn", "error"}, messages(t, data))
+}
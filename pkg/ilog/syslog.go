@@ -0,0 +1,99 @@
+//go:build !windows && !plan9
+
+package ilog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"log/syslog"
+	"strings"
+)
+
+// SyslogSink writes records to the local syslog daemon over the unix socket
+// at path, or over the default socket of the system if path is empty. The
+// severity of the messages follows the level of the records.
+func SyslogSink(path, tag string, format Format, level slog.Leveler) (Sink, error) {
+	w, err := dialSyslog(path, tag)
+	if err != nil {
+		return Sink{}, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	return Sink{
+		Level: level,
+		handler: func(opts *slog.HandlerOptions) slog.Handler {
+			return &syslogHandler{w: w, format: format, opts: opts}
+		},
+		closer: w,
+	}, nil
+}
+
+func dialSyslog(path, tag string) (*syslog.Writer, error) {
+	priority := syslog.LOG_INFO | syslog.LOG_USER
+	if path == "" {
+		return syslog.New(priority, tag)
+	}
+
+	w, err := syslog.Dial("unixgram", path, priority, tag)
+	if err != nil {
+		return syslog.Dial("unix", path, priority, tag)
+	}
+
+	return w, err
+}
+
+// syslogHandler formats every record on its own, to send it with the
+// severity of its level.
+type syslogHandler struct {
+	w      *syslog.Writer
+	format Format
+	opts   *slog.HandlerOptions
+	// with replays the WithAttrs and WithGroup calls on the handler
+	// formatting a record.
+	with []func(slog.Handler) slog.Handler
+}
+
+var _ slog.Handler = (*syslogHandler)(nil)
+
+func (h *syslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.opts.Level == nil || level >= h.opts.Level.Level()
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	handler := newFormatHandler(&buf, h.format, h.opts)
+	for _, with := range h.with {
+		handler = with(handler)
+	}
+	if err := handler.Handle(ctx, r); err != nil {
+		return err
+	}
+
+	msg := strings.TrimSuffix(buf.String(), "\n")
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.w.Err(msg)
+	case r.Level >= slog.LevelWarn:
+		return h.w.Warning(msg)
+	case r.Level >= slog.LevelInfo:
+		return h.w.Info(msg)
+	default:
+		return h.w.Debug(msg)
+	}
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.withFunc(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return h.withFunc(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *syslogHandler) withFunc(fn func(slog.Handler) slog.Handler) slog.Handler {
+	c := *h
+	c.with = append(c.with[:len(c.with):len(c.with)], fn)
+
+	return &c
+}
@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package ilog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// SyslogSink is not supported on this system.
+func SyslogSink(_, _ string, _ Format, _ slog.Leveler) (Sink, error) {
+	return Sink{}, fmt.Errorf("failed to connect to syslog: %w", errors.ErrUnsupported)
+}
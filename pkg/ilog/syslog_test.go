@@ -0,0 +1,122 @@
+//go:build !windows && !plan9
+
+package ilog_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+// syslogMessage matches the messages of log/syslog over a unix socket, which
+// omit the hostname.
+var syslogMessage = regexp.MustCompile(`^<(\d+)>\w{3} [ \d]\d \d{2}:\d{2}:\d{2} (\S+)\[\d+\]: (.*)\n?$`)
+
+// listenSyslog listens on a unixgram socket like a syslog daemon.
+func listenSyslog(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+
+	// socket paths are limited to about 100 bytes, shorter than t.TempDir
+	dir, err := os.MkdirTemp("", "syslog")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, path
+}
+
+// readSyslog reads a message and returns its priority, tag and content.
+func readSyslog(t *testing.T, conn *net.UnixConn) (string, string, string) {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 64<<10)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	m := syslogMessage.FindStringSubmatch(string(buf[:n]))
+	require.NotNil(t, m, "unexpected syslog message %q", buf[:n])
+
+	return m[1], m[2], m[3]
+}
+
+func TestSyslogSink(t *testing.T) {
+	t.Parallel()
+
+	conn, path := listenSyslog(t)
+	sink, err := ilog.SyslogSink(path, "book-svc", ilog.FormatJSON, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { sink.Close() })
+
+	log := ilog.NewLogger(ilog.WithLogLevel(slog.LevelDebug), ilog.WithSinks(sink))
+
+	// the priority is the user facility (1) * 8 + the severity of the level
+	tests := []struct {
+		level    slog.Level
+		priority string
+	}{
+		{slog.LevelError, "11"},
+		{slog.LevelError + 4, "11"},
+		{slog.LevelWarn, "12"},
+		{slog.LevelInfo, "14"},
+		{slog.LevelInfo + 2, "14"},
+		{slog.LevelDebug, "15"},
+	}
+	for _, tc := range tests {
+		log.With("key", "value").WithGroup("group").Log(t.Context(), tc.level, "hello", "n", 1)
+
+		priority, tag, content := readSyslog(t, conn)
+		assert.Equal(t, tc.priority, priority, "level %s", tc.level)
+		assert.Equal(t, "book-svc", tag)
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(content), &record))
+		assert.Equal(t, "hello", record["msg"])
+		assert.Equal(t, tc.level.String(), record["level"])
+		assert.Equal(t, "value", record["key"])
+		assert.Equal(t, map[string]any{"n": float64(1)}, record["group"])
+	}
+}
+
+func TestSyslogSinkLevel(t *testing.T) {
+	t.Parallel()
+
+	conn, path := listenSyslog(t)
+	sink, err := ilog.SyslogSink(path, "book-svc", ilog.FormatText, slog.LevelWarn)
+	require.NoError(t, err)
+	t.Cleanup(func() { sink.Close() })
+
+	logEveryLevel(ilog.NewLogger(ilog.WithLogLevel(slog.LevelDebug), ilog.WithSinks(sink)))
+
+	for _, want := range []struct{ priority, msg string }{{"12", "msg=warn"}, {"11", "msg=error"}} {
+		priority, _, content := readSyslog(t, conn)
+		assert.Equal(t, want.priority, priority)
+		assert.Contains(t, content, want.msg)
+	}
+
+	// nothing else was sent
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err = conn.Read(make([]byte, 1024))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestSyslogSinkUnavailable(t *testing.T) {
+	t.Parallel()
+
+	_, err := ilog.SyslogSink(filepath.Join(t.TempDir(), "missing.sock"), "book-svc", ilog.FormatJSON, nil)
+	require.ErrorContains(t, err, "failed to connect to syslog")
+}
@@ -0,0 +1,270 @@
+// Package lifecycle starts the components of an application in dependency
+// order and stops them gracefully, in reverse order, on shutdown.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/FotiadisM/service-template/pkg/ilog"
+)
+
+var (
+	ErrUnknownDependency = errors.New("unknown dependency")
+	ErrCyclicDependency  = errors.New("cyclic dependency")
+	ErrDuplicateHook     = errors.New("duplicate hook")
+)
+
+// Hook is a component managed by the Manager. All functions are optional.
+type Hook struct {
+	Name string
+	// DependsOn are the names of the hooks that must be started before, and
+	// stopped after, this one.
+	DependsOn []string
+
+	// OnStart must not block, long running work belongs in a goroutine that
+	// reports fatal errors with Manager.Fail.
+	OnStart func(ctx context.Context) error
+	// OnDrain is called when shutdown begins, before the drain period, e.g. to
+	// report NOT_SERVING to load balancers.
+	OnDrain func()
+	// OnStop is called with a context bounded by the shutdown timeout.
+	OnStop func(ctx context.Context) error
+}
+
+type options struct {
+	drainPeriod     time.Duration
+	shutdownTimeout time.Duration
+	signals         []os.Signal
+}
+
+func defaultOptions() *options {
+	return &options{
+		drainPeriod:     0,
+		shutdownTimeout: 5 * time.Second,
+		signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+	}
+}
+
+type Option func(*options)
+
+// WithDrainPeriod sets how long to wait between draining and stopping the
+// hooks, so that load balancers notice the instance is not ready (0).
+func WithDrainPeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.drainPeriod = d
+	}
+}
+
+// WithShutdownTimeout bounds the time all hooks have to stop (5s).
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = d
+	}
+}
+
+// WithSignals sets the signals that trigger a shutdown (SIGINT, SIGTERM).
+func WithSignals(signals ...os.Signal) Option {
+	return func(o *options) {
+		o.signals = signals
+	}
+}
+
+type Manager struct {
+	log  *slog.Logger
+	opts *options
+
+	hooks []Hook
+
+	failOnce sync.Once
+	failErr  error
+	failed   chan struct{}
+}
+
+func New(log *slog.Logger, opts ...Option) *Manager {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Manager{
+		log:    log,
+		opts:   o,
+		failed: make(chan struct{}),
+	}
+}
+
+// Append adds a hook. Hooks without dependencies between them start in the
+// order they are appended.
+func (m *Manager) Append(hook Hook) {
+	m.hooks = append(m.hooks, hook)
+}
+
+// Fail triggers a shutdown because of err, which Run then returns. Only the
+// first error is kept.
+func (m *Manager) Fail(err error) {
+	m.failOnce.Do(func() {
+		m.failErr = err
+		close(m.failed)
+	})
+}
+
+// order sorts the hooks so that every hook comes after its dependencies.
+func (m *Manager) order() ([]Hook, error) {
+	byName := make(map[string]int, len(m.hooks))
+	for i, h := range m.hooks {
+		if _, ok := byName[h.Name]; ok {
+			return nil, fmt.Errorf("%s: %w", h.Name, ErrDuplicateHook)
+		}
+		byName[h.Name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(m.hooks))
+	ordered := make([]Hook, 0, len(m.hooks))
+
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		h := m.hooks[i]
+		path = append(path, h.Name)
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%v: %w", path, ErrCyclicDependency)
+		}
+
+		state[i] = visiting
+		for _, dep := range h.DependsOn {
+			j, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("%s depends on %s: %w", h.Name, dep, ErrUnknownDependency)
+			}
+			if err := visit(j, path); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		ordered = append(ordered, h)
+
+		return nil
+	}
+
+	for i := range m.hooks {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// Run starts the hooks in dependency order and blocks until ctx is done, a
+// shutdown signal is received or Fail is called. It then drains and stops the
+// started hooks in reverse order. The returned error joins the failure that
+// caused the shutdown, if any, with the errors of the stopped hooks.
+func (m *Manager) Run(ctx context.Context) error {
+	hooks, err := m.order()
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, m.opts.signals...)
+	defer signal.Stop(signals)
+
+	started := make([]Hook, 0, len(hooks))
+	for _, h := range hooks {
+		if h.OnStart != nil {
+			if err = h.OnStart(ctx); err != nil {
+				err = fmt.Errorf("failed to start %s: %w", h.Name, err)
+				return errors.Join(err, m.stop(ctx, started))
+			}
+		}
+		started = append(started, h)
+	}
+	m.log.Info("application started")
+
+	var cause error
+	select {
+	case <-ctx.Done():
+	case sig := <-signals:
+		m.log.Info("received signal, shutting down", "signal", sig.String())
+	case <-m.failed:
+		cause = m.failErr
+		m.log.Error("application failed, shutting down", ilog.Err(cause))
+	}
+
+	m.drain(started, signals)
+
+	return errors.Join(cause, m.stop(ctx, started))
+}
+
+// Abort stops the hooks appended so far without having run them, in reverse
+// dependency order. It cleans up resources when the setup of the application
+// fails before Run.
+func (m *Manager) Abort(ctx context.Context) error {
+	hooks, err := m.order()
+	if err != nil {
+		hooks = m.hooks
+	}
+
+	return m.stop(ctx, hooks)
+}
+
+// drain calls the OnDrain hooks and waits for the drain period, which a second
+// signal cuts short.
+func (m *Manager) drain(started []Hook, signals <-chan os.Signal) {
+	for _, h := range slices.Backward(started) {
+		if h.OnDrain != nil {
+			h.OnDrain()
+		}
+	}
+
+	if m.opts.drainPeriod <= 0 {
+		return
+	}
+
+	m.log.Info("draining", "period", m.opts.drainPeriod)
+	timer := time.NewTimer(m.opts.drainPeriod)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-signals:
+		m.log.Warn("received second signal, skipping drain")
+	}
+}
+
+func (m *Manager) stop(ctx context.Context, started []Hook) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.opts.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, h := range slices.Backward(started) {
+		if h.OnStop == nil {
+			continue
+		}
+
+		if err := h.OnStop(ctx); err != nil {
+			m.log.Error("failed to stop "+h.Name, ilog.Err(err))
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", h.Name, err))
+			continue
+		}
+		m.log.Debug("stopped " + h.Name)
+	}
+
+	return errors.Join(errs...)
+}
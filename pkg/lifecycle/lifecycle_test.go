@@ -0,0 +1,387 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FotiadisM/service-template/pkg/lifecycle"
+)
+
+// recorder records the calls of the hooks it creates.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events
+}
+
+func (r *recorder) hook(name string, dependsOn ...string) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name:      name,
+		DependsOn: dependsOn,
+		OnStart: func(context.Context) error {
+			r.record("start " + name)
+			return nil
+		},
+		OnDrain: func() {
+			r.record("drain " + name)
+		},
+		OnStop: func(context.Context) error {
+			r.record("stop " + name)
+			return nil
+		},
+	}
+}
+
+func newManager(opts ...lifecycle.Option) *lifecycle.Manager {
+	// signals are only tested with WithSignals
+	opts = append([]lifecycle.Option{lifecycle.WithSignals(syscall.SIGUSR2)}, opts...)
+	return lifecycle.New(slog.New(slog.DiscardHandler), opts...)
+}
+
+// cancelOnStart is a hook that shuts the manager down once it is started.
+func cancelOnStart(cancel context.CancelFunc, dependsOn ...string) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name:      "cancel",
+		DependsOn: dependsOn,
+		OnStart: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	}
+}
+
+func TestRunOrder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		hooks func(r *recorder) []lifecycle.Hook
+		want  []string
+	}{
+		{
+			name: "AppendOrder",
+			hooks: func(r *recorder) []lifecycle.Hook {
+				return []lifecycle.Hook{r.hook("a"), r.hook("b"), r.hook("c")}
+			},
+			want: []string{
+				"start a", "start b", "start c",
+				"drain c", "drain b", "drain a",
+				"stop c", "stop b", "stop a",
+			},
+		},
+		{
+			name: "Dependencies",
+			hooks: func(r *recorder) []lifecycle.Hook {
+				return []lifecycle.Hook{
+					r.hook("server", "database", "cache"),
+					r.hook("migrations", "database"),
+					r.hook("database", "logging"),
+					r.hook("cache"),
+					r.hook("logging"),
+				}
+			},
+			want: []string{
+				"start logging", "start database", "start cache", "start server", "start migrations",
+				"drain migrations", "drain server", "drain cache", "drain database", "drain logging",
+				"stop migrations", "stop server", "stop cache", "stop database", "stop logging",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var r recorder
+			m := newManager()
+			hooks := tc.hooks(&r)
+			for _, h := range hooks {
+				m.Append(h)
+			}
+			names := make([]string, 0, len(hooks))
+			for _, h := range hooks {
+				names = append(names, h.Name)
+			}
+			// started after every other hook
+			m.Append(cancelOnStart(cancel, names...))
+
+			require.NoError(t, m.Run(ctx))
+			assert.Equal(t, tc.want, r.Events())
+		})
+	}
+}
+
+func TestRunInvalidDependencies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		hooks []lifecycle.Hook
+		want  error
+	}{
+		{
+			name:  "Unknown",
+			hooks: []lifecycle.Hook{{Name: "server", DependsOn: []string{"database"}}},
+			want:  lifecycle.ErrUnknownDependency,
+		},
+		{
+			name:  "Self",
+			hooks: []lifecycle.Hook{{Name: "server", DependsOn: []string{"server"}}},
+			want:  lifecycle.ErrCyclicDependency,
+		},
+		{
+			name: "Cycle",
+			hooks: []lifecycle.Hook{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+			want: lifecycle.ErrCyclicDependency,
+		},
+		{
+			name:  "Duplicate",
+			hooks: []lifecycle.Hook{{Name: "database"}, {Name: "database"}},
+			want:  lifecycle.ErrDuplicateHook,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			started := false
+			m := newManager()
+			for _, h := range tc.hooks {
+				h.OnStart = func(context.Context) error {
+					started = true
+					return nil
+				}
+				m.Append(h)
+			}
+
+			err := m.Run(context.Background())
+			require.ErrorIs(t, err, tc.want)
+			assert.False(t, started)
+		})
+	}
+}
+
+func TestRunStartFailure(t *testing.T) {
+	t.Parallel()
+
+	var r recorder
+	want := errors.New("connection refused")
+	m := newManager()
+	m.Append(r.hook("logging"))
+	m.Append(r.hook("database", "logging"))
+	m.Append(lifecycle.Hook{
+		Name:      "server",
+		DependsOn: []string{"database"},
+		OnStart:   func(context.Context) error { return want },
+		OnStop: func(context.Context) error {
+			r.record("stop server")
+			return nil
+		},
+	})
+	m.Append(r.hook("migrations", "server"))
+
+	err := m.Run(context.Background())
+	require.ErrorIs(t, err, want)
+	assert.ErrorContains(t, err, "failed to start server")
+	// the hooks that started are stopped, without draining
+	assert.Equal(t, []string{"start logging", "start database", "stop database", "stop logging"}, r.Events())
+}
+
+func TestRunFail(t *testing.T) {
+	t.Parallel()
+
+	var r recorder
+	want := errors.New("listener closed")
+	m := newManager()
+	m.Append(r.hook("server"))
+	m.Append(lifecycle.Hook{
+		Name: "worker",
+		OnStart: func(context.Context) error {
+			go func() {
+				m.Fail(want)
+				// only the first failure is returned
+				m.Fail(errors.New("worker stopped"))
+			}()
+			return nil
+		},
+	})
+
+	err := m.Run(context.Background())
+	require.ErrorIs(t, err, want)
+	assert.NotContains(t, err.Error(), "worker stopped")
+	assert.Equal(t, []string{"start server", "drain server", "stop server"}, r.Events())
+}
+
+func TestRunDrainPeriod(t *testing.T) {
+	t.Parallel()
+
+	const period = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var drained, stopped time.Time
+	m := newManager(lifecycle.WithDrainPeriod(period))
+	m.Append(lifecycle.Hook{
+		Name:    "server",
+		OnDrain: func() { drained = time.Now() },
+		OnStop: func(context.Context) error {
+			stopped = time.Now()
+			return nil
+		},
+	})
+	m.Append(cancelOnStart(cancel, "server"))
+
+	require.NoError(t, m.Run(ctx))
+	require.False(t, drained.IsZero())
+	// the server keeps serving while load balancers notice it is not ready
+	assert.GreaterOrEqual(t, stopped.Sub(drained), period)
+}
+
+func TestRunStopErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var r recorder
+	want := errors.New("flush failed")
+	m := newManager()
+	m.Append(r.hook("logging"))
+	m.Append(lifecycle.Hook{
+		Name:      "exporter",
+		DependsOn: []string{"logging"},
+		OnStop:    func(context.Context) error { return want },
+	})
+	m.Append(cancelOnStart(cancel, "exporter"))
+
+	err := m.Run(ctx)
+	require.ErrorIs(t, err, want)
+	assert.ErrorContains(t, err, "failed to stop exporter")
+	// the other hooks are still stopped
+	assert.Contains(t, r.Events(), "stop logging")
+}
+
+func TestRunStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	const timeout = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var loggingErr error
+	m := newManager(lifecycle.WithShutdownTimeout(timeout))
+	m.Append(lifecycle.Hook{
+		Name: "logging",
+		OnStop: func(ctx context.Context) error {
+			loggingErr = ctx.Err()
+			return nil
+		},
+	})
+	m.Append(lifecycle.Hook{
+		Name:      "server",
+		DependsOn: []string{"logging"},
+		OnStop: func(ctx context.Context) error {
+			// the context of Run is canceled, the one of the hooks is not
+			require.NoError(t, ctx.Err())
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	m.Append(cancelOnStart(cancel, "server"))
+
+	start := time.Now()
+	err := m.Run(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "failed to stop server")
+	// the timeout bounds the stop of every hook together
+	assert.Less(t, time.Since(start), 10*timeout)
+	assert.ErrorIs(t, loggingErr, context.DeadlineExceeded)
+}
+
+func TestAbort(t *testing.T) {
+	t.Parallel()
+
+	var r recorder
+	m := newManager()
+	m.Append(r.hook("server", "database"))
+	m.Append(r.hook("database"))
+
+	require.NoError(t, m.Abort(context.Background()))
+	assert.Equal(t, []string{"stop server", "stop database"}, r.Events())
+}
+
+func TestAbortInvalidDependencies(t *testing.T) {
+	t.Parallel()
+
+	var r recorder
+	m := newManager()
+	m.Append(r.hook("database"))
+	m.Append(r.hook("server", "cache"))
+
+	// hooks are stopped in reverse append order
+	require.NoError(t, m.Abort(context.Background()))
+	assert.Equal(t, []string{"stop server", "stop database"}, r.Events())
+}
+
+// TestRunSignals is not parallel, since it sends signals to the test process.
+func TestRunSignals(t *testing.T) {
+	var r recorder
+	m := lifecycle.New(slog.New(slog.DiscardHandler),
+		lifecycle.WithSignals(syscall.SIGUSR1),
+		lifecycle.WithDrainPeriod(time.Minute),
+	)
+	m.Append(lifecycle.Hook{
+		Name: "server",
+		OnStart: func(context.Context) error {
+			return syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+		},
+		OnDrain: func() {
+			r.record("drain server")
+			// a second signal skips the drain period
+			assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+		},
+		OnStop: func(context.Context) error {
+			r.record("stop server")
+			return nil
+		},
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after two signals")
+	}
+	assert.Equal(t, []string{"drain server", "stop server"}, r.Events())
+}
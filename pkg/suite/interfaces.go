@@ -27,3 +27,40 @@ type TearDownAllSuite interface {
 type TearDownTestSuite interface {
 	TearDownTest(t *testing.T)
 }
+
+// SetupSubTest has a SetupSubTest method, which will run before each
+// subtest started with Subtest.
+type SetupSubTest interface {
+	SetupSubTest(t *testing.T)
+}
+
+// TearDownSubTest has a TearDownSubTest method, which will run after
+// each subtest started with Subtest.
+type TearDownSubTest interface {
+	TearDownSubTest(t *testing.T)
+}
+
+// BeforeTest has a BeforeTest method, which will run right before each
+// test, after SetupTest.
+type BeforeTest interface {
+	BeforeTest(t *testing.T, suiteName, testName string)
+}
+
+// AfterTest has an AfterTest method, which will run right after each
+// test, before TearDownTest.
+type AfterTest interface {
+	AfterTest(t *testing.T, suiteName, testName string)
+}
+
+// ParallelSuite has a RunParallel method, which reports whether a test of
+// the suite runs in parallel with the others. Return true for every test to
+// make the whole suite parallel.
+type ParallelSuite interface {
+	RunParallel(testName string) bool
+}
+
+// WithStats has a HandleStats method, which will receive the stats of the
+// suite after all of its tests have run.
+type WithStats interface {
+	HandleStats(suiteName string, stats *SuiteInformation)
+}
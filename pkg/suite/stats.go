@@ -0,0 +1,69 @@
+package suite
+
+import (
+	"sync"
+	"time"
+)
+
+// SuiteInformation holds the stats of a suite run.
+type SuiteInformation struct {
+	Start, End time.Time
+	TestStats  map[string]*TestInformation
+
+	mu sync.Mutex
+}
+
+// TestInformation holds the stats of a single test.
+type TestInformation struct {
+	TestName   string
+	Start, End time.Time
+	Passed     bool
+}
+
+// Duration returns how long the test ran.
+func (i *TestInformation) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+func newSuiteInformation() *SuiteInformation {
+	return &SuiteInformation{
+		Start:     time.Now(),
+		TestStats: map[string]*TestInformation{},
+	}
+}
+
+func (s *SuiteInformation) start(testName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.TestStats[testName] = &TestInformation{
+		TestName: testName,
+		Start:    time.Now(),
+	}
+}
+
+func (s *SuiteInformation) end(testName string, passed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.TestStats[testName]
+	if !ok {
+		return
+	}
+	stats.End = time.Now()
+	stats.Passed = passed
+}
+
+// Passed reports whether every test of the suite passed.
+func (s *SuiteInformation) Passed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stats := range s.TestStats {
+		if !stats.Passed {
+			return false
+		}
+	}
+
+	return true
+}
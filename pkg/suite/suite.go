@@ -8,10 +8,13 @@ import (
 	"regexp"
 	"runtime/debug"
 	"testing"
+	"time"
 )
 
 var matchMethod = flag.String("suite.m", "", "regular expression to select tests of the suite to run")
 
+var testingTType = reflect.TypeOf((*testing.T)(nil))
+
 func failOnPanic(t *testing.T) {
 	t.Helper()
 
@@ -31,6 +34,18 @@ func methodFilter(name string) (bool, error) {
 	return regexp.MatchString(*matchMethod, name)
 }
 
+// checkSignature returns an error if method can not be run as a test.
+func checkSignature(method reflect.Method) error {
+	typ := method.Type
+	// the receiver is the first argument
+	if typ.NumIn() != 2 || typ.In(1) != testingTType || typ.NumOut() != 0 {
+		return fmt.Errorf("suite: method %s has signature %s, expected func(t *testing.T)",
+			method.Name, typ.String())
+	}
+
+	return nil
+}
+
 // Run takes a testing suite and runs all of the tests attached
 // to it.
 func Run(t *testing.T, suite interface{}) {
@@ -38,6 +53,9 @@ func Run(t *testing.T, suite interface{}) {
 
 	var isSetupFinished bool
 
+	suiteName := reflect.Indirect(reflect.ValueOf(suite)).Type().Name()
+	stats := newSuiteInformation()
+
 	tests := []testing.InternalTest{}
 	methodFinder := reflect.TypeOf(suite)
 
@@ -54,6 +72,11 @@ func Run(t *testing.T, suite interface{}) {
 			continue
 		}
 
+		if err = checkSignature(method); err != nil {
+			t.Error(err)
+			continue
+		}
+
 		if !isSetupFinished {
 			if setupAllSuite, ok := suite.(SetupAllSuite); ok {
 				setupAllSuite.SetupSuite(t)
@@ -66,8 +89,17 @@ func Run(t *testing.T, suite interface{}) {
 			F: func(t *testing.T) {
 				t.Helper()
 
+				if parallelSuite, ok := suite.(ParallelSuite); ok && parallelSuite.RunParallel(method.Name) {
+					t.Parallel()
+				}
+
 				defer failOnPanic(t)
 
+				stats.start(method.Name)
+				t.Cleanup(func() {
+					stats.end(method.Name, !t.Failed())
+				})
+
 				if tearDownTestSuite, ok := suite.(TearDownTestSuite); ok {
 					t.Cleanup(func() {
 						tearDownTestSuite.TearDownTest(t)
@@ -78,6 +110,16 @@ func Run(t *testing.T, suite interface{}) {
 					setupTestSuite.SetupTest(t)
 				}
 
+				if afterTestSuite, ok := suite.(AfterTest); ok {
+					t.Cleanup(func() {
+						afterTestSuite.AfterTest(t, suiteName, method.Name)
+					})
+				}
+
+				if beforeTestSuite, ok := suite.(BeforeTest); ok {
+					beforeTestSuite.BeforeTest(t, suiteName, method.Name)
+				}
+
 				method.Func.Call([]reflect.Value{reflect.ValueOf(suite), reflect.ValueOf(t)})
 			},
 		}
@@ -89,13 +131,46 @@ func Run(t *testing.T, suite interface{}) {
 		return
 	}
 
+	// cleanups run after parallel tests have finished
 	if tearDownAllSuite, ok := suite.(TearDownAllSuite); ok {
 		t.Cleanup(func() {
 			tearDownAllSuite.TearDownSuite(t)
 		})
 	}
 
+	if withStats, ok := suite.(WithStats); ok {
+		t.Cleanup(func() {
+			stats.End = time.Now()
+			withStats.HandleStats(suiteName, stats)
+		})
+	}
+
 	for _, test := range tests {
 		t.Run(test.Name, test.F)
 	}
 }
+
+// Subtest runs f as a subtest of t, like t.Run, surrounded by the
+// SetupSubTest and TearDownSubTest hooks of the suite. Use it for table
+// driven tests inside suite methods.
+func Subtest(t *testing.T, suite interface{}, name string, f func(t *testing.T)) bool {
+	t.Helper()
+
+	return t.Run(name, func(t *testing.T) {
+		t.Helper()
+
+		defer failOnPanic(t)
+
+		if tearDownSubTest, ok := suite.(TearDownSubTest); ok {
+			t.Cleanup(func() {
+				tearDownSubTest.TearDownSubTest(t)
+			})
+		}
+
+		if setupSubTest, ok := suite.(SetupSubTest); ok {
+			setupSubTest.SetupSubTest(t)
+		}
+
+		f(t)
+	})
+}
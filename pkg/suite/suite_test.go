@@ -0,0 +1,211 @@
+package suite
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// events records the hooks of a suite, which may run in parallel.
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, event)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.list
+}
+
+type hooksSuite struct {
+	events events
+	stats  *SuiteInformation
+	name   string
+}
+
+func (s *hooksSuite) SetupSuite(*testing.T)    { s.events.add("SetupSuite") }
+func (s *hooksSuite) TearDownSuite(*testing.T) { s.events.add("TearDownSuite") }
+func (s *hooksSuite) SetupTest(*testing.T)     { s.events.add("SetupTest") }
+func (s *hooksSuite) TearDownTest(*testing.T)  { s.events.add("TearDownTest") }
+func (s *hooksSuite) SetupSubTest(*testing.T)  { s.events.add("SetupSubTest") }
+
+func (s *hooksSuite) TearDownSubTest(*testing.T) { s.events.add("TearDownSubTest") }
+
+func (s *hooksSuite) BeforeTest(_ *testing.T, suiteName, testName string) {
+	s.events.add("BeforeTest " + suiteName + " " + testName)
+}
+
+func (s *hooksSuite) AfterTest(_ *testing.T, suiteName, testName string) {
+	s.events.add("AfterTest " + suiteName + " " + testName)
+}
+
+func (s *hooksSuite) HandleStats(suiteName string, stats *SuiteInformation) {
+	s.events.add("HandleStats")
+	s.name = suiteName
+	s.stats = stats
+}
+
+func (s *hooksSuite) TestA(*testing.T) { s.events.add("TestA") }
+
+func (s *hooksSuite) TestB(t *testing.T) {
+	s.events.add("TestB")
+	Subtest(t, s, "Sub", func(*testing.T) {
+		s.events.add("TestB/Sub")
+	})
+}
+
+// NotATest is not run, since its name does not start with Test.
+func (s *hooksSuite) NotATest(*testing.T) { s.events.add("NotATest") }
+
+func TestRunHooks(t *testing.T) {
+	t.Parallel()
+
+	s := &hooksSuite{}
+	t.Run("Suite", func(t *testing.T) {
+		Run(t, s)
+	})
+
+	assert.Equal(t, []string{
+		"SetupSuite",
+		"SetupTest",
+		"BeforeTest hooksSuite TestA",
+		"TestA",
+		"AfterTest hooksSuite TestA",
+		"TearDownTest",
+		"SetupTest",
+		"BeforeTest hooksSuite TestB",
+		"TestB",
+		"SetupSubTest",
+		"TestB/Sub",
+		"TearDownSubTest",
+		"AfterTest hooksSuite TestB",
+		"TearDownTest",
+		"HandleStats",
+		"TearDownSuite",
+	}, s.events.get())
+}
+
+func TestRunStats(t *testing.T) {
+	t.Parallel()
+
+	s := &hooksSuite{}
+	before := time.Now()
+	t.Run("Suite", func(t *testing.T) {
+		Run(t, s)
+	})
+
+	require.NotNil(t, s.stats)
+	assert.Equal(t, "hooksSuite", s.name)
+	assert.True(t, s.stats.Passed())
+	assert.False(t, s.stats.Start.Before(before))
+	assert.False(t, s.stats.End.Before(s.stats.Start))
+
+	require.Len(t, s.stats.TestStats, 2)
+	for _, name := range []string{"TestA", "TestB"} {
+		stats := s.stats.TestStats[name]
+		require.NotNil(t, stats, name)
+		assert.Equal(t, name, stats.TestName)
+		assert.True(t, stats.Passed)
+		assert.GreaterOrEqual(t, stats.Duration(), time.Duration(0))
+		assert.False(t, stats.Start.Before(s.stats.Start))
+		assert.False(t, stats.End.After(s.stats.End))
+	}
+}
+
+func TestSuiteInformationPassed(t *testing.T) {
+	t.Parallel()
+
+	stats := newSuiteInformation()
+	stats.start("TestA")
+	stats.start("TestB")
+	stats.end("TestA", true)
+	// a test that has not ended did not pass
+	assert.False(t, stats.Passed())
+
+	stats.end("TestB", false)
+	assert.False(t, stats.Passed())
+
+	stats.end("TestB", true)
+	assert.True(t, stats.Passed())
+
+	// unknown tests are ignored
+	stats.end("TestC", false)
+	assert.True(t, stats.Passed())
+}
+
+// parallelSuite runs TestA in parallel and TestB sequentially.
+type parallelSuite struct {
+	events events
+}
+
+func (s *parallelSuite) RunParallel(testName string) bool { return testName == "TestA" }
+
+func (s *parallelSuite) TearDownSuite(*testing.T) { s.events.add("TearDownSuite") }
+
+func (s *parallelSuite) TestA(*testing.T) { s.events.add("TestA") }
+func (s *parallelSuite) TestB(*testing.T) { s.events.add("TestB") }
+
+func TestRunParallel(t *testing.T) {
+	t.Parallel()
+
+	s := &parallelSuite{}
+	t.Run("Suite", func(t *testing.T) {
+		Run(t, s)
+		s.events.add("Run")
+	})
+
+	// parallel tests are paused until the function that started them returns,
+	// the suite is torn down once they have finished
+	assert.Equal(t, []string{"TestB", "Run", "TestA", "TearDownSuite"}, s.events.get())
+}
+
+// signatureSuite has methods with the signatures checkSignature accepts and
+// rejects.
+type signatureSuite struct{}
+
+func (signatureSuite) TestValid(*testing.T)                 {}
+func (signatureSuite) TestNoArguments()                     {}
+func (signatureSuite) TestBenchmark(*testing.B)             {}
+func (signatureSuite) TestTooManyArguments(*testing.T, int) {}
+func (signatureSuite) TestResult(*testing.T) error          { return nil }
+
+func TestCheckSignature(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		method  string
+		wantErr string
+	}{
+		{"TestValid", ""},
+		{"TestNoArguments", "suite: method TestNoArguments has signature func(suite.signatureSuite), expected func(t *testing.T)"},
+		{"TestBenchmark", "suite: method TestBenchmark has signature func(suite.signatureSuite, *testing.B), expected func(t *testing.T)"},
+		{"TestTooManyArguments", "suite: method TestTooManyArguments has signature func(suite.signatureSuite, *testing.T, int), expected func(t *testing.T)"},
+		{"TestResult", "suite: method TestResult has signature func(suite.signatureSuite, *testing.T) error, expected func(t *testing.T)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.method, func(t *testing.T) {
+			t.Parallel()
+
+			method, ok := reflect.TypeOf(signatureSuite{}).MethodByName(tc.method)
+			require.True(t, ok)
+
+			err := checkSignature(method)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
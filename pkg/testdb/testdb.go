@@ -0,0 +1,286 @@
+// Package testdb provides every test with its own PostgreSQL database, cloned
+// from a template that is migrated once.
+//
+// The server is the one at the TESTDB_URL connection URI if set, otherwise a
+// testcontainers PostgreSQL container is started. Tests without a server fail,
+// unless TESTDB_SKIP is set.
+package testdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"github.com/FotiadisM/service-template/internal/database/migrate"
+)
+
+// EnvURL is the environment variable holding the connection URI of an already
+// running PostgreSQL server. The user must be allowed to create databases.
+const EnvURL = "TESTDB_URL"
+
+// EnvSkip is the environment variable that makes SkipUnavailable skip tests
+// when no PostgreSQL server is available, instead of failing them.
+const EnvSkip = "TESTDB_SKIP"
+
+// ErrUnavailable is returned by New if TESTDB_URL is not set and Docker is
+// not available either.
+var ErrUnavailable = errors.New("no PostgreSQL server available")
+
+// SkipUnavailable skips t if err is ErrUnavailable and TESTDB_SKIP is set.
+// Other errors are left to the caller, so a missing server fails by default.
+func SkipUnavailable(t testing.TB, err error) {
+	t.Helper()
+
+	if errors.Is(err, ErrUnavailable) && os.Getenv(EnvSkip) != "" {
+		t.Skipf("testdb: %v", err)
+	}
+}
+
+// SetupFunc prepares the template database, e.g. by loading fixtures.
+type SetupFunc func(ctx context.Context, conn *pgx.Conn) error
+
+type options struct {
+	image      string
+	migrations fs.FS
+	setup      []SetupFunc
+}
+
+func defaultOptions() *options {
+	return &options{
+		image: "postgres:15.1-alpine",
+	}
+}
+
+type Option func(*options)
+
+// WithImage sets the container image used when TESTDB_URL is not set (postgres:15.1-alpine).
+func WithImage(image string) Option {
+	return func(o *options) {
+		o.image = image
+	}
+}
+
+// WithMigrations applies the atlas migration directory fsys to the template,
+// the same way the service does with migrate.Migrator.
+func WithMigrations(fsys fs.FS) Option {
+	return func(o *options) {
+		o.migrations = fsys
+	}
+}
+
+// WithSetup runs fn against the template after the migrations are applied.
+func WithSetup(fn SetupFunc) Option {
+	return func(o *options) {
+		o.setup = append(o.setup, fn)
+	}
+}
+
+type Server struct {
+	root      *pgxpool.Pool
+	rootURL   *url.URL
+	container *postgres.PostgresContainer
+
+	template string
+	clones   atomic.Int64
+	// cloneMu serializes clones, as CREATE DATABASE fails if the template
+	// is accessed by another CREATE DATABASE at the same time.
+	cloneMu sync.Mutex
+}
+
+// New connects to the PostgreSQL server and creates the template database.
+func New(ctx context.Context, opts ...Option) (*Server, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	s := &Server{}
+	if err := s.start(ctx, o); err != nil {
+		_ = s.Close(ctx)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Server) start(ctx context.Context, o *options) error {
+	connString := os.Getenv(EnvURL)
+	if connString == "" {
+		if !dockerAvailable(ctx) {
+			return fmt.Errorf("%w: set %s or start Docker", ErrUnavailable, EnvURL)
+		}
+
+		container, err := postgres.Run(ctx, o.image,
+			postgres.WithUsername("postgres"),
+			postgres.WithPassword("postgres"),
+			postgres.WithDatabase("postgres"),
+			postgres.BasicWaitStrategies(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to start postgres container: %w", err)
+		}
+		s.container = container
+
+		connString, err = container.ConnectionString(ctx, "sslmode=disable")
+		if err != nil {
+			return fmt.Errorf("failed to get container connection string: %w", err)
+		}
+	}
+
+	var err error
+	s.rootURL, err = url.Parse(connString)
+	if err != nil {
+		return fmt.Errorf("invalid connection URI: %w", err)
+	}
+
+	s.root, err = pgxpool.New(ctx, connString)
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	// databases of packages tested in parallel against the same server must not clash
+	s.template = "testdb_" + randomSuffix()
+	return s.createTemplate(ctx, o)
+}
+
+func (s *Server) createTemplate(ctx context.Context, o *options) error {
+	if _, err := s.root.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{s.template}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to create template database: %w", err)
+	}
+
+	conn, err := pgx.Connect(ctx, s.ConnString(s.template))
+	if err != nil {
+		return fmt.Errorf("failed to connect to template database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if o.migrations != nil {
+		if err = applyMigrations(ctx, conn, o.migrations); err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range o.setup {
+		if err = fn(ctx, conn); err != nil {
+			return fmt.Errorf("failed to set up template database: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigrations(ctx context.Context, conn *pgx.Conn, fsys fs.FS) error {
+	m, err := migrate.New(fsys)
+	if err != nil {
+		return err
+	}
+	if _, err = m.Up(ctx, conn); err != nil {
+		return fmt.Errorf("failed to migrate template database: %w", err)
+	}
+
+	return nil
+}
+
+// ConnString returns the connection URI of database name on the server.
+func (s *Server) ConnString(name string) string {
+	u := *s.rootURL
+	u.Path = "/" + name
+
+	return u.String()
+}
+
+// NewDatabase clones the template into a new database and returns a pool
+// connected to it. The pool is closed and the database dropped when t ends.
+func (s *Server) NewDatabase(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+
+	name := fmt.Sprintf("%s_%d", s.template, s.clones.Add(1))
+
+	s.cloneMu.Lock()
+	_, err := s.root.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s",
+		pgx.Identifier{name}.Sanitize(),
+		pgx.Identifier{s.template}.Sanitize(),
+	))
+	s.cloneMu.Unlock()
+	if err != nil {
+		t.Fatalf("testdb: failed to clone template database: %v", err)
+	}
+
+	pool, err := pgxpool.New(ctx, s.ConnString(name))
+	if err != nil {
+		t.Fatalf("testdb: failed to connect to %s: %v", name, err)
+	}
+
+	t.Cleanup(func() {
+		pool.Close()
+		if err := s.drop(context.Background(), name); err != nil {
+			t.Logf("testdb: %v", err)
+		}
+	})
+
+	return pool
+}
+
+func (s *Server) drop(ctx context.Context, name string) error {
+	_, err := s.root.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)")
+	if err != nil {
+		return fmt.Errorf("failed to drop database %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Close drops the template database and stops the container, if any.
+func (s *Server) Close(ctx context.Context) error {
+	var errs []error
+	if s.root != nil {
+		if s.template != "" {
+			errs = append(errs, s.drop(ctx, s.template))
+		}
+		s.root.Close()
+	}
+	if s.container != nil {
+		errs = append(errs, testcontainers.TerminateContainer(s.container))
+	}
+
+	return errors.Join(errs...)
+}
+
+// dockerAvailable reports whether testcontainers can reach a Docker daemon.
+// The provider panics instead of returning an error in some setups.
+func dockerAvailable(ctx context.Context) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return false
+	}
+	defer provider.Close()
+
+	return provider.Health(ctx) == nil
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -30,3 +30,22 @@ func String() string {
 		DirtyBuild,
 	)
 }
+
+// Info is the version information in a form suitable for encoding.
+type Info struct {
+	ModulePath string    `json:"module_path"`
+	Version    string    `json:"version"`
+	Revision   string    `json:"revision"`
+	LastCommit time.Time `json:"last_commit"`
+	DirtyBuild bool      `json:"dirty_build"`
+}
+
+func Get() Info {
+	return Info{
+		ModulePath: ModulePath,
+		Version:    Version,
+		Revision:   Revision,
+		LastCommit: LastCommit,
+		DirtyBuild: DirtyBuild,
+	}
+}